@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authApiTokenPrefix    = "auth/apiToken"
+	ApiTokenPrefix        = "hat_"
+	apiTokenLength        = 40
+	apiTokenIdLength      = 12
+	maxApiTokenNameLength = 100
+)
+
+var (
+	ErrApiTokenNameRequired = errors.New("API token name is required")
+	ErrApiTokenNoScopes     = errors.New("API token should have at least one scope")
+	ErrApiTokenScopeDenied  = errors.New("API token scope exceeds user permissions")
+)
+
+// ApiTokens manages personal API tokens which can be used by scripts instead
+// of logging in. Token is scoped to modules and access levels - it never
+// gives more access than the user has.
+type ApiTokens struct {
+	DbClient *db.Client
+}
+
+// ApiTokenInfo describes API token on the settings page.
+type ApiTokenInfo struct {
+	TokenId    string
+	Name       string
+	Scopes     map[string]string // module -> access level
+	CreateTs   string
+	LastUsedTs *string
+}
+
+// Create creates new API token for given user and returns the token. Token
+// itself is not stored, so it can be displayed only once.
+func (at ApiTokens) Create(userId int, name string, scopes map[string]string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", ErrApiTokenNameRequired
+	}
+	if len(name) > maxApiTokenNameLength {
+		name = name[:maxApiTokenNameLength]
+	}
+
+	permissions, pErr := UserPermissions(at.DbClient, userId)
+	if pErr != nil {
+		return "", pErr
+	}
+	tokenScopes := make(map[string]string, len(scopes))
+	for module, access := range scopes {
+		if access == AccessNone {
+			continue
+		}
+		if !isKnownModule(module) {
+			return "", ErrUnknownModule
+		}
+		if access != AccessRead && access != AccessWrite {
+			return "", ErrUnknownAccess
+		}
+		if !permissions.Can(module, access) {
+			return "", ErrApiTokenScopeDenied
+		}
+		tokenScopes[module] = access
+	}
+	if len(tokenScopes) == 0 {
+		return "", ErrApiTokenNoScopes
+	}
+
+	token := ApiTokenPrefix + rand.AlphanumStr(apiTokenLength)
+	apiToken := db.ApiToken{
+		TokenId:   rand.AlphanumStr(apiTokenIdLength),
+		UserId:    userId,
+		Name:      name,
+		TokenHash: hashApiToken(token),
+		Scopes:    formatScopes(tokenScopes),
+		CreateTs:  time.Now().UTC().Format(db.TimestampFormat),
+	}
+	if insErr := at.DbClient.ApiTokenInsertNew(apiToken); insErr != nil {
+		return "", insErr
+	}
+
+	log.Info().Int("userId", userId).Str("tokenId", apiToken.TokenId).Str("scopes", apiToken.Scopes).
+		Msgf("[%s] new API token created", authApiTokenPrefix)
+	return token, nil
+}
+
+// Tokens lists not revoked API tokens of given user.
+func (at ApiTokens) Tokens(userId int) ([]ApiTokenInfo, error) {
+	tokens, tErr := at.DbClient.ApiTokensActive(userId)
+	if tErr != nil {
+		return nil, tErr
+	}
+
+	infos := make([]ApiTokenInfo, len(tokens))
+	for idx, t := range tokens {
+		infos[idx] = ApiTokenInfo{
+			TokenId:    t.TokenId,
+			Name:       t.Name,
+			Scopes:     parseScopes(t.Scopes),
+			CreateTs:   t.CreateTs,
+			LastUsedTs: t.LastUsedTs,
+		}
+	}
+	return infos, nil
+}
+
+// Revoke revokes API token of given user.
+func (at ApiTokens) Revoke(userId int, tokenId string) error {
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	return at.DbClient.ApiTokenRevoke(userId, tokenId, nowTs)
+}
+
+// IsApiTokenValid checks if API token exists, is not revoked and belongs to
+// active user. Token last used timestamp is updated at most once per
+// lastSeenUpdateEachSec.
+func (ua UserAuth) IsApiTokenValid(token string) (TokenStatus, error) {
+	apiToken, tErr := ua.DbClient.ApiTokenByHash(hashApiToken(token))
+	if tErr == sql.ErrNoRows {
+		return TokenStatus{IsValid: false}, nil
+	}
+	if tErr != nil {
+		return TokenStatus{}, tErr
+	}
+
+	user, uErr := ua.DbClient.UserByUserId(apiToken.UserId)
+	if uErr != nil {
+		return TokenStatus{}, uErr
+	}
+	tokenStatus := TokenStatus{
+		IsValid:    user.IsActive,
+		UserId:     apiToken.UserId,
+		ApiTokenId: apiToken.TokenId,
+		ApiScopes:  parseScopes(apiToken.Scopes),
+	}
+	if !user.IsActive {
+		log.Warn().Int("userId", user.UserId).Str("tokenId", apiToken.TokenId).
+			Msgf("[%s] API token of disabled user", authApiTokenPrefix)
+		return tokenStatus, nil
+	}
+
+	now := time.Now().UTC()
+	var lastUsed time.Time // zero value when token was never used
+	if apiToken.LastUsedTs != nil {
+		lastUsed, _ = time.Parse(db.TimestampFormat, *apiToken.LastUsedTs)
+	}
+	if now.Sub(lastUsed) >= lastSeenUpdateEachSec*time.Second {
+		ua.DbClient.ApiTokenUpdateLastUsed(apiToken.TokenId, now.Format(db.TimestampFormat))
+	}
+	return tokenStatus, nil
+}
+
+// Gets token from "Authorization: Bearer" header. The second value is false
+// when header is not set.
+func bearerToken(r *http.Request) (string, bool) {
+	const bearerPrefix = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) <= len(bearerPrefix) || !strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(bearerPrefix):]), true
+}
+
+// API tokens are long random strings, so plain SHA256 is enough.
+func hashApiToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
+
+func formatScopes(scopes map[string]string) string {
+	pairs := make([]string, 0, len(scopes))
+	for module, access := range scopes {
+		pairs = append(pairs, module+":"+access)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func parseScopes(scopes string) map[string]string {
+	parsed := make(map[string]string)
+	for _, pair := range strings.Split(scopes, ",") {
+		module, access, found := strings.Cut(pair, ":")
+		if found {
+			parsed[module] = access
+		}
+	}
+	return parsed
+}
@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestScopesRoundTrip(t *testing.T) {
+	scopes := map[string]string{ModuleFinance: AccessRead, ModuleCounters: AccessWrite}
+
+	formatted := formatScopes(scopes)
+	if formatted != "counters:write,finance:read" {
+		t.Errorf("unexpected formatted scopes: %s", formatted)
+	}
+
+	parsed := parseScopes(formatted)
+	if len(parsed) != len(scopes) {
+		t.Fatalf("expected %d scopes, got %d", len(scopes), len(parsed))
+	}
+	for module, access := range scopes {
+		if parsed[module] != access {
+			t.Errorf("expected %s:%s, got %s:%s", module, access, module, parsed[module])
+		}
+	}
+}
+
+func TestBearerToken(t *testing.T) {
+	testCases := []struct {
+		header  string
+		token   string
+		isFound bool
+	}{
+		{"", "", false},
+		{"Basic dXNlcjpwYXNz", "", false},
+		{"Bearer ", "", false},
+		{"Bearer hat_abc", "hat_abc", true},
+		{"bearer hat_abc ", "hat_abc", true},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest("GET", "/counters", nil)
+		if tc.header != "" {
+			r.Header.Set("Authorization", tc.header)
+		}
+		token, isFound := bearerToken(r)
+		if token != tc.token || isFound != tc.isFound {
+			t.Errorf("for header [%s] expected (%s, %v), got (%s, %v)", tc.header, tc.token, tc.isFound, token,
+				isFound)
+		}
+	}
+}
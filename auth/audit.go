@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"homeApp/db"
+	"homeApp/notify"
+
+	"github.com/rs/zerolog/log"
+)
+
+const authAuditPrefix = "auth/audit"
+
+// Types of authentication events.
+const (
+	EventLogin          = "login"
+	Event2FA            = "2fa"
+	EventSessionProlong = "session_prolong"
+	EventLogout         = "logout"
+	EventPasswordChange = "password_change"
+)
+
+// Results of authentication events.
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+	ResultBlocked = "blocked"
+)
+
+var (
+	EventTypes   = []string{EventLogin, Event2FA, EventSessionProlong, EventLogout, EventPasswordChange}
+	EventResults = []string{ResultSuccess, ResultFailure, ResultBlocked}
+)
+
+// AuthEvent is a single authentication event. UserId is 0 when user is not
+// known.
+type AuthEvent struct {
+	Type     string
+	Result   string
+	UserId   int
+	Username string
+	Client   ClientInfo
+	Details  string
+}
+
+// AuthAudit persists authentication events in the audit log. Optionally
+// alert is sent when user logs in from IP address which wasn't used for
+// successful login before.
+type AuthAudit struct {
+	DbClient   *db.Client
+	Alerter    notify.Notifier
+	AlertNewIp bool
+}
+
+// Record persists authentication event. Errors are only logged, so recording
+// never breaks authentication. Missing username is read based on UserId.
+func (aa *AuthAudit) Record(event AuthEvent) {
+	var userId *int
+	if event.UserId != 0 {
+		userId = &event.UserId
+		if event.Username == "" {
+			if user, uErr := aa.DbClient.UserByUserId(event.UserId); uErr == nil {
+				event.Username = user.Username
+			}
+		}
+	}
+
+	if aa.AlertNewIp && event.Type == EventLogin && event.Result == ResultSuccess {
+		aa.alertIfNewIp(event)
+	}
+
+	insErr := aa.DbClient.AuthEventInsert(db.AuthEvent{
+		Ts:        time.Now().UTC().Format(db.TimestampFormat),
+		EventType: event.Type,
+		Result:    event.Result,
+		UserId:    userId,
+		Username:  event.Username,
+		IpAddress: event.Client.IpAddress,
+		UserAgent: event.Client.UserAgent,
+		Details:   event.Details,
+	})
+	if insErr != nil {
+		log.Error().Err(insErr).Str("eventType", event.Type).Str("username", event.Username).
+			Msgf("[%s] cannot record auth event", authAuditPrefix)
+	}
+}
+
+// Events reads the latest authentication events matching the filter.
+func (aa *AuthAudit) Events(filter db.AuthEventsFilter) ([]db.AuthEvent, error) {
+	return aa.DbClient.AuthEvents(filter)
+}
+
+// Has to be called before the event is recorded.
+func (aa *AuthAudit) alertIfNewIp(event AuthEvent) {
+	isKnownIp, kErr := aa.DbClient.AuthEventExists(event.UserId, EventLogin, ResultSuccess, event.Client.IpAddress)
+	if kErr != nil || isKnownIp {
+		return
+	}
+
+	msg := fmt.Sprintf("[login] User [%s] logged in from new IP address %s (%s)", event.Username,
+		event.Client.IpAddress, event.Client.UserAgent)
+	if sendErr := aa.Alerter.Notify(notify.Message{Event: notify.EventSecurity, Text: msg}); sendErr != nil {
+		log.Error().Err(sendErr).Msgf("[%s] cannot send new IP alert", authAuditPrefix)
+	}
+}
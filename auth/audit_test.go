@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+
+	"homeApp/db"
+	"homeApp/notify"
+)
+
+type fakeNotifier struct {
+	messages []notify.Message
+}
+
+func (fn *fakeNotifier) Notify(msg notify.Message) error {
+	fn.messages = append(fn.messages, msg)
+	return nil
+}
+
+// Reads the latest recorded authentication event.
+func lastAuthEvent(t *testing.T, audit *AuthAudit) db.AuthEvent {
+	t.Helper()
+	events, eErr := audit.Events(db.AuthEventsFilter{Limit: 1})
+	if eErr != nil || len(events) != 1 {
+		t.Fatalf("expected recorded event, got %d (error %v)", len(events), eErr)
+	}
+	return events[0]
+}
+
+func TestAuditRecordUsername(t *testing.T) {
+	dbClient := newTestDbClient(t)
+	audit := &AuthAudit{DbClient: dbClient}
+	user := addTestUser(t, dbClient, "alice", "alice password 1")
+
+	testCases := []struct {
+		event    AuthEvent
+		userId   int // 0 when event shouldn't be attributed to a user
+		username string
+	}{
+		{AuthEvent{Type: EventLogout, Result: ResultSuccess, UserId: user.UserId}, user.UserId, "alice"},
+		{AuthEvent{Type: EventLogin, Result: ResultSuccess, UserId: user.UserId, Username: "given"}, user.UserId,
+			"given"},
+		{AuthEvent{Type: EventLogin, Result: ResultFailure, Username: "unknown"}, 0, "unknown"},
+	}
+
+	for _, tc := range testCases {
+		audit.Record(tc.event)
+		event := lastAuthEvent(t, audit)
+		userId := 0
+		if event.UserId != nil {
+			userId = *event.UserId
+		}
+		if userId != tc.userId || event.Username != tc.username {
+			t.Errorf("[%+v] expected user %d [%s], got %d [%s]", tc.event, tc.userId, tc.username, userId,
+				event.Username)
+		}
+	}
+}
+
+func TestLoginAuditEvents(t *testing.T) {
+	ua := newTestUserAuth(t)
+	hm := newTestHandlerManager(ua)
+	alice := addTestUser(t, ua.DbClient, "alice", "alice password 1")
+	bob := addTestUser(t, ua.DbClient, "bob", "bob password 1")
+	if sErr := (UserAdmin{DbClient: ua.DbClient}).SetActive("bob", false); sErr != nil {
+		t.Fatal(sErr)
+	}
+
+	// Every failure locks out the user and third one the IP address, so blocking
+	// doesn't depend on backoff timing
+	hm.LoginLimiter.MaxFailures = 1
+	hm.LoginLimiter.Alerter = &fakeNotifier{}
+
+	testCases := []struct {
+		name     string
+		username string
+		password string
+		result   string
+		userId   int // 0 when event shouldn't be attributed to a user
+		details  string
+	}{
+		{"correct password", "alice", "alice password 1", ResultSuccess, alice.UserId, "2fa: none"},
+		{"disabled user", "bob", "bob password 1", ResultFailure, bob.UserId, "user is disabled"},
+		{"incorrect password", "alice", "incorrect", ResultFailure, 0, "incorrect username or password"},
+		{"disabled user incorrect password", "bob", "incorrect", ResultFailure, 0,
+			"incorrect username or password"},
+		{"unknown user", "carol", "carol password 1", ResultFailure, 0, "incorrect username or password"},
+		{"blocked user", "alice", "alice password 1", ResultBlocked, 0, "blocked for"},
+	}
+
+	for _, tc := range testCases {
+		postForm(hm.Login, "/login", url.Values{"login": {tc.username}, "pass": {tc.password}})
+		event := lastAuthEvent(t, hm.Audit)
+		userId := 0
+		if event.UserId != nil {
+			userId = *event.UserId
+		}
+		if event.EventType != EventLogin || event.Result != tc.result || userId != tc.userId ||
+			event.Username != tc.username || !strings.HasPrefix(event.Details, tc.details) {
+			t.Errorf("[%s] unexpected event: %+v (user %d)", tc.name, event, userId)
+		}
+	}
+}
+
+func TestAuditNewIpAlert(t *testing.T) {
+	dbClient := newTestDbClient(t)
+	alerter := &fakeNotifier{}
+	audit := &AuthAudit{DbClient: dbClient, Alerter: alerter, AlertNewIp: true}
+	user := addTestUser(t, dbClient, "alice", "alice password 1")
+
+	testCases := []struct {
+		result    string
+		ipAddress string
+		isAlerted bool
+	}{
+		{ResultSuccess, "10.0.0.1", true},
+		{ResultSuccess, "10.0.0.1", false},
+		{ResultFailure, "10.0.0.2", false},
+		{ResultSuccess, "10.0.0.2", true},
+		{ResultSuccess, "10.0.0.2", false},
+	}
+
+	for idx, tc := range testCases {
+		alertsBefore := len(alerter.messages)
+		audit.Record(AuthEvent{Type: EventLogin, Result: tc.result, UserId: user.UserId,
+			Client: ClientInfo{IpAddress: tc.ipAddress}})
+		isAlerted := len(alerter.messages) > alertsBefore
+		if isAlerted != tc.isAlerted {
+			t.Errorf("[%d] %s login from %s: expected alert %v, got %v", idx, tc.result, tc.ipAddress,
+				tc.isAlerted, isAlerted)
+		}
+		if isAlerted && !strings.Contains(alerter.messages[alertsBefore].Text, tc.ipAddress) {
+			t.Errorf("[%d] expected IP address in alert, got [%s]", idx, alerter.messages[alertsBefore].Text)
+		}
+	}
+}
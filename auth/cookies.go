@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"net/http"
+	"time"
+)
+
+// SessionCookie creates session cookie holding given JWT. Secure cookies are
+// sent by browsers only over HTTPS.
+func SessionCookie(userJwt string, isSecure bool) *http.Cookie {
+	return newCookie(SessCookieName, userJwt, time.Now().Add(SessCookieExpMinutes*time.Minute), isSecure)
+}
+
+// ExpiredSessionCookie creates session cookie which deletes the cookie in the
+// browser. Setting cookie with the same name and expiring time stamp in the
+// past is equivalent of deleting the cookie.
+func ExpiredSessionCookie(isSecure bool) *http.Cookie {
+	return newCookie(SessCookieName, "", time.Unix(0, 0), isSecure)
+}
+
+func pendingCookie(pendingId string, isSecure bool) *http.Cookie {
+	return newCookie(PendingCookieName, pendingId, time.Now().Add(pendingLoginTtl), isSecure)
+}
+
+func expiredPendingCookie(isSecure bool) *http.Cookie {
+	return newCookie(PendingCookieName, "", time.Unix(0, 0), isSecure)
+}
+
+// Cookies are not sent with cross-site subrequests and form posts (SameSite
+// Lax), but they are still sent when user follows a link to HomeApp.
+func newCookie(name, value string, expires time.Time, isSecure bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Path:     "/",
+		Value:    value,
+		Expires:  expires,
+		HttpOnly: true,
+		Secure:   isSecure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	CsrfFieldName   = "csrfToken"
+	CsrfHeaderName  = "X-CSRF-Token"
+	csrfTokenLength = 32
+)
+
+// CheckCsrf is a middleware which rejects state-changing requests (other than
+// GET, HEAD and OPTIONS) without CSRF token of the current session, either in
+// CsrfFieldName form field or in CsrfHeaderName header. Token is generated
+// when session starts. Requests authenticated by API token are not checked,
+// because browsers don't attach those automatically. It has to be used after
+// CheckAuth.
+func (hm *HandlerManager) CheckCsrf(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r)
+			return
+		}
+
+		tokenStatus, _ := TokenStatusFromRequest(r)
+		if tokenStatus.ApiTokenId != "" {
+			next(w, r)
+			return
+		}
+		token := r.Header.Get(CsrfHeaderName)
+		if token == "" {
+			token = r.FormValue(CsrfFieldName)
+		}
+		if !isCsrfTokenValid(tokenStatus.CsrfToken, token) {
+			log.Error().Int("userId", tokenStatus.UserId).Str("path", r.URL.Path).Str("method", r.Method).
+				Msgf("[%s] missing or incorrect CSRF token", authHandlerPrefix)
+			http.Error(w, "Forbidden - invalid CSRF token, reload the page and try again", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// CsrfTokenFromRequest gets CSRF token of the current session.
+func CsrfTokenFromRequest(r *http.Request) string {
+	tokenStatus, _ := TokenStatusFromRequest(r)
+	return tokenStatus.CsrfToken
+}
+
+func isCsrfTokenValid(expected, token string) bool {
+	if expected == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(token)) == 1
+}
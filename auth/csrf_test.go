@@ -0,0 +1,52 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestCheckCsrf(t *testing.T) {
+	const sessionToken = "abcdefghijklmnopqrstuvwxyz012345"
+	hm := &HandlerManager{}
+	handler := hm.CheckCsrf(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	testCases := []struct {
+		name     string
+		method   string
+		form     url.Values
+		header   string
+		expected int
+	}{
+		{"GET without token", http.MethodGet, nil, "", http.StatusOK},
+		{"POST without token", http.MethodPost, url.Values{}, "", http.StatusForbidden},
+		{"POST with incorrect token", http.MethodPost, url.Values{CsrfFieldName: {"incorrect"}}, "", http.StatusForbidden},
+		{"POST with form token", http.MethodPost, url.Values{CsrfFieldName: {sessionToken}}, "", http.StatusOK},
+		{"POST with header token", http.MethodPost, url.Values{}, sessionToken, http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(tc.method, "/logout", strings.NewReader(tc.form.Encode()))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if tc.header != "" {
+			r.Header.Set(CsrfHeaderName, tc.header)
+		}
+		r = withTokenStatus(r, TokenStatus{IsValid: true, UserId: 1, CsrfToken: sessionToken})
+		w := httptest.NewRecorder()
+
+		handler(w, r)
+		if w.Code != tc.expected {
+			t.Errorf("%s: expected status %d, got %d", tc.name, tc.expected, w.Code)
+		}
+	}
+}
+
+func TestCheckCsrfWithoutSessionToken(t *testing.T) {
+	if isCsrfTokenValid("", "") {
+		t.Error("expected empty tokens to be invalid")
+	}
+}
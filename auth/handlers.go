@@ -1,9 +1,13 @@
 package auth
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"time"
 
+	"homeApp/db"
+
 	"github.com/rs/zerolog/log"
 )
 
@@ -15,67 +19,261 @@ const (
 
 type HandlerManager struct {
 	UserAuthenticator UserAuthenticator
+	PendingLogins     *PendingLogins
+	LoginLimiter      *LoginLimiter
+	Audit             *AuthAudit
+	SecureCookies     bool
 }
 
-// Login HTTP handler performs user authentication. // TODO more
+// Login HTTP handler performs first step of user authentication - password
+// check. Then second factor challenge is issued and user is redirected to 2FA
+// page, unless user has 2FA turned off. In that case session is started right
+// away. Challenges which don't need user response (like Telegram) are
+// verified in the background, so the request is not blocked. Attempts are
+// throttled per username and IP address by LoginLimiter.
 func (hm *HandlerManager) Login(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
 	r.ParseForm()
 	name := r.FormValue("login")
 	pass := r.FormValue("pass")
+	client := ClientInfoFromRequest(r)
 	log.Info().Str("username", name).Msgf("[%s] start user authentication", authHandlerPrefix)
 
-	userJwt, authErr := hm.UserAuthenticator.IsUserValid(name, pass)
+	blockedFor, blockErr := hm.LoginLimiter.Blocked(name, client.IpAddress)
+	if blockErr != nil || blockedFor > 0 {
+		log.Error().Err(blockErr).Str("username", name).Str("ip", client.IpAddress).Dur("blockedFor", blockedFor).
+			Msgf("[%s] login attempts are blocked", authHandlerPrefix)
+		hm.Audit.Record(AuthEvent{Type: EventLogin, Result: ResultBlocked, Username: name, Client: client,
+			Details: fmt.Sprintf("blocked for %v", blockedFor.Round(time.Second))})
+		http.Redirect(w, r, "/?error=blocked", http.StatusSeeOther)
+		return
+	}
+
+	user, authErr := hm.UserAuthenticator.IsUserValid(name, pass)
 	switch authErr {
 	case nil:
 		break
 	case ErrInvalidUsernameOrPass:
 		log.Error().Err(authErr).Str("username", name).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] authentication failed - incorrect username or password", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
-		http.Redirect(w, r, "/", http.StatusSeeOther)
+		hm.LoginLimiter.RegisterFailure(name, client.IpAddress)
+		hm.Audit.Record(AuthEvent{Type: EventLogin, Result: ResultFailure, Username: name, Client: client,
+			Details: "incorrect username or password"})
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/?error=invalid", http.StatusSeeOther)
+		return
+	case ErrUserDisabled:
+		log.Error().Err(authErr).Str("username", name).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] authentication failed - user is disabled", authHandlerPrefix)
+		hm.Audit.Record(AuthEvent{Type: EventLogin, Result: ResultFailure, UserId: user.UserId, Username: name,
+			Client: client, Details: "user is disabled"})
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/?error=invalid", http.StatusSeeOther)
 		return
 	default:
 		log.Error().Err(authErr).Str("username", name).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] authentication failed - might be a backend error", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	twoFaPassed, twoFaErr := hm.UserAuthenticator.Check2FA(name)
-	if twoFaErr != nil {
-		log.Error().Err(twoFaErr).Str("username", name).Dur("duration", time.Since(startTs)).
-			Msgf("[%s] 2FA via Telegram failed", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+	challenge, beginErr := hm.UserAuthenticator.Begin2FA(user)
+	if beginErr != nil {
+		log.Error().Err(beginErr).Str("username", name).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] cannot begin 2FA", authHandlerPrefix)
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	if challenge.Method == TwoFactorNone {
+		hm.startSession(w, r, user, challenge.Method, startTs)
+		return
+	}
+
+	pendingId := hm.PendingLogins.add(user, challenge)
+	if !challenge.NeedsResponse {
+		go hm.verifyInBackground(pendingId, user, challenge, client)
+	}
+	http.SetCookie(w, pendingCookie(pendingId, hm.SecureCookies))
+	log.Info().Str("username", name).Str("method", challenge.Method).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] password is correct, waiting for 2FA", authHandlerPrefix)
+	http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+}
+
+// PendingChallenge gets second factor challenge of pending login of given
+// request, so it can be presented to the user.
+func (hm *HandlerManager) PendingChallenge(r *http.Request) (TwoFactorChallenge, bool) {
+	pendingCookie, cookieErr := r.Cookie(PendingCookieName)
+	if cookieErr != nil {
+		return TwoFactorChallenge{}, false
+	}
+	login, exists := hm.PendingLogins.get(pendingCookie.Value)
+	if !exists {
+		return TwoFactorChallenge{}, false
+	}
+	return login.Challenge, true
+}
+
+// Login2FAStatus HTTP handler returns status of background second factor
+// verification of pending login as JSON. It's polled by 2FA page.
+func (hm *HandlerManager) Login2FAStatus(w http.ResponseWriter, r *http.Request) {
+	status := PendingStatusExpired
+	if pendingCookie, cookieErr := r.Cookie(PendingCookieName); cookieErr == nil {
+		if login, exists := hm.PendingLogins.get(pendingCookie.Value); exists {
+			status = login.Status
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(map[string]string{"status": status})
+}
+
+// Login2FA HTTP handler performs second step of user authentication - verifies
+// user response to second factor challenge or checks result of background
+// verification. On success new session is started. User has
+// pendingLoginMaxAttempts attempts, then login has to be started over.
+func (hm *HandlerManager) Login2FA(w http.ResponseWriter, r *http.Request) {
+	startTs := time.Now()
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+
+	pendingCookie, cookieErr := r.Cookie(PendingCookieName)
+	if cookieErr != nil {
+		log.Error().Err(cookieErr).Msgf("[%s] there is no pending 2FA cookie. Redirect to login", authHandlerPrefix)
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
-	if twoFaErr == nil && !twoFaPassed {
-		log.Error().Str("username", name).Dur("duration", time.Since(startTs)).
-			Msgf("[%s] 2FA via Telegram does not succeeded, username was not matched", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+	login, exists := hm.PendingLogins.get(pendingCookie.Value)
+	if !exists {
+		log.Error().Msgf("[%s] pending login does not exist or expired. Redirect to login", authHandlerPrefix)
+		http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	if !login.Challenge.NeedsResponse {
+		hm.completeBackgroundLogin(w, r, pendingCookie.Value, login, startTs)
+		return
+	}
+
+	client := ClientInfoFromRequest(r)
+	twoFaPassed, twoFaErr := hm.UserAuthenticator.Verify2FA(login.User, login.Challenge, r.FormValue("otp"))
+	if twoFaErr != nil {
+		log.Error().Err(twoFaErr).Str("username", login.User.Username).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] 2FA failed", authHandlerPrefix)
+		hm.record2FA(login.User, login.Challenge, client, ResultFailure, twoFaErr.Error())
+		hm.PendingLogins.remove(pendingCookie.Value)
+		http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
+	if !twoFaPassed {
+		hm.LoginLimiter.RegisterFailure(login.User.Username, client.IpAddress)
+		attemptsLeft := hm.PendingLogins.failedAttempt(pendingCookie.Value)
+		hm.record2FA(login.User, login.Challenge, client, ResultFailure,
+			fmt.Sprintf("incorrect code, %d attempts left", attemptsLeft))
+		log.Error().Str("username", login.User.Username).Int("attemptsLeft", attemptsLeft).
+			Dur("duration", time.Since(startTs)).Msgf("[%s] 2FA does not succeeded", authHandlerPrefix)
+		if attemptsLeft <= 0 {
+			http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
+			http.Redirect(w, r, "/", http.StatusSeeOther)
+			return
+		}
+		http.Redirect(w, r, "/login/2fa?failed=1", http.StatusSeeOther)
+		return
+	}
 
-	sessCookie := &http.Cookie{
-		Name:     SessCookieName,
-		Value:    userJwt,
-		Expires:  time.Now().Add(SessCookieExpMinutes * time.Minute),
-		HttpOnly: true,
+	hm.record2FA(login.User, login.Challenge, client, ResultSuccess, "")
+	hm.PendingLogins.remove(pendingCookie.Value)
+	http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
+	hm.startSession(w, r, login.User, login.Challenge.Method, startTs)
+}
+
+// Verifies second factor of pending login in the background and stores the
+// result, so it can be polled via Login2FAStatus.
+func (hm *HandlerManager) verifyInBackground(pendingId string, user db.User, challenge TwoFactorChallenge,
+	client ClientInfo) {
+	startTs := time.Now()
+	twoFaPassed, twoFaErr := hm.UserAuthenticator.Verify2FA(user, challenge, "")
+	if twoFaErr != nil || !twoFaPassed {
+		log.Error().Err(twoFaErr).Str("username", user.Username).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] background 2FA failed", authHandlerPrefix)
+		hm.LoginLimiter.RegisterFailure(user.Username, client.IpAddress)
+		details := "not confirmed"
+		if twoFaErr != nil {
+			details = twoFaErr.Error()
+		}
+		hm.record2FA(user, challenge, client, ResultFailure, details)
+		hm.PendingLogins.setStatus(pendingId, PendingStatusFailed)
+		return
 	}
-	http.SetCookie(w, sessCookie)
-	log.Info().Str("username", name).Dur("duration", time.Since(startTs)).
+	log.Info().Str("username", user.Username).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] background 2FA confirmed", authHandlerPrefix)
+	hm.record2FA(user, challenge, client, ResultSuccess, "")
+	hm.PendingLogins.setStatus(pendingId, PendingStatusConfirmed)
+}
+
+// Starts session for pending login which was confirmed in the background.
+func (hm *HandlerManager) completeBackgroundLogin(w http.ResponseWriter, r *http.Request, pendingId string,
+	login pendingLogin, startTs time.Time) {
+	switch login.Status {
+	case PendingStatusConfirmed:
+		hm.PendingLogins.remove(pendingId)
+		http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
+		hm.startSession(w, r, login.User, login.Challenge.Method, startTs)
+	case PendingStatusWaiting:
+		http.Redirect(w, r, "/login/2fa", http.StatusSeeOther)
+	default:
+		log.Error().Str("username", login.User.Username).Str("status", login.Status).
+			Msgf("[%s] 2FA was not confirmed", authHandlerPrefix)
+		hm.PendingLogins.remove(pendingId)
+		http.SetCookie(w, expiredPendingCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+	}
+}
+
+// Starts new session for authenticated user and sets session cookie.
+func (hm *HandlerManager) startSession(w http.ResponseWriter, r *http.Request, user db.User, twoFaMethod string,
+	startTs time.Time) {
+	client := ClientInfoFromRequest(r)
+
+	// User might have been disabled during 2FA, NewSession checks it again
+	userJwt, sessErr := hm.UserAuthenticator.NewSession(user.UserId, client)
+	if sessErr != nil {
+		log.Error().Err(sessErr).Str("username", user.Username).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] cannot start new user session", authHandlerPrefix)
+		hm.Audit.Record(AuthEvent{Type: EventLogin, Result: ResultFailure, UserId: user.UserId,
+			Username: user.Username, Client: client, Details: sessErr.Error()})
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	hm.LoginLimiter.RegisterSuccess(user.Username)
+
+	http.SetCookie(w, SessionCookie(userJwt, hm.SecureCookies))
+	hm.Audit.Record(AuthEvent{Type: EventLogin, Result: ResultSuccess, UserId: user.UserId, Username: user.Username,
+		Client: client, Details: "2fa: " + twoFaMethod})
+	log.Info().Str("username", user.Username).Dur("duration", time.Since(startTs)).
 		Msgf("[%s] finished user authentication - cookie is set", authHandlerPrefix)
 	http.Redirect(w, r, "/home", http.StatusSeeOther)
 }
 
 // CheckAuth is a middleware for user authentication once the session cookie
 // was set. It should be used upon every other API endpoint which shouldn't be
-// accessed without being authenticated.
+// accessed without being authenticated. Requests with "Authorization: Bearer"
+// header are authenticated by personal API token instead.
 func (hm *HandlerManager) CheckAuth(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if apiToken, isApiRequest := bearerToken(r); isApiRequest {
+			hm.checkApiToken(w, r, apiToken, next)
+			return
+		}
+
 		startTs := time.Now()
 		log.Info().Msgf("[%s] start user session validation", authHandlerPrefix)
 
@@ -96,15 +294,97 @@ func (hm *HandlerManager) CheckAuth(next func(http.ResponseWriter, *http.Request
 		if !tokenStatus.IsValid {
 			log.Error().Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 				Msgf("[%s] session expired or credentials are incorrect, redirecting to login", authHandlerPrefix)
-			http.SetCookie(w, expiredSessionCookie())
+			http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
 			http.Redirect(w, r, "/", http.StatusSeeOther)
 			return
 		}
 
+		permissions, permErr := hm.UserAuthenticator.Permissions(tokenStatus.UserId)
+		if permErr != nil {
+			log.Error().Err(permErr).Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
+				Msgf("[%s] cannot read user permissions", authHandlerPrefix)
+			http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+			return
+		}
+
 		log.Info().Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] user session validation succeeded", authHandlerPrefix)
 
 		// It's fine! Letting traffic flow
+		next(w, withPermissions(withTokenStatus(r, tokenStatus), permissions))
+	}
+}
+
+// Authenticates request by API token. Permissions of the user are restricted
+// to token scopes.
+func (hm *HandlerManager) checkApiToken(w http.ResponseWriter, r *http.Request, apiToken string, next func(http.ResponseWriter, *http.Request)) {
+	startTs := time.Now()
+	tokenStatus, validErr := hm.UserAuthenticator.IsApiTokenValid(apiToken)
+	if validErr != nil || !tokenStatus.IsValid {
+		log.Error().Err(validErr).Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] API token is invalid", authHandlerPrefix)
+		w.Header().Set("WWW-Authenticate", "Bearer")
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return
+	}
+
+	permissions, permErr := hm.UserAuthenticator.Permissions(tokenStatus.UserId)
+	if permErr != nil {
+		log.Error().Err(permErr).Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] cannot read user permissions", authHandlerPrefix)
+		http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+		return
+	}
+
+	log.Info().Int("userId", tokenStatus.UserId).Str("tokenId", tokenStatus.ApiTokenId).
+		Dur("duration", time.Since(startTs)).Msgf("[%s] API token validation succeeded", authHandlerPrefix)
+	next(w, withPermissions(withTokenStatus(r, tokenStatus), permissions.Restrict(tokenStatus.ApiScopes)))
+}
+
+// RequireSession is a middleware which rejects requests authenticated by API
+// token. It's used for pages which shouldn't be accessible by scripts, like
+// settings. It has to be used after CheckAuth.
+func (hm *HandlerManager) RequireSession(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStatus, _ := TokenStatusFromRequest(r)
+		if tokenStatus.ApiTokenId != "" {
+			log.Error().Int("userId", tokenStatus.UserId).Str("tokenId", tokenStatus.ApiTokenId).
+				Str("path", r.URL.Path).Msgf("[%s] API token cannot be used for this endpoint", authHandlerPrefix)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequireAdmin is a middleware which lets through only users with admin role.
+// It should be used after CheckAuth.
+func (hm *HandlerManager) RequireAdmin(next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStatus, _ := TokenStatusFromRequest(r)
+		permissions, _ := PermissionsFromRequest(r)
+		if !permissions.IsAdmin {
+			log.Error().Int("userId", tokenStatus.UserId).Str("path", r.URL.Path).
+				Msgf("[%s] user is not an admin", authHandlerPrefix)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// RequirePermission is a middleware which responds with 403 to users without
+// given access level to the module. It has to be used after CheckAuth.
+func (hm *HandlerManager) RequirePermission(module, access string, next func(http.ResponseWriter, *http.Request)) func(http.ResponseWriter, *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		tokenStatus, _ := TokenStatusFromRequest(r)
+		permissions, _ := PermissionsFromRequest(r)
+		if !permissions.Can(module, access) {
+			log.Error().Int("userId", tokenStatus.UserId).Str("path", r.URL.Path).Str("module", module).
+				Str("access", access).Msgf("[%s] user has no permission", authHandlerPrefix)
+			http.Error(w, "Forbidden", http.StatusForbidden)
+			return
+		}
 		next(w, r)
 	}
 }
@@ -128,9 +408,14 @@ func (hm *HandlerManager) IsSessionCookieValid(r *http.Request) (bool, error) {
 	return true, nil
 }
 
-// Terminates session - basically delete session cookie.
+// Terminates session - revokes server-side session and deletes session cookie.
+// Only POST requests (protected by CSRF token) terminate session.
 func (hm *HandlerManager) TerminateSession(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+		return
+	}
 
 	sessCookie, cookieErr := r.Cookie(SessCookieName)
 	if cookieErr != nil {
@@ -144,31 +429,37 @@ func (hm *HandlerManager) TerminateSession(w http.ResponseWriter, r *http.Reques
 	if validErr != nil {
 		log.Error().Err(validErr).Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] there was error while validating JWT - deleting cooking anyway", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 	if !tokenStatus.IsValid {
 		log.Error().Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] session expired or credentials are incorrect, redirecting to login", authHandlerPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+		http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	http.SetCookie(w, expiredSessionCookie())
+	revokeErr := hm.UserAuthenticator.RevokeSession(tokenStatus)
+	if revokeErr != nil {
+		log.Error().Err(revokeErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot revoke session - deleting cookie anyway", authHandlerPrefix)
+	}
+
+	http.SetCookie(w, ExpiredSessionCookie(hm.SecureCookies))
+	hm.Audit.Record(AuthEvent{Type: EventLogout, Result: ResultSuccess, UserId: tokenStatus.UserId,
+		Client: ClientInfoFromRequest(r)})
 	log.Info().Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 		Msgf("[%s] session terminated by user [%d]", authHandlerPrefix, tokenStatus.UserId)
 	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
 
-// Setting cookie with the same name and expiring time stamp in the past is
-// equivalent of deleting the cookie.
-func expiredSessionCookie() *http.Cookie {
-	return &http.Cookie{
-		Name:     SessCookieName,
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		HttpOnly: true,
+func (hm *HandlerManager) record2FA(user db.User, challenge TwoFactorChallenge, client ClientInfo, result,
+	details string) {
+	if details != "" {
+		details = ", " + details
 	}
+	hm.Audit.Record(AuthEvent{Type: Event2FA, Result: result, UserId: user.UserId, Username: user.Username,
+		Client: client, Details: "method: " + challenge.Method + details})
 }
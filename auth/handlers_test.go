@@ -0,0 +1,113 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Creates HandlerManager on top of given UserAuth.
+func newTestHandlerManager(ua UserAuth) *HandlerManager {
+	return &HandlerManager{
+		UserAuthenticator: ua,
+		PendingLogins:     NewPendingLogins(),
+		LoginLimiter:      &LoginLimiter{DbClient: ua.DbClient, MaxFailures: 10, Lockout: time.Hour},
+		Audit:             &AuthAudit{DbClient: ua.DbClient},
+	}
+}
+
+// Posts given form to the handler and returns the response.
+func postForm(handler http.HandlerFunc, path string, form url.Values, cookies ...*http.Cookie) *http.Response {
+	r := httptest.NewRequest(http.MethodPost, path, strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	for _, cookie := range cookies {
+		r.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w.Result()
+}
+
+// Gets non expired cookie of given name set by the response.
+func responseCookie(resp *http.Response, name string) *http.Cookie {
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name && cookie.Value != "" {
+			return cookie
+		}
+	}
+	return nil
+}
+
+func TestLoginTwoSteps(t *testing.T) {
+	ua := newTestUserAuth(t)
+	ua.TwoFactor = newTestTwoFactor(t, ua.DbClient)
+	hm := newTestHandlerManager(ua)
+
+	testCases := []struct {
+		name        string
+		method      string
+		code        func(secret []byte) string
+		redirect2FA string // empty when session is started without second step
+		hasSession  bool
+	}{
+		{"2FA turned off", TwoFactorNone, nil, "", true},
+		{"correct TOTP code", TwoFactorTotp, func(secret []byte) string {
+			return hotpCode(secret, totpCounter(time.Now()))
+		}, "/home", true},
+		{"incorrect TOTP code", TwoFactorTotp, func(secret []byte) string {
+			return hotpCode(secret, totpCounter(time.Now())+10)
+		}, "/login/2fa?failed=1", false},
+	}
+
+	for idx, tc := range testCases {
+		username := "user" + string(rune('a'+idx))
+		user := addTestUser(t, ua.DbClient, username, "user password 1")
+		secret := setTestTwoFactor(t, ua.TwoFactor, user.UserId, tc.method)
+
+		resp := postForm(hm.Login, "/login", url.Values{"login": {username}, "pass": {"user password 1"}})
+		if tc.redirect2FA == "" {
+			if location := resp.Header.Get("Location"); location != "/home" {
+				t.Errorf("[%s] expected redirect to /home, got %s", tc.name, location)
+			}
+			if responseCookie(resp, SessCookieName) == nil {
+				t.Errorf("[%s] expected session cookie", tc.name)
+			}
+			continue
+		}
+
+		if location := resp.Header.Get("Location"); location != "/login/2fa" {
+			t.Errorf("[%s] expected redirect to 2FA page, got %s", tc.name, location)
+			continue
+		}
+		pending := responseCookie(resp, PendingCookieName)
+		if pending == nil || responseCookie(resp, SessCookieName) != nil {
+			t.Errorf("[%s] expected only pending login cookie after password check", tc.name)
+			continue
+		}
+
+		resp = postForm(hm.Login2FA, "/login/2fa", url.Values{"otp": {tc.code(secret)}}, pending)
+		if location := resp.Header.Get("Location"); location != tc.redirect2FA {
+			t.Errorf("[%s] expected redirect to %s, got %s", tc.name, tc.redirect2FA, location)
+		}
+		if hasSession := responseCookie(resp, SessCookieName) != nil; hasSession != tc.hasSession {
+			t.Errorf("[%s] expected session cookie %v, got %v", tc.name, tc.hasSession, hasSession)
+		}
+	}
+}
+
+func TestLoginIncorrectPassword(t *testing.T) {
+	ua := newTestUserAuth(t)
+	hm := newTestHandlerManager(ua)
+	addTestUser(t, ua.DbClient, "alice", "alice password 1")
+
+	resp := postForm(hm.Login, "/login", url.Values{"login": {"alice"}, "pass": {"incorrect"}})
+	if location := resp.Header.Get("Location"); location != "/?error=invalid" {
+		t.Errorf("expected redirect to login page with error, got %s", location)
+	}
+	if responseCookie(resp, SessCookieName) != nil || responseCookie(resp, PendingCookieName) != nil {
+		t.Error("expected no session nor pending login cookie")
+	}
+}
@@ -0,0 +1,55 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSecurityHeadersNonce(t *testing.T) {
+	var nonce string
+	handler := SecurityHeaders(func(w http.ResponseWriter, r *http.Request) {
+		nonce = CspNonceFromRequest(r)
+	})
+	rec := httptest.NewRecorder()
+	handler(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+
+	if len(nonce) != cspNonceLength {
+		t.Fatalf("expected nonce of length %d in the request context, got %q", cspNonceLength, nonce)
+	}
+	csp := rec.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "script-src 'nonce-"+nonce+"'") {
+		t.Errorf("expected CSP to allow scripts with the request nonce, got %q", csp)
+	}
+	if rec.Header().Get("X-Frame-Options") != "DENY" {
+		t.Errorf("expected X-Frame-Options DENY, got %q", rec.Header().Get("X-Frame-Options"))
+	}
+}
+
+func TestSetFileHeaders(t *testing.T) {
+	testCases := []struct {
+		contentType string
+		filename    string
+		isInline    bool
+		disposition string
+		isSandboxed bool
+	}{
+		{"image/png", "scan.png", true, `inline; filename=scan.png`, true},
+		{"application/octet-stream", "page.html", false, `attachment; filename=page.html`, true},
+		{"application/pdf", "umowa najmu.pdf", true, `inline; filename="umowa najmu.pdf"`, false},
+		{"text/plain", `x"; filename=evil.html`, false, `attachment; filename="x\"; filename=evil.html"`, true},
+	}
+
+	for _, tc := range testCases {
+		rec := httptest.NewRecorder()
+		SetFileHeaders(rec, tc.contentType, tc.filename, tc.isInline)
+		if got := rec.Header().Get("Content-Disposition"); got != tc.disposition {
+			t.Errorf("for %q expected Content-Disposition %q, got %q", tc.filename, tc.disposition, got)
+		}
+		csp := rec.Header().Get("Content-Security-Policy")
+		if strings.Contains(csp, "sandbox") != tc.isSandboxed {
+			t.Errorf("for %q expected sandboxed=%v, got CSP %q", tc.filename, tc.isSandboxed, csp)
+		}
+	}
+}
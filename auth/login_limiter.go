@@ -0,0 +1,123 @@
+package auth
+
+import (
+	"fmt"
+	"time"
+
+	"homeApp/db"
+	"homeApp/notify"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authLimiterPrefix    = "auth/limiter"
+	freeLoginAttempts    = 3
+	ipFailuresMultiplier = 3 // Many users might share single IP address
+	baseLoginBackoff     = time.Second
+	failuresResetAfter   = 24 * time.Hour
+)
+
+// LoginLimiter throttles login attempts per username and per IP address.
+// After freeLoginAttempts failed attempts next attempts are blocked for
+// exponentially growing time. After MaxFailures failed attempts (per IP
+// ipFailuresMultiplier times more) login is locked for Lockout duration and
+// alert is sent. State is kept in the database, so it survives restarts.
+type LoginLimiter struct {
+	DbClient    *db.Client
+	Alerter     notify.Notifier
+	MaxFailures int
+	Lockout     time.Duration
+}
+
+// Blocked checks whenever login attempts for given username or IP address are
+// currently blocked. Returns time left until attempts are allowed again.
+func (ll *LoginLimiter) Blocked(username, ipAddress string) (time.Duration, error) {
+	throttles, tErr := ll.DbClient.LoginThrottleByKeys(userThrottleKey(username), ipThrottleKey(ipAddress))
+	if tErr != nil {
+		return 0, tErr
+	}
+
+	now := time.Now().UTC()
+	var maxWait time.Duration
+	for _, t := range throttles {
+		if t.BlockedUntilTs == nil {
+			continue
+		}
+		blockedUntil, pErr := time.Parse(db.TimestampFormat, *t.BlockedUntilTs)
+		if pErr != nil {
+			log.Error().Err(pErr).Str("key", t.ThrottleKey).Msgf("[%s] cannot parse blocked until", authLimiterPrefix)
+			continue
+		}
+		if wait := blockedUntil.Sub(now); wait > maxWait {
+			maxWait = wait
+		}
+	}
+	return maxWait, nil
+}
+
+// RegisterFailure registers failed login attempt (either password or second
+// factor) and blocks further attempts if needed.
+func (ll *LoginLimiter) RegisterFailure(username, ipAddress string) {
+	ll.registerFailure(userThrottleKey(username), ll.MaxFailures)
+	ll.registerFailure(ipThrottleKey(ipAddress), ll.MaxFailures*ipFailuresMultiplier)
+}
+
+// RegisterSuccess resets failed attempts of the user after successful login.
+// Failures from IP address aren't reset, otherwise anyone with a valid account
+// could clear IP backoff between guesses of other users' passwords. Those
+// expire after failuresResetAfter.
+func (ll *LoginLimiter) RegisterSuccess(username string) {
+	ll.DbClient.LoginThrottleReset(userThrottleKey(username))
+}
+
+func (ll *LoginLimiter) registerFailure(key string, maxFailures int) {
+	now := time.Now().UTC()
+	resetBeforeTs := now.Add(-failuresResetAfter).Format(db.TimestampFormat)
+	failedCount, fErr := ll.DbClient.LoginThrottleRegisterFailure(key, now.Format(db.TimestampFormat), resetBeforeTs)
+	if fErr != nil {
+		return
+	}
+
+	delay, isLockout := loginBackoff(failedCount, maxFailures, ll.Lockout)
+	if delay == 0 {
+		return
+	}
+	blockErr := ll.DbClient.LoginThrottleBlock(key, now.Add(delay).Format(db.TimestampFormat))
+	if blockErr != nil {
+		return
+	}
+
+	log.Warn().Str("key", key).Int("failedCount", failedCount).Dur("delay", delay).
+		Msgf("[%s] login attempts blocked", authLimiterPrefix)
+	if isLockout {
+		msg := fmt.Sprintf("[login] Lockout of [%s] for %v after %d failed login attempts", key, delay, failedCount)
+		if sendErr := ll.Alerter.Notify(notify.Message{Event: notify.EventSecurity, Text: msg}); sendErr != nil {
+			log.Error().Err(sendErr).Msgf("[%s] cannot send lockout alert", authLimiterPrefix)
+		}
+	}
+}
+
+// Calculates for how long login attempts should be blocked after given number
+// of failed attempts. The second value is true, when it's lockout.
+func loginBackoff(failedCount, maxFailures int, lockout time.Duration) (time.Duration, bool) {
+	if failedCount >= maxFailures {
+		return lockout, true
+	}
+	if failedCount < freeLoginAttempts {
+		return 0, false
+	}
+	delay := baseLoginBackoff << (failedCount - freeLoginAttempts)
+	if delay > lockout || delay <= 0 {
+		return lockout, false
+	}
+	return delay, false
+}
+
+func userThrottleKey(username string) string {
+	return "user:" + username
+}
+
+func ipThrottleKey(ipAddress string) string {
+	return "ip:" + ipAddress
+}
@@ -0,0 +1,44 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	const maxFailures = 10
+	lockout := 15 * time.Minute
+
+	testCases := []struct {
+		failedCount int
+		delay       time.Duration
+		isLockout   bool
+	}{
+		{1, 0, false},
+		{2, 0, false},
+		{3, 1 * time.Second, false},
+		{4, 2 * time.Second, false},
+		{5, 4 * time.Second, false},
+		{9, 64 * time.Second, false},
+		{10, lockout, true},
+		{11, lockout, true},
+	}
+
+	for _, tc := range testCases {
+		delay, isLockout := loginBackoff(tc.failedCount, maxFailures, lockout)
+		if delay != tc.delay || isLockout != tc.isLockout {
+			t.Errorf("for %d failed attempts expected (%v, %v), got (%v, %v)",
+				tc.failedCount, tc.delay, tc.isLockout, delay, isLockout)
+		}
+	}
+}
+
+func TestLoginBackoffIsCappedByLockout(t *testing.T) {
+	delay, isLockout := loginBackoff(40, 100, time.Minute)
+	if delay != time.Minute {
+		t.Errorf("expected delay capped at lockout duration, got %v", delay)
+	}
+	if isLockout {
+		t.Error("expected capped delay not to be treated as lockout")
+	}
+}
@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+const argon2idPrefix = "$argon2id$"
+
+var ErrIncorrectPasswordHash = errors.New("incorrect format of encoded password hash")
+
+// Argon2Params are parameters of Argon2id key derivation function. Those are
+// encoded together with the hash, so hashes created with older parameters can
+// still be verified.
+type Argon2Params struct {
+	Memory      uint32 // in KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params are parameters used for hashing new passwords. Those
+// follows RFC 9106 second recommended option, with lower parallelism, because
+// HomeApp runs on rather small machines.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024,
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16,
+	KeyLength:   32,
+}
+
+// HashPassword hashes given password using Argon2id with fresh random salt.
+// Hash is returned in encoded form (including parameters and salt) together
+// with the salt alone in base64.
+func HashPassword(password string) (string, string, error) {
+	return hashPasswordWithParams(password, DefaultArgon2Params)
+}
+
+func hashPasswordWithParams(password string, params Argon2Params) (string, string, error) {
+	salt := make([]byte, params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", "", fmt.Errorf("cannot generate salt: %w", err)
+	}
+
+	key := argon2.IDKey([]byte(password), salt, params.Iterations, params.Memory, params.Parallelism,
+		params.KeyLength)
+
+	saltEncoded := base64.RawStdEncoding.EncodeToString(salt)
+	encoded := fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version, params.Memory,
+		params.Iterations, params.Parallelism, saltEncoded, base64.RawStdEncoding.EncodeToString(key))
+
+	return encoded, saltEncoded, nil
+}
+
+// verifyPassword compares given password with stored password hash. Besides
+// Argon2id encoded hashes it also supports legacy SHA256(password+salt)
+// hashes. The second returned value says whenever stored hash should be
+// replaced by fresh one with default parameters.
+func verifyPassword(password, passwordHashed, salt string) (bool, bool, error) {
+	if !isArgon2idHash(passwordHashed) {
+		legacyHashed := legacyHashedPassword(password, salt)
+		isValid := subtle.ConstantTimeCompare([]byte(legacyHashed), []byte(passwordHashed)) == 1
+		return isValid, true, nil
+	}
+
+	params, saltBytes, key, decErr := decodeArgon2idHash(passwordHashed)
+	if decErr != nil {
+		return false, false, decErr
+	}
+
+	givenKey := argon2.IDKey([]byte(password), saltBytes, params.Iterations, params.Memory,
+		params.Parallelism, params.KeyLength)
+	if subtle.ConstantTimeCompare(givenKey, key) != 1 {
+		return false, false, nil
+	}
+
+	return true, params != DefaultArgon2Params, nil
+}
+
+// Decodes hash in form of $argon2id$v=19$m=65536,t=3,p=2$salt$key.
+func decodeArgon2idHash(encoded string) (Argon2Params, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return Argon2Params{}, nil, nil, ErrIncorrectPasswordHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return Argon2Params{}, nil, nil, ErrIncorrectPasswordHash
+	}
+	if version != argon2.Version {
+		return Argon2Params{}, nil, nil, fmt.Errorf("unsupported argon2 version: %d", version)
+	}
+
+	var params Argon2Params
+	_, pErr := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Iterations, &params.Parallelism)
+	if pErr != nil {
+		return Argon2Params{}, nil, nil, ErrIncorrectPasswordHash
+	}
+
+	salt, sErr := base64.RawStdEncoding.DecodeString(parts[4])
+	if sErr != nil {
+		return Argon2Params{}, nil, nil, ErrIncorrectPasswordHash
+	}
+	key, kErr := base64.RawStdEncoding.DecodeString(parts[5])
+	if kErr != nil {
+		return Argon2Params{}, nil, nil, ErrIncorrectPasswordHash
+	}
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
+
+func isArgon2idHash(passwordHashed string) bool {
+	return strings.HasPrefix(passwordHashed, argon2idPrefix)
+}
+
+// Legacy password hashing used before Argon2id. Left only to verify and
+// upgrade not yet migrated users.
+func legacyHashedPassword(givenPassword, salt string) string {
+	givenPassHashed := sha256.Sum256([]byte(givenPassword + salt))
+	return fmt.Sprintf("%x", givenPassHashed)
+}
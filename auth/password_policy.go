@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+const (
+	minPasswordLength      = 10
+	maxPasswordLength      = 128
+	passphraseLength       = 16 // long passwords don't need mixed characters
+	minPasswordCharClasses = 2
+)
+
+var (
+	ErrPasswordTooShort      = fmt.Errorf("password should have at least %d characters", minPasswordLength)
+	ErrPasswordTooLong       = fmt.Errorf("password should have at most %d characters", maxPasswordLength)
+	ErrPasswordTooSimple     = fmt.Errorf("password shorter than %d characters should mix letters, digits or other characters", passphraseLength)
+	ErrPasswordHasUsername   = errors.New("password should not contain username")
+	ErrPasswordTooCommon     = errors.New("password is too common")
+	ErrPasswordRepeatedChars = errors.New("password should not consist of single repeated character")
+)
+
+// The most common passwords which are long enough to pass other checks.
+var commonPasswords = map[string]struct{}{
+	"123456789a":       {},
+	"1q2w3e4r5t":       {},
+	"1qaz2wsx3edc":     {},
+	"password12":       {},
+	"password123":      {},
+	"password1234":     {},
+	"qwerty1234":       {},
+	"qwerty12345":      {},
+	"qwertyuiop123":    {},
+	"iloveyou123":      {},
+	"administrator1":   {},
+	"homeapp123":       {},
+	"letmein123":       {},
+	"welcome123":       {},
+	"abc123456789":     {},
+	"passw0rd123":      {},
+	"changeme123":      {},
+	"q1w2e3r4t5":       {},
+	"asdfghjkl1":       {},
+	"trustno1234":      {},
+	"superman123":      {},
+	"football123":      {},
+	"monkey123456":     {},
+	"dragon123456":     {},
+	"sunshine123":      {},
+	"princess123":      {},
+	"passwordpassword": {},
+}
+
+// ValidatePassword checks if password meets minimum strength policy:
+//   - between minPasswordLength and maxPasswordLength characters,
+//   - mix of at least two character classes (letters, digits, other) unless
+//     it's at least passphraseLength characters long,
+//   - not a single repeated character, not containing the username and not
+//     one of the common passwords.
+func ValidatePassword(username, password string) error {
+	length := len([]rune(password))
+	if length < minPasswordLength {
+		return ErrPasswordTooShort
+	}
+	if length > maxPasswordLength {
+		return ErrPasswordTooLong
+	}
+	if firstRune := []rune(password)[0]; strings.Count(password, string(firstRune)) == length {
+		return ErrPasswordRepeatedChars
+	}
+
+	lowered := strings.ToLower(password)
+	if username = strings.ToLower(strings.TrimSpace(username)); username != "" && strings.Contains(lowered, username) {
+		return ErrPasswordHasUsername
+	}
+	if _, isCommon := commonPasswords[lowered]; isCommon {
+		return ErrPasswordTooCommon
+	}
+	if length < passphraseLength && passwordCharClasses(password) < minPasswordCharClasses {
+		return ErrPasswordTooSimple
+	}
+	return nil
+}
+
+// Counts character classes (letters, digits, other) used in the password.
+func passwordCharClasses(password string) int {
+	var hasLetter, hasDigit, hasOther bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLetter(r):
+			hasLetter = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasOther = true
+		}
+	}
+
+	classes := 0
+	for _, has := range []bool{hasLetter, hasDigit, hasOther} {
+		if has {
+			classes++
+		}
+	}
+	return classes
+}
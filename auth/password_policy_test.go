@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidatePassword(t *testing.T) {
+	testCases := []struct {
+		username string
+		password string
+		expected error
+	}{
+		{"john", "short1!", ErrPasswordTooShort},
+		{"john", strings.Repeat("a1", 65), ErrPasswordTooLong},
+		{"john", "aaaaaaaaaaaa", ErrPasswordRepeatedChars},
+		{"john", "żżżżżżżżżżżż", ErrPasswordRepeatedChars},
+		{"john", "JOHN-secret-42", ErrPasswordHasUsername},
+		{"john", "Password123", ErrPasswordTooCommon},
+		{"john", "onlyletters", ErrPasswordTooSimple},
+		{"john", "1234567890", ErrPasswordTooSimple},
+		{"john", "letters4digits", nil},
+		{"john", "correct horse battery", nil},
+		{"john", "longpassphrasewithoutdigits", nil},
+		{"", "anyUser-123", nil},
+	}
+
+	for _, tc := range testCases {
+		if err := ValidatePassword(tc.username, tc.password); err != tc.expected {
+			t.Errorf("for password %q expected %v, got %v", tc.password, tc.expected, err)
+		}
+	}
+}
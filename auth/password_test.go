@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	hashed, salt, err := HashPassword("password")
+	if err != nil {
+		t.Fatalf("cannot hash password: %v", err)
+	}
+	if !strings.HasPrefix(hashed, "$argon2id$v=19$m=65536,t=3,p=2$") {
+		t.Errorf("unexpected encoded hash format: %s", hashed)
+	}
+	if !strings.Contains(hashed, "$"+salt+"$") {
+		t.Errorf("expected salt [%s] to be encoded in the hash [%s]", salt, hashed)
+	}
+
+	isValid, needsRehash, vErr := verifyPassword("password", hashed, salt)
+	if vErr != nil {
+		t.Fatalf("cannot verify password: %v", vErr)
+	}
+	if !isValid {
+		t.Error("expected password to be valid")
+	}
+	if needsRehash {
+		t.Error("hash with default parameters should not need rehash")
+	}
+
+	isValid, _, vErr = verifyPassword("Password", hashed, salt)
+	if vErr != nil {
+		t.Fatalf("cannot verify password: %v", vErr)
+	}
+	if isValid {
+		t.Error("expected different password to be invalid")
+	}
+}
+
+func TestHashPasswordUniqueSalt(t *testing.T) {
+	hashed1, _, _ := HashPassword("password")
+	hashed2, _, _ := HashPassword("password")
+	if hashed1 == hashed2 {
+		t.Error("expected different hashes for the same password because of random salt")
+	}
+}
+
+func TestVerifyPasswordLegacy(t *testing.T) {
+	// User from sql/mock_data.sql
+	const hashed = "e5ad54cf823a8de54b9ed452523567720cd03f3b60af5af38d81997151bdae8f"
+	const salt = "21sadG4#aVB"
+
+	isValid, needsRehash, err := verifyPassword("password", hashed, salt)
+	if err != nil {
+		t.Fatalf("cannot verify legacy password: %v", err)
+	}
+	if !isValid {
+		t.Error("expected legacy password to be valid")
+	}
+	if !needsRehash {
+		t.Error("expected legacy hash to need rehash")
+	}
+
+	isValid, _, _ = verifyPassword("wrong", hashed, salt)
+	if isValid {
+		t.Error("expected wrong legacy password to be invalid")
+	}
+}
+
+func TestVerifyPasswordOutdatedParams(t *testing.T) {
+	params := DefaultArgon2Params
+	params.Iterations = 1
+	hashed, salt, err := hashPasswordWithParams("password", params)
+	if err != nil {
+		t.Fatalf("cannot hash password: %v", err)
+	}
+
+	isValid, needsRehash, vErr := verifyPassword("password", hashed, salt)
+	if vErr != nil {
+		t.Fatalf("cannot verify password: %v", vErr)
+	}
+	if !isValid {
+		t.Error("expected password to be valid")
+	}
+	if !needsRehash {
+		t.Error("expected hash with outdated parameters to need rehash")
+	}
+}
+
+func TestDecodeArgon2idHashNegative(t *testing.T) {
+	testCases := [...]string{
+		"$argon2id$",
+		"$argon2id$v=19$m=65536,t=3,p=2$salt",
+		"$argon2id$v=18$m=65536,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x,t=3,p=2$c2FsdA$a2V5",
+		"$argon2id$v=19$m=65536,t=3,p=2$!!!$a2V5",
+	}
+	for _, input := range testCases {
+		_, _, _, err := decodeArgon2idHash(input)
+		if err == nil {
+			t.Errorf("expected decoding failure for [%s]", input)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"homeApp/db"
+	"homeApp/rand"
+)
+
+const (
+	PendingCookieName       = "pending2fa"
+	pendingLoginTtl         = 5 * time.Minute
+	pendingLoginMaxAttempts = 5
+	pendingLoginIdLength    = 32
+)
+
+// Statuses of pending login which second factor is verified in the
+// background (providers which don't need user response typed in).
+const (
+	PendingStatusWaiting   = "waiting"
+	PendingStatusConfirmed = "confirmed"
+	PendingStatusFailed    = "failed"
+	PendingStatusExpired   = "expired"
+)
+
+// Login attempt which passed password check and waits for second factor.
+type pendingLogin struct {
+	User      db.User
+	Challenge TwoFactorChallenge
+	ExpireTs  time.Time
+	Attempts  int
+	Status    string
+}
+
+// PendingLogins keeps in memory login attempts between password check and
+// second factor verification. Those are identified by random ID stored in
+// PendingCookieName cookie.
+type PendingLogins struct {
+	sync.Mutex
+	logins map[string]*pendingLogin
+}
+
+func NewPendingLogins() *PendingLogins {
+	return &PendingLogins{logins: make(map[string]*pendingLogin)}
+}
+
+// Adds new pending login and returns its ID. Expired pending logins are
+// removed on the way.
+func (pl *PendingLogins) add(user db.User, challenge TwoFactorChallenge) string {
+	pl.Lock()
+	defer pl.Unlock()
+
+	now := time.Now()
+	for id, login := range pl.logins {
+		if now.After(login.ExpireTs) {
+			delete(pl.logins, id)
+		}
+	}
+
+	id := rand.AlphanumStr(pendingLoginIdLength)
+	pl.logins[id] = &pendingLogin{
+		User:      user,
+		Challenge: challenge,
+		ExpireTs:  now.Add(pendingLoginTtl),
+		Status:    PendingStatusWaiting,
+	}
+	return id
+}
+
+// Gets not expired pending login.
+func (pl *PendingLogins) get(id string) (pendingLogin, bool) {
+	pl.Lock()
+	defer pl.Unlock()
+
+	login, exists := pl.logins[id]
+	if !exists {
+		return pendingLogin{}, false
+	}
+	if time.Now().After(login.ExpireTs) {
+		delete(pl.logins, id)
+		return pendingLogin{}, false
+	}
+	return *login, true
+}
+
+// Registers failed verification of pending login. Returns number of attempts
+// left. Pending login is removed once there are no attempts left.
+func (pl *PendingLogins) failedAttempt(id string) int {
+	pl.Lock()
+	defer pl.Unlock()
+
+	login, exists := pl.logins[id]
+	if !exists {
+		return 0
+	}
+	login.Attempts++
+	attemptsLeft := pendingLoginMaxAttempts - login.Attempts
+	if attemptsLeft <= 0 {
+		delete(pl.logins, id)
+	}
+	return attemptsLeft
+}
+
+// Sets status of background verification of pending login.
+func (pl *PendingLogins) setStatus(id, status string) {
+	pl.Lock()
+	defer pl.Unlock()
+
+	if login, exists := pl.logins[id]; exists {
+		login.Status = status
+	}
+}
+
+// Removes all pending logins of given user, e.g. when the user is disabled.
+func (pl *PendingLogins) removeUser(userId int) {
+	pl.Lock()
+	defer pl.Unlock()
+	for id, login := range pl.logins {
+		if login.User.UserId == userId {
+			delete(pl.logins, id)
+		}
+	}
+}
+
+func (pl *PendingLogins) remove(id string) {
+	pl.Lock()
+	defer pl.Unlock()
+	delete(pl.logins, id)
+}
@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"net/url"
+	"testing"
+	"time"
+
+	"homeApp/db"
+)
+
+func TestPendingLoginExpiry(t *testing.T) {
+	testCases := []struct {
+		expireIn time.Duration
+		exists   bool
+	}{
+		{pendingLoginTtl, true},
+		{time.Second, true},
+		{-time.Second, false},
+		{-pendingLoginTtl, false},
+	}
+
+	for _, tc := range testCases {
+		pl := NewPendingLogins()
+		id := pl.add(db.User{UserId: 1}, TwoFactorChallenge{Method: TwoFactorTotp})
+		pl.logins[id].ExpireTs = time.Now().Add(tc.expireIn)
+
+		if _, exists := pl.get(id); exists != tc.exists {
+			t.Errorf("[%v] expected pending login exists=%v, got %v", tc.expireIn, tc.exists, exists)
+		}
+		if _, isKept := pl.logins[id]; isKept != tc.exists {
+			t.Errorf("[%v] expected expired pending login to be removed", tc.expireIn)
+		}
+	}
+}
+
+func TestPendingLoginExpiredRemovedOnAdd(t *testing.T) {
+	pl := NewPendingLogins()
+	expiredId := pl.add(db.User{UserId: 1}, TwoFactorChallenge{})
+	pl.logins[expiredId].ExpireTs = time.Now().Add(-time.Second)
+	activeId := pl.add(db.User{UserId: 2}, TwoFactorChallenge{})
+
+	pl.add(db.User{UserId: 3}, TwoFactorChallenge{})
+	if _, exists := pl.logins[expiredId]; exists {
+		t.Error("expected expired pending login to be removed")
+	}
+	if _, exists := pl.logins[activeId]; !exists {
+		t.Error("expected active pending login to be kept")
+	}
+}
+
+func TestPendingLoginAttemptLimit(t *testing.T) {
+	pl := NewPendingLogins()
+	id := pl.add(db.User{UserId: 1}, TwoFactorChallenge{Method: TwoFactorTotp})
+
+	for attempt := 1; attempt <= pendingLoginMaxAttempts; attempt++ {
+		attemptsLeft := pl.failedAttempt(id)
+		if attemptsLeft != pendingLoginMaxAttempts-attempt {
+			t.Errorf("after %d failed attempts expected %d left, got %d", attempt,
+				pendingLoginMaxAttempts-attempt, attemptsLeft)
+		}
+		if _, exists := pl.get(id); exists != (attemptsLeft > 0) {
+			t.Errorf("after %d failed attempts expected pending login exists=%v", attempt, attemptsLeft > 0)
+		}
+	}
+	if attemptsLeft := pl.failedAttempt(id); attemptsLeft != 0 {
+		t.Errorf("expected no attempts left for removed pending login, got %d", attemptsLeft)
+	}
+}
+
+func TestLogin2FAAttemptLimit(t *testing.T) {
+	ua := newTestUserAuth(t)
+	ua.TwoFactor = newTestTwoFactor(t, ua.DbClient)
+	hm := newTestHandlerManager(ua)
+	user := addTestUser(t, ua.DbClient, "alice", "alice password 1")
+	secret := setTestTwoFactor(t, ua.TwoFactor, user.UserId, TwoFactorTotp)
+
+	resp := postForm(hm.Login, "/login", url.Values{"login": {"alice"}, "pass": {"alice password 1"}})
+	pending := responseCookie(resp, PendingCookieName)
+	if pending == nil {
+		t.Fatal("expected pending login cookie after password check")
+	}
+
+	incorrectCode := url.Values{"otp": {hotpCode(secret, totpCounter(time.Now())+10)}}
+	for attempt := 1; attempt < pendingLoginMaxAttempts; attempt++ {
+		resp = postForm(hm.Login2FA, "/login/2fa", incorrectCode, pending)
+		if location := resp.Header.Get("Location"); location != "/login/2fa?failed=1" {
+			t.Errorf("after %d failed attempts expected redirect back to 2FA page, got %s", attempt, location)
+		}
+	}
+	resp = postForm(hm.Login2FA, "/login/2fa", incorrectCode, pending)
+	if location := resp.Header.Get("Location"); location != "/" {
+		t.Errorf("after last failed attempt expected redirect to login page, got %s", location)
+	}
+
+	// Correct code doesn't help once login has to be started over
+	correctCode := url.Values{"otp": {hotpCode(secret, totpCounter(time.Now()))}}
+	resp = postForm(hm.Login2FA, "/login/2fa", correctCode, pending)
+	if location := resp.Header.Get("Location"); location != "/" || responseCookie(resp, SessCookieName) != nil {
+		t.Errorf("expected no session after attempts limit, got redirect to %s", location)
+	}
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"homeApp/db"
+)
+
+// Modules which access is controlled by per user permissions.
+const (
+	ModuleFinance   = "finance"
+	ModuleDocuments = "documents"
+	ModuleBooks     = "books"
+	ModuleCounters  = "counters"
+)
+
+// Access levels to a module. Write access implies read access.
+const (
+	AccessNone  = "none"
+	AccessRead  = "read"
+	AccessWrite = "write"
+)
+
+var (
+	Modules = []string{ModuleCounters, ModuleFinance, ModuleDocuments, ModuleBooks}
+
+	ErrUnknownModule = errors.New("unknown module")
+	ErrUnknownAccess = errors.New("unknown access level")
+)
+
+type permissionsCtxKey struct{}
+
+// Permissions describes what given user can access. Admins can access every
+// module.
+type Permissions struct {
+	IsAdmin bool
+	Access  map[string]string // module -> access level
+}
+
+// Can checks whenever user has at least given access level to the module.
+func (p Permissions) Can(module, access string) bool {
+	if p.IsAdmin {
+		return true
+	}
+	switch p.Access[module] {
+	case AccessWrite:
+		return access == AccessRead || access == AccessWrite
+	case AccessRead:
+		return access == AccessRead
+	}
+	return false
+}
+
+// CanRead checks whenever user has read access to the module.
+func (p Permissions) CanRead(module string) bool {
+	return p.Can(module, AccessRead)
+}
+
+// Restrict limits permissions to given API token scopes. Scope never gives
+// more access than the user has. Admin role is not passed to API tokens.
+func (p Permissions) Restrict(scopes map[string]string) Permissions {
+	restricted := Permissions{Access: make(map[string]string, len(scopes))}
+	for module, access := range scopes {
+		switch {
+		case p.Can(module, access):
+			restricted.Access[module] = access
+		case access == AccessWrite && p.Can(module, AccessRead):
+			restricted.Access[module] = AccessRead
+		}
+	}
+	return restricted
+}
+
+// UserPermissions reads roles and module permissions of given user.
+func UserPermissions(dbClient *db.Client, userId int) (Permissions, error) {
+	isAdmin, aErr := UserAdmin{DbClient: dbClient}.IsAdmin(userId)
+	if aErr != nil {
+		return Permissions{}, aErr
+	}
+	userPermissions, pErr := dbClient.UserPermissions(userId)
+	if pErr != nil {
+		return Permissions{}, pErr
+	}
+
+	permissions := Permissions{IsAdmin: isAdmin, Access: make(map[string]string, len(userPermissions))}
+	for _, p := range userPermissions {
+		permissions.Access[p.Module] = p.Access
+	}
+	return permissions, nil
+}
+
+// PermissionsFromRequest gets permissions of logged in user which are put into
+// request context by CheckAuth middleware.
+func PermissionsFromRequest(r *http.Request) (Permissions, bool) {
+	permissions, ok := r.Context().Value(permissionsCtxKey{}).(Permissions)
+	return permissions, ok
+}
+
+func withPermissions(r *http.Request, permissions Permissions) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), permissionsCtxKey{}, permissions))
+}
+
+func isKnownModule(module string) bool {
+	for _, m := range Modules {
+		if m == module {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,72 @@
+package auth
+
+import "testing"
+
+func TestPermissionsCan(t *testing.T) {
+	permissions := Permissions{Access: map[string]string{
+		ModuleFinance:  AccessRead,
+		ModuleCounters: AccessWrite,
+	}}
+
+	testCases := []struct {
+		module   string
+		access   string
+		expected bool
+	}{
+		{ModuleFinance, AccessRead, true},
+		{ModuleFinance, AccessWrite, false},
+		{ModuleCounters, AccessRead, true},
+		{ModuleCounters, AccessWrite, true},
+		{ModuleDocuments, AccessRead, false},
+		{ModuleBooks, AccessWrite, false},
+		{ModuleFinance, "unknown", false},
+	}
+
+	for _, tc := range testCases {
+		if can := permissions.Can(tc.module, tc.access); can != tc.expected {
+			t.Errorf("for %s:%s expected %v, got %v", tc.module, tc.access, tc.expected, can)
+		}
+	}
+}
+
+func TestPermissionsAdminCanAccessEverything(t *testing.T) {
+	permissions := Permissions{IsAdmin: true}
+	for _, module := range Modules {
+		if !permissions.Can(module, AccessWrite) {
+			t.Errorf("expected admin to have write access to %s", module)
+		}
+	}
+}
+
+func TestPermissionsRestrict(t *testing.T) {
+	permissions := Permissions{Access: map[string]string{
+		ModuleFinance:  AccessRead,
+		ModuleCounters: AccessWrite,
+	}}
+
+	restricted := permissions.Restrict(map[string]string{
+		ModuleFinance:   AccessWrite, // user can only read
+		ModuleCounters:  AccessRead,
+		ModuleDocuments: AccessRead, // user has no access
+	})
+
+	if !restricted.Can(ModuleFinance, AccessRead) || restricted.Can(ModuleFinance, AccessWrite) {
+		t.Error("expected finance scope to be limited to read access")
+	}
+	if !restricted.Can(ModuleCounters, AccessRead) || restricted.Can(ModuleCounters, AccessWrite) {
+		t.Error("expected counters scope to be limited to read access")
+	}
+	if restricted.CanRead(ModuleDocuments) {
+		t.Error("expected no access to documents")
+	}
+}
+
+func TestPermissionsRestrictDropsAdmin(t *testing.T) {
+	restricted := Permissions{IsAdmin: true}.Restrict(map[string]string{ModuleBooks: AccessWrite})
+	if restricted.IsAdmin {
+		t.Error("expected admin role not to be passed to API token")
+	}
+	if !restricted.Can(ModuleBooks, AccessWrite) || restricted.CanRead(ModuleFinance) {
+		t.Error("expected access only to books")
+	}
+}
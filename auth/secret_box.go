@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"fmt"
+
+	"homeApp/rand"
+)
+
+const SecretBoxKeyLength = 32
+
+var ErrSecretBoxCiphertext = errors.New("ciphertext is too short")
+
+// SecretBox encrypts secrets stored in the database (like TOTP secrets) using
+// AES-256-GCM. Random nonce is prepended to each ciphertext.
+type SecretBox struct {
+	aead cipher.AEAD
+}
+
+// NewSecretBox creates SecretBox for given 32 bytes long key.
+func NewSecretBox(key []byte) (*SecretBox, error) {
+	if len(key) != SecretBoxKeyLength {
+		return nil, fmt.Errorf("secret box key should have %d bytes, got %d", SecretBoxKeyLength, len(key))
+	}
+	block, bErr := aes.NewCipher(key)
+	if bErr != nil {
+		return nil, bErr
+	}
+	aead, gErr := cipher.NewGCM(block)
+	if gErr != nil {
+		return nil, gErr
+	}
+	return &SecretBox{aead: aead}, nil
+}
+
+// Seal encrypts given plaintext.
+func (sb *SecretBox) Seal(plaintext []byte) []byte {
+	nonce := rand.Bytes(sb.aead.NonceSize())
+	return sb.aead.Seal(nonce, nonce, plaintext, nil)
+}
+
+// Open decrypts ciphertext produced by Seal.
+func (sb *SecretBox) Open(ciphertext []byte) ([]byte, error) {
+	nonceSize := sb.aead.NonceSize()
+	if len(ciphertext) < nonceSize {
+		return nil, ErrSecretBoxCiphertext
+	}
+	return sb.aead.Open(nil, ciphertext[:nonceSize], ciphertext[nonceSize:], nil)
+}
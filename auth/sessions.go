@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"time"
+
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authSessPrefix        = "auth/sessions"
+	sessionIdLength       = 32
+	maxUserAgentLength    = 512
+	lastSeenUpdateEachSec = 60
+)
+
+type tokenStatusCtxKey struct{}
+
+// ClientInfo describes client (browser) which started user session.
+type ClientInfo struct {
+	UserAgent string
+	IpAddress string
+}
+
+// ClientInfoFromRequest gets client information from HTTP request.
+func ClientInfoFromRequest(r *http.Request) ClientInfo {
+	userAgent := r.UserAgent()
+	if len(userAgent) > maxUserAgentLength {
+		userAgent = userAgent[:maxUserAgentLength]
+	}
+	ip, _, splitErr := net.SplitHostPort(r.RemoteAddr)
+	if splitErr != nil {
+		ip = r.RemoteAddr
+	}
+	return ClientInfo{UserAgent: userAgent, IpAddress: ip}
+}
+
+// TokenStatusFromRequest gets status of validated session token which is put
+// into request context by CheckAuth middleware.
+func TokenStatusFromRequest(r *http.Request) (TokenStatus, bool) {
+	tokenStatus, ok := r.Context().Value(tokenStatusCtxKey{}).(TokenStatus)
+	return tokenStatus, ok
+}
+
+func withTokenStatus(r *http.Request, tokenStatus TokenStatus) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), tokenStatusCtxKey{}, tokenStatus))
+}
+
+// NewSession starts new server-side session for given user and returns signed
+// session JWT. Session ID is put into "jti" claim. Sessions are not started
+// for disabled users.
+func (ua UserAuth) NewSession(userId int, client ClientInfo) (string, error) {
+	user, uErr := ua.DbClient.UserByUserId(userId)
+	if uErr != nil {
+		return "", uErr
+	}
+	if !user.IsActive {
+		return "", ErrUserDisabled
+	}
+
+	now := time.Now().UTC()
+	expireTs := now.Add(time.Duration(ua.JwtExpMinutes) * time.Minute)
+	session := db.Session{
+		SessionId:  rand.AlphanumStr(sessionIdLength),
+		UserId:     userId,
+		UserAgent:  client.UserAgent,
+		IpAddress:  client.IpAddress,
+		CsrfToken:  rand.AlphanumStr(csrfTokenLength),
+		CreateTs:   now.Format(db.TimestampFormat),
+		LastSeenTs: now.Format(db.TimestampFormat),
+		ExpireTs:   expireTs.Format(db.TimestampFormat),
+	}
+
+	insErr := ua.DbClient.SessionInsertNew(session)
+	if insErr != nil {
+		return "", insErr
+	}
+
+	userToken, tokenErr := ua.prepJwtString(userId, session.SessionId, expireTs)
+	if tokenErr != nil {
+		log.Error().Err(tokenErr).Int("userId", userId).Msgf("[%s] cannot sign user token", authSessPrefix)
+		return "", tokenErr
+	}
+
+	log.Info().Int("userId", userId).Str("ip", client.IpAddress).Msgf("[%s] started new session", authSessPrefix)
+	return userToken, nil
+}
+
+// RevokeSession revokes server-side session, so its token is no longer valid
+// even before expiration.
+func (ua UserAuth) RevokeSession(tokenStatus TokenStatus) error {
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	return ua.DbClient.SessionRevoke(tokenStatus.UserId, tokenStatus.SessionId, nowTs)
+}
+
+// Gets server-side session of the token if it exists, belongs to the token
+// user and is neither revoked nor expired. Otherwise nil is returned. Session
+// last seen timestamp is updated at most once per lastSeenUpdateEachSec.
+func (ua UserAuth) activeSession(tokenStatus TokenStatus) (*db.Session, error) {
+	session, sErr := ua.DbClient.SessionById(tokenStatus.SessionId)
+	if sErr == sql.ErrNoRows {
+		return nil, nil
+	}
+	if sErr != nil {
+		return nil, sErr
+	}
+
+	now := time.Now().UTC()
+	nowTs := now.Format(db.TimestampFormat)
+	if session.UserId != tokenStatus.UserId {
+		log.Error().Int("userId", tokenStatus.UserId).Int("sessionUserId", session.UserId).
+			Msgf("[%s] session belongs to different user", authSessPrefix)
+		return nil, nil
+	}
+	if session.RevokeTs != nil {
+		log.Warn().Int("userId", tokenStatus.UserId).Str("revokeTs", *session.RevokeTs).
+			Msgf("[%s] session was revoked", authSessPrefix)
+		return nil, nil
+	}
+	if session.ExpireTs <= nowTs {
+		log.Warn().Int("userId", tokenStatus.UserId).Str("expireTs", session.ExpireTs).
+			Msgf("[%s] session expired", authSessPrefix)
+		return nil, nil
+	}
+
+	lastSeen, lsErr := time.Parse(db.TimestampFormat, session.LastSeenTs)
+	if lsErr != nil || now.Sub(lastSeen) >= lastSeenUpdateEachSec*time.Second {
+		ua.DbClient.SessionUpdateLastSeen(session.SessionId, nowTs)
+	}
+
+	return &session, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"homeApp/db"
+)
+
+// Creates UserAuth on top of new test database.
+func newTestUserAuth(t *testing.T) UserAuth {
+	t.Helper()
+	dbClient := newTestDbClient(t)
+	keys, kErr := NewSigningKeys(dbClient, time.Hour, time.Hour)
+	if kErr != nil {
+		t.Fatal(kErr)
+	}
+	return UserAuth{
+		DbClient:      dbClient,
+		SigningKeys:   keys,
+		JwtExpMinutes: 10,
+		TwoFactor:     NewTwoFactor(dbClient, nil, nil, TwoFactorNone),
+	}
+}
+
+// Adds active user with given password.
+func addTestUser(t *testing.T, dbClient *db.Client, username, password string) db.User {
+	t.Helper()
+	hashed, salt, hErr := HashPassword(password)
+	if hErr != nil {
+		t.Fatal(hErr)
+	}
+	userId, iErr := dbClient.UserInsertNew(db.User{
+		Email:          username + "@example.com",
+		Username:       username,
+		PasswordHashed: hashed,
+		Salt:           salt,
+	}, nil)
+	if iErr != nil {
+		t.Fatal(iErr)
+	}
+	user, uErr := dbClient.UserByUserId(userId)
+	if uErr != nil {
+		t.Fatal(uErr)
+	}
+	return user
+}
+
+func TestSessionValidation(t *testing.T) {
+	ua := newTestUserAuth(t)
+	user := addTestUser(t, ua.DbClient, "alice", "alice password 1")
+	other := addTestUser(t, ua.DbClient, "bob", "bob password 1")
+	client := ClientInfo{UserAgent: "test", IpAddress: "10.0.0.1"}
+
+	newSession := func(ua UserAuth) string {
+		token, sErr := ua.NewSession(user.UserId, client)
+		if sErr != nil {
+			t.Fatalf("cannot start session: %v", sErr)
+		}
+		return token
+	}
+	expiredUa := ua
+	expiredUa.JwtExpMinutes = -1
+
+	testCases := []struct {
+		name    string
+		token   func() string
+		isValid bool
+	}{
+		{"active session", func() string { return newSession(ua) }, true},
+		{"revoked session", func() string {
+			token := newSession(ua)
+			status, _ := ua.IsJwtTokenValid(token)
+			if rErr := ua.RevokeSession(status); rErr != nil {
+				t.Fatalf("cannot revoke session: %v", rErr)
+			}
+			return token
+		}, false},
+		{"expired session", func() string { return newSession(expiredUa) }, false},
+		{"prolonged session", func() string {
+			status, _ := ua.IsJwtTokenValid(newSession(expiredUa))
+			token, pErr := ua.RegenerateJwt(status)
+			if pErr != nil {
+				t.Fatalf("cannot prolong session: %v", pErr)
+			}
+			return token
+		}, true},
+		{"session of other user", func() string {
+			status, _ := ua.IsJwtTokenValid(newSession(ua))
+			token, _ := ua.prepJwtString(other.UserId, status.SessionId, time.Now().Add(time.Hour))
+			return token
+		}, false},
+		{"unknown session", func() string {
+			token, _ := ua.prepJwtString(user.UserId, "unknown", time.Now().Add(time.Hour))
+			return token
+		}, false},
+	}
+
+	for _, tc := range testCases {
+		status, err := ua.IsJwtTokenValid(tc.token())
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %v", tc.name, err)
+			continue
+		}
+		if status.IsValid != tc.isValid {
+			t.Errorf("[%s] expected valid=%v, got %v", tc.name, tc.isValid, status.IsValid)
+		}
+		if status.IsValid && (status.UserId != user.UserId || status.CsrfToken == "") {
+			t.Errorf("[%s] unexpected token status: %+v", tc.name, status)
+		}
+	}
+}
+
+func TestSessionRevokeOnlyOwnSession(t *testing.T) {
+	ua := newTestUserAuth(t)
+	user := addTestUser(t, ua.DbClient, "alice", "alice password 1")
+	other := addTestUser(t, ua.DbClient, "bob", "bob password 1")
+
+	token, sErr := ua.NewSession(user.UserId, ClientInfo{})
+	if sErr != nil {
+		t.Fatalf("cannot start session: %v", sErr)
+	}
+	status, _ := ua.IsJwtTokenValid(token)
+
+	// Other user cannot revoke the session even with its ID
+	ua.RevokeSession(TokenStatus{UserId: other.UserId, SessionId: status.SessionId})
+	if afterStatus, _ := ua.IsJwtTokenValid(token); !afterStatus.IsValid {
+		t.Error("expected session to stay valid after revoke by other user")
+	}
+
+	if rErr := ua.RevokeSession(status); rErr != nil {
+		t.Fatalf("cannot revoke session: %v", rErr)
+	}
+	sessions, aErr := ua.DbClient.SessionsActive(user.UserId, time.Now().UTC().Format(db.TimestampFormat))
+	if aErr != nil || len(sessions) != 0 {
+		t.Errorf("expected no active sessions after revoke, got %d (error %v)", len(sessions), aErr)
+	}
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authKeysPrefix   = "auth/keys"
+	signingKeyLength = 64
+	keyIdLength      = 16
+)
+
+var ErrUnknownSigningKey = errors.New("unknown or expired JWT signing key")
+
+// SigningKeys keeps JWT signing keys which are persisted in the database, so
+// sessions survive application restarts. New tokens are signed by the current
+// key. After rotation previous keys are still accepted for verification during
+// the grace period. Keys are identified by "kid" JWT header.
+type SigningKeys struct {
+	sync.RWMutex
+	dbClient    *db.Client
+	rotateAfter time.Duration
+	gracePeriod time.Duration
+	current     signingKey
+	retired     []signingKey
+}
+
+type signingKey struct {
+	keyId    string
+	key      []byte
+	createTs time.Time
+	retireTs time.Time
+}
+
+// NewSigningKeys loads signing keys from the database. If there is no current
+// key or the current key is older than rotateAfter, then new key is generated
+// and stored.
+func NewSigningKeys(dbClient *db.Client, rotateAfter, gracePeriod time.Duration) (*SigningKeys, error) {
+	sk := SigningKeys{
+		dbClient:    dbClient,
+		rotateAfter: rotateAfter,
+		gracePeriod: gracePeriod,
+	}
+
+	loadErr := sk.load()
+	if loadErr != nil {
+		return nil, loadErr
+	}
+
+	if sk.current.keyId == "" || time.Since(sk.current.createTs) >= rotateAfter {
+		rotErr := sk.rotate()
+		if rotErr != nil {
+			return nil, rotErr
+		}
+	}
+
+	return &sk, nil
+}
+
+// Current returns key ID and the key which should be used for signing new
+// tokens.
+func (sk *SigningKeys) Current() (string, []byte) {
+	sk.RLock()
+	defer sk.RUnlock()
+	return sk.current.keyId, sk.current.key
+}
+
+// ByKeyId returns signing key of given ID, if it's either current key or it
+// was retired within the grace period.
+func (sk *SigningKeys) ByKeyId(keyId string) ([]byte, error) {
+	sk.RLock()
+	defer sk.RUnlock()
+
+	if keyId == sk.current.keyId {
+		return sk.current.key, nil
+	}
+	for _, key := range sk.retired {
+		if key.keyId == keyId && time.Since(key.retireTs) < sk.gracePeriod {
+			return key.key, nil
+		}
+	}
+	return nil, ErrUnknownSigningKey
+}
+
+// RotatePeriodically checks in a loop if current key should be rotated and
+// rotates it if needed. It's meant to be run in a separate goroutine.
+func (sk *SigningKeys) RotatePeriodically() {
+	for {
+		sk.RLock()
+		nextRotation := sk.current.createTs.Add(sk.rotateAfter)
+		sk.RUnlock()
+
+		time.Sleep(time.Until(nextRotation))
+
+		rotErr := sk.rotate()
+		if rotErr != nil {
+			log.Error().Err(rotErr).Msgf("[%s] JWT signing key rotation failed, retry in 1 minute", authKeysPrefix)
+			time.Sleep(time.Minute)
+		}
+	}
+}
+
+// Generates new key, persists it and retires the current one.
+func (sk *SigningKeys) rotate() error {
+	now := time.Now().UTC()
+	newKey := signingKey{
+		keyId:    rand.AlphanumStr(keyIdLength),
+		key:      rand.Bytes(signingKeyLength),
+		createTs: now,
+	}
+
+	dbKey := db.JwtSigningKey{
+		KeyId:      newKey.keyId,
+		SigningKey: newKey.key,
+		CreateTs:   now.Format(db.TimestampFormat),
+	}
+	deleteBefore := now.Add(-sk.gracePeriod).Format(db.TimestampFormat)
+	dbErr := sk.dbClient.JwtSigningKeyRotate(dbKey, deleteBefore)
+	if dbErr != nil {
+		return dbErr
+	}
+
+	sk.Lock()
+	defer sk.Unlock()
+	retired := make([]signingKey, 0, len(sk.retired)+1)
+	if sk.current.keyId != "" {
+		prev := sk.current
+		prev.retireTs = now
+		retired = append(retired, prev)
+	}
+	for _, key := range sk.retired {
+		if now.Sub(key.retireTs) < sk.gracePeriod {
+			retired = append(retired, key)
+		}
+	}
+	sk.current = newKey
+	sk.retired = retired
+
+	log.Info().Str("keyId", newKey.keyId).Int("retiredKeys", len(retired)).
+		Msgf("[%s] rotated JWT signing key", authKeysPrefix)
+	return nil
+}
+
+// Loads keys from the database.
+func (sk *SigningKeys) load() error {
+	dbKeys, dbErr := sk.dbClient.JwtSigningKeys()
+	if dbErr != nil {
+		return dbErr
+	}
+
+	for _, dbKey := range dbKeys {
+		createTs, cErr := time.Parse(db.TimestampFormat, dbKey.CreateTs)
+		if cErr != nil {
+			log.Warn().Err(cErr).Str("keyId", dbKey.KeyId).
+				Msgf("[%s] incorrect key creation timestamp, skipping", authKeysPrefix)
+			continue
+		}
+		key := signingKey{keyId: dbKey.KeyId, key: dbKey.SigningKey, createTs: createTs}
+
+		if dbKey.RetireTs == nil {
+			sk.current = key
+			continue
+		}
+		retireTs, rErr := time.Parse(db.TimestampFormat, *dbKey.RetireTs)
+		if rErr != nil {
+			log.Warn().Err(rErr).Str("keyId", dbKey.KeyId).
+				Msgf("[%s] incorrect key retire timestamp, skipping", authKeysPrefix)
+			continue
+		}
+		key.retireTs = retireTs
+		sk.retired = append(sk.retired, key)
+	}
+
+	log.Info().Str("currentKeyId", sk.current.keyId).Int("retiredKeys", len(sk.retired)).
+		Msgf("[%s] loaded JWT signing keys", authKeysPrefix)
+	return nil
+}
@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"homeApp/db"
+)
+
+// Creates database with the schema from sql/schema.sql in temporary directory.
+func newTestDbClient(t *testing.T) *db.Client {
+	t.Helper()
+	schema, rErr := os.ReadFile(filepath.Join("..", "sql", "schema.sql"))
+	if rErr != nil {
+		t.Fatal(rErr)
+	}
+	dbClient, cErr := db.NewClient(filepath.Join(t.TempDir(), "test.db"))
+	if cErr != nil {
+		t.Fatal(cErr)
+	}
+	if mErr := dbClient.Migrate(string(schema)); mErr != nil {
+		t.Fatalf("cannot create schema: %v", mErr)
+	}
+	return dbClient
+}
+
+func TestSigningKeysRotation(t *testing.T) {
+	dbClient := newTestDbClient(t)
+	keys, err := NewSigningKeys(dbClient, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("cannot create signing keys: %v", err)
+	}
+	firstKeyId, firstKey := keys.Current()
+	if firstKeyId == "" || len(firstKey) != signingKeyLength {
+		t.Fatalf("expected generated current key, got [%s] of %d bytes", firstKeyId, len(firstKey))
+	}
+
+	if rErr := keys.rotate(); rErr != nil {
+		t.Fatalf("cannot rotate keys: %v", rErr)
+	}
+	secondKeyId, _ := keys.Current()
+	if secondKeyId == firstKeyId {
+		t.Fatal("expected new current key after rotation")
+	}
+	if key, kErr := keys.ByKeyId(firstKeyId); kErr != nil || string(key) != string(firstKey) {
+		t.Errorf("expected previous key to be accepted during grace period, got error %v", kErr)
+	}
+
+	// Restart loads the same keys instead of generating new ones
+	reloaded, err := NewSigningKeys(dbClient, time.Hour, time.Hour)
+	if err != nil {
+		t.Fatalf("cannot reload signing keys: %v", err)
+	}
+	if keyId, _ := reloaded.Current(); keyId != secondKeyId {
+		t.Errorf("expected current key [%s] after reload, got [%s]", secondKeyId, keyId)
+	}
+	if _, kErr := reloaded.ByKeyId(firstKeyId); kErr != nil {
+		t.Errorf("expected retired key to be loaded, got error %v", kErr)
+	}
+
+	// Current key older than rotateAfter is rotated on start
+	rotated, err := NewSigningKeys(dbClient, 0, time.Hour)
+	if err != nil {
+		t.Fatalf("cannot reload signing keys: %v", err)
+	}
+	if keyId, _ := rotated.Current(); keyId == secondKeyId {
+		t.Error("expected outdated current key to be rotated on start")
+	}
+}
+
+func TestSigningKeysGracePeriod(t *testing.T) {
+	const gracePeriod = time.Hour
+	now := time.Now()
+	keys := SigningKeys{
+		gracePeriod: gracePeriod,
+		current:     signingKey{keyId: "current", key: []byte("c"), createTs: now},
+		retired: []signingKey{
+			{keyId: "recent", key: []byte("r"), retireTs: now.Add(-time.Minute)},
+			{keyId: "old", key: []byte("o"), retireTs: now.Add(-gracePeriod - time.Minute)},
+		},
+	}
+
+	testCases := []struct {
+		keyId      string
+		isAccepted bool
+	}{
+		{"current", true},
+		{"recent", true},
+		{"old", false},
+		{"unknown", false},
+		{"", false},
+	}
+
+	for _, tc := range testCases {
+		_, err := keys.ByKeyId(tc.keyId)
+		if (err == nil) != tc.isAccepted {
+			t.Errorf("[%s] expected accepted=%v, got error %v", tc.keyId, tc.isAccepted, err)
+		}
+		if err != nil && err != ErrUnknownSigningKey {
+			t.Errorf("[%s] expected ErrUnknownSigningKey, got %v", tc.keyId, err)
+		}
+	}
+}
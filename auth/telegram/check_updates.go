@@ -1,11 +1,13 @@
 package telegram
 
 import (
+	"crypto/subtle"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"strings"
 	"time"
+	"unicode"
 
 	"github.com/rs/zerolog/log"
 )
@@ -14,64 +16,146 @@ const (
 	teleUpdatesPrefix          = "telegram/updates"
 	updatesLimit               = 25
 	updatesQueryTimeoutSeconds = 10
-	maxNumberOfRetries         = 20
 	secondsBeforeRetry         = 5
+	maxFailedAttempts          = 3
 )
 
-var ErrTelegramUser2FATimeout = errors.New("timeout, there wasn't correct user input on Telegram channel")
+var (
+	ErrTelegramUser2FATimeout         = errors.New("timeout, there wasn't correct user input on Telegram channel")
+	ErrTelegramUser2FATooManyAttempts = errors.New("too many incorrect inputs on Telegram channel")
+)
+
+// Result of single check of recent Telegram updates.
+type matchResult struct {
+	isMatched       bool
+	failedUpdateIds []int
+}
 
 // CheckMessageWithPattern periodically (by secondsBeforeRetry) reads Telegram
 // updates (last updatesLimit messages from the Telegram channel) and check if
-// given pattern occurs within text of those updates. Messages only from
-// specific channel (c.channelId) are checked. Updates are loaded until either
-// success or "twoFaTimeout" timeout.
+// text of any of those updates is exactly the given pattern (surrounding
+// whitespaces are ignored). Messages only from specific channel (c.channelId)
+// posted after the check started are taken into account. Other messages which
+// look like a code count as failed attempts of this check, unless those are
+// codes of other checks in progress (concurrent logins). Updates are loaded
+// until either success, maxFailedAttempts failed attempts or "twoFaTimeout"
+// timeout. When the client receives updates already (StartPolling or
+// StartWebhook), it waits for updates passed by the dispatcher instead.
 func (c *Client) CheckMessageWithPattern(pattern string, twoFaTimeout time.Duration) (bool, error) {
 	log.Info().Msgf("[%s] start checking telegram chat messages", teleUpdatesPrefix)
+	defer c.addPendingCode(pattern)()
+	if c.receivesUpdates.Load() {
+		return c.waitForMessageWithPattern(pattern, twoFaTimeout)
+	}
 	startTs := time.Now()
 	startTsUnixSeconds := int(startTs.UnixMilli() / 1000)
-	isMatchedChan := make(chan bool)
-	errChan := make(chan error)
+	// Buffered, so goroutine started just before timeout doesn't leak
+	matchChan := make(chan matchResult, 1)
+	errChan := make(chan error, 1)
 	timeout := time.After(twoFaTimeout)
+	failedUpdateIds := make(map[int]struct{})
 
 	lastMessageUpdateId := c.getLastUpdateId()
-	go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, isMatchedChan, errChan)
+	go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, matchChan, errChan)
 
 	for {
 		select {
 		case <-timeout:
-			// User 2FA action timeouted
-			log.Error().Err(ErrTelegramUser2FATimeout).Dur("duration", time.Since(startTs)).
-				Msgf("[%s] timeout", teleUpdatesPrefix)
-			c.SendMessage(fmt.Sprintf("[2FA] Timeout! I didn't receive input [%s] from user. Please try again.", pattern))
-			return false, ErrTelegramUser2FATimeout
+			return false, c.twoFaTimeout(startTs)
 
 		case err := <-errChan:
 			// An error during single Telegram communication, retrying after a pause
 			log.Error().Err(err).Msgf("[%s] error while checking Telegram updates", teleUpdatesPrefix)
-			time.Sleep(secondsBeforeRetry * time.Second)
-			go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, isMatchedChan, errChan)
-
-		case isMatched := <-isMatchedChan:
-			// got result, if pattern is matched true is returned else we retry until timeout or other success
-			if isMatched {
-				log.Info().Str("pattern", pattern).Msgf("[%s] found matching pattern in Telegram updates", teleUpdatesPrefix)
-				c.SendMessage(fmt.Sprintf("[2FA] [%s] confirmed!", pattern))
-				return true, nil
-			} else {
-				log.Info().Str("pattern", pattern).
-					Msgf("[%s] parsed Telegram updates but pattern was not matched", teleUpdatesPrefix)
-				time.Sleep(secondsBeforeRetry * time.Second)
-				go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, isMatchedChan, errChan)
+			time.Sleep(c.retryDelay)
+			go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, matchChan, errChan)
+
+		case result := <-matchChan:
+			// got result, if pattern is matched true is returned else we retry until timeout or other success
+			if result.isMatched {
+				return c.twoFaConfirmed()
+			}
+
+			// The same updates are read again on each retry
+			for _, updateId := range result.failedUpdateIds {
+				failedUpdateIds[updateId] = struct{}{}
+			}
+			if len(failedUpdateIds) >= maxFailedAttempts {
+				return false, c.twoFaTooManyAttempts(startTs, len(failedUpdateIds))
+			}
+
+			log.Info().Int("failedAttempts", len(failedUpdateIds)).
+				Msgf("[%s] parsed Telegram updates but code was not matched", teleUpdatesPrefix)
+			time.Sleep(c.retryDelay)
+			go c.getUpdatesWithPattern(startTsUnixSeconds, lastMessageUpdateId, pattern, matchChan, errChan)
+		}
+	}
+}
+
+// Waits for the pattern in updates received by polling or webhook. Works the
+// same way as CheckMessageWithPattern, but doesn't call getUpdates itself.
+func (c *Client) waitForMessageWithPattern(pattern string, twoFaTimeout time.Duration) (bool, error) {
+	startTs := time.Now()
+	startTsUnixSeconds := int(startTs.UnixMilli() / 1000)
+	updatesChan := make(chan Update, updatesLimit)
+	unsubscribe := c.Subscribe(func(update Update) {
+		select {
+		case updatesChan <- update:
+		default:
+			log.Warn().Int("updateId", update.UpdateID).Msgf("[%s] 2FA update skipped", teleUpdatesPrefix)
+		}
+	})
+	defer unsubscribe()
+
+	timeout := time.After(twoFaTimeout)
+	failedAttempts := 0
+	for {
+		select {
+		case <-timeout:
+			return false, c.twoFaTimeout(startTs)
+
+		case update := <-updatesChan:
+			if update.ChannelPost != nil && update.ChannelPost.IsCommand() {
+				continue // bot commands are not 2FA attempts
+			}
+			result := matchExactMessageText(startTsUnixSeconds, pattern, c.channelId, []Update{update},
+				c.isOtherPendingCode)
+			if result.isMatched {
+				return c.twoFaConfirmed()
+			}
+			failedAttempts += len(result.failedUpdateIds)
+			if failedAttempts >= maxFailedAttempts {
+				return false, c.twoFaTooManyAttempts(startTs, failedAttempts)
 			}
 		}
 	}
 }
 
+func (c *Client) twoFaConfirmed() (bool, error) {
+	log.Info().Msgf("[%s] found matching code in Telegram updates", teleUpdatesPrefix)
+	c.SendMessage("[2FA] Login confirmed!")
+	return true, nil
+}
+
+func (c *Client) twoFaTimeout(startTs time.Time) error {
+	// User 2FA action timeouted
+	log.Error().Err(ErrTelegramUser2FATimeout).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] timeout", teleUpdatesPrefix)
+	c.SendMessage("[2FA] Timeout! I didn't receive correct code. Please try again.")
+	return ErrTelegramUser2FATimeout
+}
+
+func (c *Client) twoFaTooManyAttempts(startTs time.Time, failedAttempts int) error {
+	log.Error().Err(ErrTelegramUser2FATooManyAttempts).Int("failedAttempts", failedAttempts).
+		Dur("duration", time.Since(startTs)).Msgf("[%s] too many failed attempts", teleUpdatesPrefix)
+	c.SendMessage("[2FA] Too many incorrect codes. Login was rejected.")
+	return ErrTelegramUser2FATooManyAttempts
+}
+
 // This method gets recent messages from the Telegram channel and try to find
 // message with matching pattern. Either errors or results are sent over
 // channels (Go channels :)).
 func (c *Client) getUpdatesWithPattern(startTsUnixSeconds int, lastMessageUpdateId *int, pattern string,
-	isMatchedChan chan<- bool, errChan chan error) {
+	matchChan chan<- matchResult, errChan chan error) {
 
 	updatesUrl := c.getUpdatesUrl(updatesLimit, updatesQueryTimeoutSeconds, lastMessageUpdateId)
 	apiResp, reqErr := c.getRequest(updatesUrl, "getUpdates")
@@ -87,13 +171,41 @@ func (c *Client) getUpdatesWithPattern(startTsUnixSeconds int, lastMessageUpdate
 		return
 	}
 
-	isMatchedChan <- matchPatternWithinMessageText(startTsUnixSeconds, pattern, c.channelId, updates)
+	matchChan <- matchExactMessageText(startTsUnixSeconds, pattern, c.channelId, updates, c.isOtherPendingCode)
 }
 
-// This function tries to match given pattern to Telegram chat post message
-// text which happens later than startTsUnixSeconds on particular chat (based
-// on chatId).
-func matchPatternWithinMessageText(startTsUnixSeconds int, pattern string, chatId int64, messages []Update) bool {
+// Registers code of 2FA check in progress. Returned function removes it.
+func (c *Client) addPendingCode(code string) func() {
+	c.pendingMu.Lock()
+	c.pendingCodes[code]++
+	c.pendingMu.Unlock()
+	return func() {
+		c.pendingMu.Lock()
+		defer c.pendingMu.Unlock()
+		if c.pendingCodes[code]--; c.pendingCodes[code] <= 0 {
+			delete(c.pendingCodes, code)
+		}
+	}
+}
+
+// Checks whenever text is the code of another 2FA check in progress.
+func (c *Client) isOtherPendingCode(pattern, text string) bool {
+	if text == pattern {
+		return false
+	}
+	c.pendingMu.Lock()
+	defer c.pendingMu.Unlock()
+	return c.pendingCodes[text] > 0
+}
+
+// This function checks if text of any Telegram chat post which happens later
+// than startTsUnixSeconds on particular chat (based on chatId) is exactly the
+// given pattern. IDs of other posts which look like a code are returned as
+// failed attempts. Ordinary messages and codes for which isOtherPending is
+// true (codes of concurrent logins) are not counted.
+func matchExactMessageText(startTsUnixSeconds int, pattern string, chatId int64, messages []Update,
+	isOtherPending func(pattern, text string) bool) matchResult {
+	var result matchResult
 	for _, update := range messages {
 		if update.ChannelPost == nil {
 			log.Warn().Msgf("[%s] empty channel post", teleUpdatesPrefix)
@@ -106,14 +218,32 @@ func matchPatternWithinMessageText(startTsUnixSeconds int, pattern string, chatI
 		if update.ChannelPost.Date <= startTsUnixSeconds || update.ChannelPost.Chat.ID != chatId {
 			continue
 		}
-		log.Info().Str("text", update.ChannelPost.Text).Msgf("[%s] text", teleUpdatesPrefix)
-		if strings.Contains(strings.ToLower(update.ChannelPost.Text), strings.ToLower(pattern)) {
-			log.Info().Str("pattern", pattern).Str("telegramText", update.ChannelPost.Text).
-				Msgf("[%s] found match", teleUpdatesPrefix)
-			return true
+		text := strings.TrimSpace(update.ChannelPost.Text)
+		if subtle.ConstantTimeCompare([]byte(text), []byte(pattern)) == 1 {
+			log.Info().Int("updateId", update.UpdateID).Msgf("[%s] found match", teleUpdatesPrefix)
+			result.isMatched = true
+			return result
+		}
+		if !looksLikeCode(text, pattern) || isOtherPending(pattern, text) {
+			continue
+		}
+		result.failedUpdateIds = append(result.failedUpdateIds, update.UpdateID)
+	}
+	return result
+}
+
+// Checks whenever text could be an attempt to post the code, i.e. it has the
+// same length as the code and consists only of letters and digits.
+func looksLikeCode(text, pattern string) bool {
+	if len(text) != len(pattern) {
+		return false
+	}
+	for _, r := range text {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			return false
 		}
 	}
-	return false
+	return true
 }
 
 // Gets Update ID of last message on the Telegram channel. In case of error nil
@@ -148,13 +278,13 @@ func (c *Client) getLastUpdateId() *int {
 
 func (c *Client) getUpdatesUrl(limit, timeout int, offset *int) string {
 	if offset != nil {
-		return fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?limit=%d&timeout=%d&offset=%d",
-			c.botToken, limit, timeout, *offset)
+		return fmt.Sprintf("%s/bot%s/getUpdates?limit=%d&timeout=%d&offset=%d",
+			c.baseUrl, c.botToken, limit, timeout, *offset)
 	}
-	return fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?limit=%d&timeout=%d",
-		c.botToken, limit, timeout)
+	return fmt.Sprintf("%s/bot%s/getUpdates?limit=%d&timeout=%d",
+		c.baseUrl, c.botToken, limit, timeout)
 }
 
 func (c *Client) getUpdateLastUrl() string {
-	return fmt.Sprintf("https://api.telegram.org/bot%s/getUpdates?offset=-1", c.botToken)
+	return fmt.Sprintf("%s/bot%s/getUpdates?offset=-1", c.baseUrl, c.botToken)
 }
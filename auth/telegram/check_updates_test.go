@@ -0,0 +1,164 @@
+package telegram
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"homeApp/auth/telegram/telegramtest"
+)
+
+func TestMatchExactMessageText(t *testing.T) {
+	const chatId = int64(-100123)
+	const startTs = 1000
+	post := func(updateId, date int, chatId int64, text string) Update {
+		return Update{
+			UpdateID:    updateId,
+			ChannelPost: &Message{Date: date, Chat: &Chat{ID: chatId}, Text: text},
+		}
+	}
+
+	testCases := []struct {
+		name            string
+		updates         []Update
+		isMatched       bool
+		failedUpdateIds []int
+	}{
+		{"exact match", []Update{post(1, 1001, chatId, "123456")}, true, nil},
+		{"whitespaces are ignored", []Update{post(1, 1001, chatId, " 123456\n")}, true, nil},
+		{"code within longer text", []Update{post(1, 1001, chatId, "code 123456")}, false, nil},
+		{"ordinary message", []Update{post(1, 1001, chatId, "hello")}, false, nil},
+		{"different code", []Update{post(1, 1001, chatId, "654321")}, false, []int{1}},
+		{"code of other login", []Update{post(1, 1001, chatId, "222222")}, false, nil},
+		{"message before start", []Update{post(1, 1000, chatId, "123456")}, false, nil},
+		{"different chat", []Update{post(1, 1001, -100999, "123456")}, false, nil},
+		{"empty channel post", []Update{{UpdateID: 1}}, false, nil},
+		{
+			"match after failed attempts",
+			[]Update{post(1, 1001, chatId, "111111"), post(2, 1002, chatId, "123456")},
+			true, []int{1},
+		},
+	}
+
+	isOtherPending := func(_, text string) bool { return text == "222222" }
+	for _, tc := range testCases {
+		result := matchExactMessageText(startTs, "123456", chatId, tc.updates, isOtherPending)
+		if result.isMatched != tc.isMatched {
+			t.Errorf("[%s] expected isMatched=%v, got %v", tc.name, tc.isMatched, result.isMatched)
+		}
+		if len(result.failedUpdateIds) != len(tc.failedUpdateIds) {
+			t.Errorf("[%s] expected failed updates %v, got %v", tc.name, tc.failedUpdateIds, result.failedUpdateIds)
+		}
+	}
+}
+
+const (
+	testBotToken  = "123:test"
+	testChannelId = int64(-100123)
+)
+
+func newTestClient(server *telegramtest.Server) *Client {
+	client := NewClient(server.Client(), server.URL, testBotToken, strconv.FormatInt(testChannelId, 10))
+	client.retryDelay = 10 * time.Millisecond
+	return client
+}
+
+// Channel post made after the 2FA check started.
+func channelPost(updateId int, text string) Update {
+	return Update{
+		UpdateID: updateId,
+		ChannelPost: &Message{
+			Date: int(time.Now().Unix()) + 1,
+			Chat: &Chat{ID: testChannelId},
+			Text: text,
+		},
+	}
+}
+
+func TestCheckMessageWithPatternMatch(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	server.SetLastUpdate(Update{UpdateID: 10})
+	server.AddUpdates()
+	server.AddUpdates(channelPost(11, "wrong"), channelPost(12, "ABC123"))
+
+	isMatched, err := newTestClient(server).CheckMessageWithPattern("ABC123", 5*time.Second)
+	if !isMatched || err != nil {
+		t.Fatalf("expected match, got (%v, %v)", isMatched, err)
+	}
+	if calls := server.GetUpdatesCalls(); calls != 2 {
+		t.Errorf("expected 2 getUpdates calls, got %d", calls)
+	}
+}
+
+func TestCheckMessageWithPatternRetriesOnError(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	server.AddError(http.StatusBadGateway, "Bad Gateway")
+	server.AddError(http.StatusConflict, "Conflict: terminated by other getUpdates request")
+	server.AddUpdates(channelPost(1, "ABC123"))
+
+	isMatched, err := newTestClient(server).CheckMessageWithPattern("ABC123", 5*time.Second)
+	if !isMatched || err != nil {
+		t.Fatalf("expected match after retries, got (%v, %v)", isMatched, err)
+	}
+	if calls := server.GetUpdatesCalls(); calls != 3 {
+		t.Errorf("expected 3 getUpdates calls, got %d", calls)
+	}
+}
+
+func TestCheckMessageWithPatternTooManyAttempts(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	// The same updates are returned again, those shouldn't be counted twice.
+	// Ordinary messages aren't attempts at all.
+	server.AddUpdates(channelPost(1, "ABC124"), channelPost(2, "hello"))
+	server.AddUpdates(channelPost(1, "ABC124"), channelPost(2, "hello"), channelPost(3, "ABC125"))
+	server.AddUpdates(channelPost(1, "ABC124"), channelPost(2, "hello"), channelPost(3, "ABC125"),
+		channelPost(4, "ABC126"))
+
+	isMatched, err := newTestClient(server).CheckMessageWithPattern("ABC123", 5*time.Second)
+	if isMatched || err != ErrTelegramUser2FATooManyAttempts {
+		t.Fatalf("expected too many attempts, got (%v, %v)", isMatched, err)
+	}
+	if calls := server.GetUpdatesCalls(); calls != 3 {
+		t.Errorf("expected 3 getUpdates calls, got %d", calls)
+	}
+}
+
+func TestCheckMessageWithPatternTimeout(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+
+	startTs := time.Now()
+	isMatched, err := newTestClient(server).CheckMessageWithPattern("ABC123", 100*time.Millisecond)
+	if isMatched || err != ErrTelegramUser2FATimeout {
+		t.Fatalf("expected timeout, got (%v, %v)", isMatched, err)
+	}
+	if elapsed := time.Since(startTs); elapsed > 2*time.Second {
+		t.Errorf("expected to stop shortly after timeout, took %v", elapsed)
+	}
+	if server.GetUpdatesCalls() == 0 {
+		t.Error("expected updates to be checked before timeout")
+	}
+}
+
+func TestCheckMessageWithPatternIgnoresOtherLogins(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	client := newTestClient(server)
+	defer client.addPendingCode("XYZ001")()
+	defer client.addPendingCode("XYZ002")()
+	defer client.addPendingCode("XYZ003")()
+	server.AddUpdates(channelPost(1, "XYZ001"), channelPost(2, "XYZ002"), channelPost(3, "XYZ003"),
+		channelPost(4, "ABC123"))
+
+	isMatched, err := client.CheckMessageWithPattern("ABC123", 5*time.Second)
+	if !isMatched || err != nil {
+		t.Fatalf("expected match despite codes of other logins, got (%v, %v)", isMatched, err)
+	}
+	if len(client.pendingCodes) != 3 {
+		t.Errorf("expected only codes of other logins to be pending, got %v", client.pendingCodes)
+	}
+}
@@ -3,22 +3,43 @@ package telegram
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 )
 
+// DefaultBaseUrl is the base URL of Telegram Bot API.
+const DefaultBaseUrl = "https://api.telegram.org"
+
 // Client is a Telegram client which handles communication with Telegram
 // channel.
 type Client struct {
 	httpClient *http.Client
+	baseUrl    string
 	botToken   string
 	channelId  int64
+	retryDelay time.Duration
+	dispatcher *Dispatcher
+
+	receivesUpdates atomic.Bool // updates are polled or received by webhook
+
+	pendingMu    sync.Mutex
+	pendingCodes map[string]int // codes of 2FA checks in progress
 }
 
-// NewClient instantiates new client.
-func NewClient(httpClient *http.Client, botToken string, channelId string) *Client {
+// NewClient instantiates new client. Base URL is the Bot API URL without
+// trailing slash, usually DefaultBaseUrl. It can point to self-hosted Bot API
+// server or to fake server in tests.
+func NewClient(httpClient *http.Client, baseUrl, botToken string, channelId string) *Client {
 	chatIdInt, _ := strconv.ParseInt(channelId, 10, 64) // TODO
 	return &Client{
-		httpClient: httpClient,
-		botToken:   botToken,
-		channelId:  chatIdInt,
+		httpClient:   httpClient,
+		baseUrl:      strings.TrimSuffix(baseUrl, "/"),
+		botToken:     botToken,
+		channelId:    chatIdInt,
+		retryDelay:   secondsBeforeRetry * time.Second,
+		dispatcher:   NewDispatcher(),
+		pendingCodes: make(map[string]int),
 	}
 }
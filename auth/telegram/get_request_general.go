@@ -22,6 +22,7 @@ func (c *Client) getRequest(telegramUrl, endpointName string) (APIResponse, erro
 
 	resp, err := c.httpClient.Get(telegramUrl)
 	if err != nil {
+		err = withoutUrl(err, endpointName)
 		log.Error().Err(err).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] telegram GET [%s] failed", teleGetPrefix, endpointName)
 		return apiResp, err
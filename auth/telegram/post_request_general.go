@@ -0,0 +1,72 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const telePostPrefix = "telegram/post"
+
+// Generic POST request with JSON payload for Telegram API method. Returns
+// APIResponse. Unsuccessful responses are returned as Error, including
+// response parameters like RetryAfter.
+func (c *Client) postJsonRequest(method string, payload interface{}) (APIResponse, error) {
+	body, mErr := json.Marshal(payload)
+	if mErr != nil {
+		return APIResponse{}, mErr
+	}
+	return c.postRequest(method, "application/json", bytes.NewReader(body))
+}
+
+// Generic POST request for Telegram API method with given content type.
+func (c *Client) postRequest(method, contentType string, body io.Reader) (APIResponse, error) {
+	startTs := time.Now()
+	var apiResp APIResponse
+
+	log.Info().Str("endpoint", method).Msgf("[%s] start sending POST request", telePostPrefix)
+
+	resp, err := c.httpClient.Post(c.methodUrl(method, nil), contentType, body)
+	if err != nil {
+		err = withoutUrl(err, method)
+		log.Error().Err(err).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] telegram POST [%s] failed", telePostPrefix, method)
+		return apiResp, err
+	}
+	defer resp.Body.Close()
+
+	respBody, rErr := io.ReadAll(resp.Body)
+	if rErr != nil {
+		log.Error().Err(rErr).Dur("duration", time.Since(startTs)).
+			Msgf("[%s] couldn't read [%s] response body", telePostPrefix, method)
+		return apiResp, fmt.Errorf("couldn't read response body: %s", rErr.Error())
+	}
+
+	jErr := json.Unmarshal(respBody, &apiResp)
+	if jErr != nil {
+		log.Error().Err(jErr).Int("statuscode", resp.StatusCode).Str("respBody", string(respBody)).
+			Dur("duration", time.Since(startTs)).Msgf("[%s] unmarshal into APIResponse failed", telePostPrefix)
+		return apiResp, fmt.Errorf("got %d status code in [%s] response", resp.StatusCode, method)
+	}
+
+	if resp.StatusCode != http.StatusOK || !apiResp.Ok {
+		log.Error().Int("statuscode", resp.StatusCode).Str("respBody", string(respBody)).
+			Dur("duration", time.Since(startTs)).
+			Msgf("[%s] got unsuccessful [%s] response", telePostPrefix, method)
+		apiErr := Error{Code: apiResp.ErrorCode, Message: apiResp.Description}
+		if apiResp.Parameters != nil {
+			apiErr.ResponseParameters = *apiResp.Parameters
+		}
+		return apiResp, apiErr
+	}
+
+	log.Info().Str("endpoint", method).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished POST request", telePostPrefix)
+
+	return apiResp, nil
+}
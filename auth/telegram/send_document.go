@@ -0,0 +1,68 @@
+package telegram
+
+import (
+	"bytes"
+	"errors"
+	"mime/multipart"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	teleSendDocPrefix = "telegram/sendDoc"
+
+	// MaxUploadSize is the maximum size of file which can be sent by bot
+	// using sendDocument with multipart upload (50 MB).
+	MaxUploadSize    = 50 * 1000 * 1000
+	maxCaptionLength = 1024
+)
+
+// ErrFileTooLarge is returned when file exceeds MaxUploadSize.
+var ErrFileTooLarge = errors.New("file exceeds Telegram upload size limit")
+
+// SendDocument sends a file onto configured Telegram channel in the Client.
+// Caption is optional.
+func (c *Client) SendDocument(fileName string, content []byte, caption string) error {
+	return c.SendDocumentToChat(c.channelId, fileName, content, caption)
+}
+
+// SendDocumentToChat uploads a file to given chat using sendDocument.
+// ErrFileTooLarge is returned for files larger than MaxUploadSize, without
+// calling Telegram. Caption longer than Telegram limit is truncated.
+func (c *Client) SendDocumentToChat(chatId int64, fileName string, content []byte, caption string) error {
+	if len(content) > MaxUploadSize {
+		log.Warn().Str("fileName", fileName).Int("size", len(content)).
+			Msgf("[%s] file is too large to be sent", teleSendDocPrefix)
+		return ErrFileTooLarge
+	}
+	startTs := time.Now()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	form.WriteField("chat_id", strconv.FormatInt(chatId, 10))
+	if caption != "" {
+		caption, _ = cutUtf16(caption, maxCaptionLength)
+		form.WriteField("caption", caption)
+	}
+	part, pErr := form.CreateFormFile("document", fileName)
+	if pErr != nil {
+		return pErr
+	}
+	part.Write(content)
+	if cErr := form.Close(); cErr != nil {
+		return cErr
+	}
+
+	sErr := withFloodRetries(func() error {
+		_, err := c.postRequest("sendDocument", form.FormDataContentType(), bytes.NewReader(body.Bytes()))
+		return err
+	})
+	if sErr != nil {
+		return sErr
+	}
+	log.Info().Str("fileName", fileName).Int("size", len(content)).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] document sent", teleSendDocPrefix)
+	return nil
+}
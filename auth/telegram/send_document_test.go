@@ -0,0 +1,37 @@
+package telegram
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"homeApp/auth/telegram/telegramtest"
+)
+
+func TestSendDocumentToChat(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	client := newTestClient(server)
+
+	content := []byte("%PDF-1.4 fake content")
+	if err := client.SendDocumentToChat(42, "umowa najmu.pdf", content, "Umowa najmu"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	documents := server.Documents()
+	if len(documents) != 1 {
+		t.Fatalf("expected single document, got %d", len(documents))
+	}
+	doc := documents[0]
+	if doc.ChatId != "42" || doc.FileName != "umowa najmu.pdf" || doc.Caption != "Umowa najmu" ||
+		!bytes.Equal(doc.Content, content) {
+		t.Errorf("unexpected document: %+v", doc)
+	}
+
+	tooLarge := make([]byte, MaxUploadSize+1)
+	if err := client.SendDocumentToChat(42, "large.zip", tooLarge, ""); !errors.Is(err, ErrFileTooLarge) {
+		t.Errorf("expected ErrFileTooLarge, got %v", err)
+	}
+	if len(server.Documents()) != 1 {
+		t.Error("expected too large file not to be sent")
+	}
+}
@@ -1,20 +1,177 @@
 package telegram
 
 import (
-	"fmt"
+	"errors"
+	"html"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	teleSendPrefix = "telegram/sendMsg"
+
+	// Parse modes supported by sendMessage. Text sent with parse mode other
+	// than ParseModeNone should be escaped using EscapeMarkdownV2 or
+	// EscapeHTML respectively, besides intended formatting.
+	ParseModeNone       = ""
+	ParseModeMarkdownV2 = "MarkdownV2"
+	ParseModeHTML       = "HTML"
+
+	// MaxMessageLength is the maximum length of single message in UTF-16 code
+	// units. Longer messages are split into several messages.
+	MaxMessageLength = 4096
+
+	maxSendRetries = 3
+	maxRetryAfter  = 60 * time.Second
 )
 
-const teleSendPrefix = "telegram/sendMsg"
+// Characters which have to be escaped in MarkdownV2 text.
+const markdownV2Special = "_*[]()~`>#+-=|{}.!\\"
+
+type sendMessageRequest struct {
+	ChatId    int64  `json:"chat_id"`
+	Text      string `json:"text"`
+	ParseMode string `json:"parse_mode,omitempty"`
+}
 
 // SendMessage sends a text message onto configured Telegram channel in the
 // Client. In case when sending message failed then non-nil error would be
 // returned.
 func (c *Client) SendMessage(text string) error {
-	_, err := c.getRequest(c.sendMessageUrl(text), "sendMessage")
-	return err
+	return c.SendFormattedMessage(c.channelId, text, ParseModeNone)
+}
+
+// SendMessageToChat sends a plain text message to given chat, e.g. reply to
+// bot command.
+func (c *Client) SendMessageToChat(chatId int64, text string) error {
+	return c.SendFormattedMessage(chatId, text, ParseModeNone)
+}
+
+// SendFormattedMessage sends a text message to given chat using given parse
+// mode. Messages longer than MaxMessageLength are split on line boundaries,
+// so formatting entities shouldn't span multiple lines in long messages. When
+// Telegram responds with retry_after (flood control), sending is retried after
+// requested time.
+func (c *Client) SendFormattedMessage(chatId int64, text, parseMode string) error {
+	for _, part := range splitMessage(text, MaxMessageLength) {
+		req := sendMessageRequest{ChatId: chatId, Text: part, ParseMode: parseMode}
+		if sErr := c.sendMessageWithRetries(req); sErr != nil {
+			return sErr
+		}
+	}
+	return nil
+}
+
+func (c *Client) sendMessageWithRetries(req sendMessageRequest) error {
+	return withFloodRetries(func() error {
+		_, err := c.postJsonRequest("sendMessage", req)
+		return err
+	})
+}
+
+// Calls send and retries it after time requested by Telegram, when flood
+// control limit is exceeded (429 Too Many Requests with retry_after).
+func withFloodRetries(send func() error) error {
+	for retry := 0; ; retry++ {
+		err := send()
+		var apiErr Error
+		if err == nil || !errors.As(err, &apiErr) || apiErr.RetryAfter <= 0 || retry >= maxSendRetries {
+			return err
+		}
+		wait := time.Duration(apiErr.RetryAfter) * time.Second
+		if wait > maxRetryAfter {
+			wait = maxRetryAfter
+		}
+		log.Warn().Dur("retryAfter", wait).Int("retry", retry+1).
+			Msgf("[%s] flood control exceeded, waiting before retry", teleSendPrefix)
+		time.Sleep(wait)
+	}
+}
+
+// EscapeMarkdownV2 escapes text, so it's displayed as is when sent with
+// ParseModeMarkdownV2.
+func EscapeMarkdownV2(text string) string {
+	var b strings.Builder
+	b.Grow(len(text))
+	for _, r := range text {
+		if strings.ContainsRune(markdownV2Special, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// EscapeHTML escapes text, so it's displayed as is when sent with
+// ParseModeHTML.
+func EscapeHTML(text string) string {
+	return html.EscapeString(text)
+}
+
+// Splits text into parts not longer than limit UTF-16 code units. Text is
+// split on new lines when possible, lines longer than limit are split on runes.
+// Empty parts are skipped, because Telegram rejects empty messages.
+func splitMessage(text string, limit int) []string {
+	if utf16Len(text) <= limit {
+		return []string{text}
+	}
+
+	parts := make([]string, 0, 2)
+	var part strings.Builder
+	partLen := 0
+	flush := func() {
+		if p := strings.TrimRight(part.String(), "\n"); strings.TrimSpace(p) != "" {
+			parts = append(parts, p)
+		}
+		part.Reset()
+		partLen = 0
+	}
+
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineLen := utf16Len(line)
+		if partLen > 0 && partLen+lineLen > limit {
+			flush()
+		}
+		for lineLen > limit {
+			var head string
+			head, line = cutUtf16(line, limit)
+			part.WriteString(head)
+			flush()
+			lineLen = utf16Len(line)
+		}
+		part.WriteString(line)
+		partLen += lineLen
+	}
+	flush()
+
+	return parts
+}
+
+// Cuts text after at most n UTF-16 code units, without splitting runes.
+func cutUtf16(text string, n int) (string, string) {
+	length := 0
+	for i, r := range text {
+		length += runeUtf16Len(r)
+		if length > n {
+			return text[:i], text[i:]
+		}
+	}
+	return text, ""
+}
+
+func utf16Len(text string) int {
+	length := 0
+	for _, r := range text {
+		length += runeUtf16Len(r)
+	}
+	return length
 }
 
-func (c *Client) sendMessageUrl(text string) string {
-	return fmt.Sprintf("https://api.telegram.org/bot%s/sendMessage?chat_id=%d&text=%s",
-		c.botToken, c.channelId, text)
+func runeUtf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2 // surrogate pair
+	}
+	return 1
 }
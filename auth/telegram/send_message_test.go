@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"homeApp/auth/telegram/telegramtest"
+)
+
+func TestSendMessageToChat(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+
+	const text = "Found 2 documents for [A&B #1]:\n[1] umowa"
+	if err := newTestClient(server).SendMessageToChat(42, text); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].ChatId != "42" || messages[0].Text != text {
+		t.Errorf("expected single message %q to chat 42, got %+v", text, messages)
+	}
+}
+
+func TestSendFormattedMessageHonoursRetryAfter(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	server.AddSendMessageError(http.StatusTooManyRequests, "Too Many Requests: retry after 1", 1)
+
+	startTs := time.Now()
+	if err := newTestClient(server).SendFormattedMessage(42, "*Report*", ParseModeMarkdownV2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(startTs); elapsed < time.Second {
+		t.Errorf("expected retry after at least 1s, retried after %v", elapsed)
+	}
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].ParseMode != ParseModeMarkdownV2 {
+		t.Errorf("expected single MarkdownV2 message, got %+v", messages)
+	}
+
+	server.AddSendMessageError(http.StatusBadRequest, "Bad Request: can't parse entities", 0)
+	err := newTestClient(server).SendFormattedMessage(42, "*Report", ParseModeMarkdownV2)
+	var apiErr Error
+	if !errors.As(err, &apiErr) || apiErr.Code != http.StatusBadRequest {
+		t.Errorf("expected Telegram error with code 400, got %v", err)
+	}
+}
+
+func TestSplitMessage(t *testing.T) {
+	if parts := splitMessage("short", 10); len(parts) != 1 || parts[0] != "short" {
+		t.Errorf("expected message unchanged, got %q", parts)
+	}
+
+	parts := splitMessage("aaaa\nbbbb\ncc\n\n\ndddddddddddd", 10)
+	expected := []string{"aaaa\nbbbb", "cc", "dddddddddd", "dd"}
+	if strings.Join(parts, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, parts)
+	}
+
+	// Emoji takes two UTF-16 code units and cannot be split.
+	parts = splitMessage("ąą😀😀", 3)
+	expected = []string{"ąą", "😀", "😀"}
+	if strings.Join(parts, "|") != strings.Join(expected, "|") {
+		t.Errorf("expected %q, got %q", expected, parts)
+	}
+
+	long := strings.Repeat("line of text\n", 1000)
+	for _, part := range splitMessage(long, MaxMessageLength) {
+		if utf16Len(part) > MaxMessageLength {
+			t.Errorf("part exceeds %d characters: %d", MaxMessageLength, utf16Len(part))
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+	const text = "1.5 * (2-1) = [x]_y! a\\b <i>&"
+	if escaped := EscapeMarkdownV2(text); escaped != "1\\.5 \\* \\(2\\-1\\) \\= \\[x\\]\\_y\\! a\\\\b <i\\>&" {
+		t.Errorf("unexpected MarkdownV2 escaping: %s", escaped)
+	}
+	if escaped := EscapeHTML(text); escaped != "1.5 * (2-1) = [x]_y! a\\b &lt;i&gt;&amp;" {
+		t.Errorf("unexpected HTML escaping: %s", escaped)
+	}
+}
@@ -0,0 +1,278 @@
+// Package telegramtest provides fake Telegram Bot API server for tests. It
+// records sent messages and serves scripted getUpdates responses.
+package telegramtest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+)
+
+// SentMessage is a message sent to the fake server via sendMessage.
+type SentMessage struct {
+	ChatId    string
+	Text      string
+	ParseMode string
+}
+
+// SentDocument is a file sent to the fake server via sendDocument.
+type SentDocument struct {
+	ChatId   string
+	FileName string
+	Content  []byte
+	Caption  string
+}
+
+// Scripted sendMessage failure.
+type sendError struct {
+	statusCode  int
+	description string
+	retryAfter  int
+}
+
+// Scripted getUpdates response. Either updates or an error (non-zero status
+// code).
+type updatesResponse struct {
+	updates     []interface{}
+	statusCode  int
+	description string
+}
+
+// Server is fake Telegram Bot API server. Client under test should use URL
+// as its base URL and Token as its bot token. Every getUpdates call (besides
+// the one for the last update, offset=-1) consumes the next scripted response.
+// When there are no scripted responses left, empty list of updates is returned.
+type Server struct {
+	*httptest.Server
+	Token string
+
+	mu              sync.Mutex
+	responses       []updatesResponse
+	lastUpdate      interface{}
+	messages        []SentMessage
+	sendErrors      []sendError
+	documents       []SentDocument
+	getUpdatesCalls int
+	webhook         Webhook
+}
+
+// Webhook is the webhook registered via setWebhook. It's empty when there's
+// no webhook.
+type Webhook struct {
+	Url         string
+	SecretToken string
+}
+
+// NewServer starts fake Telegram Bot API server. It should be closed by the
+// caller.
+func NewServer(token string) *Server {
+	s := &Server{Token: token}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.handle))
+	return s
+}
+
+// AddUpdates scripts the next getUpdates response. Updates are values which
+// marshal into Telegram updates JSON, usually telegram.Update.
+func (s *Server) AddUpdates(updates ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if updates == nil {
+		updates = []interface{}{}
+	}
+	s.responses = append(s.responses, updatesResponse{updates: updates})
+}
+
+// AddError scripts the next getUpdates response to fail with given status
+// code.
+func (s *Server) AddError(statusCode int, description string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responses = append(s.responses, updatesResponse{statusCode: statusCode, description: description})
+}
+
+// AddSendMessageError scripts the next sendMessage call to fail with given
+// status code. Positive retryAfter is returned in response parameters, like
+// in case of exceeded flood control (429 Too Many Requests).
+func (s *Server) AddSendMessageError(statusCode int, description string, retryAfter int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sendErrors = append(s.sendErrors, sendError{statusCode, description, retryAfter})
+}
+
+// SetLastUpdate sets update returned for getUpdates with offset=-1. No update
+// is returned by default.
+func (s *Server) SetLastUpdate(update interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastUpdate = update
+}
+
+// Webhook returns currently registered webhook.
+func (s *Server) Webhook() Webhook {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.webhook
+}
+
+// Messages returns messages sent so far.
+func (s *Server) Messages() []SentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentMessage(nil), s.messages...)
+}
+
+// Documents returns documents sent so far.
+func (s *Server) Documents() []SentDocument {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SentDocument(nil), s.documents...)
+}
+
+// GetUpdatesCalls returns number of getUpdates calls (besides ones for the
+// last update) so far.
+func (s *Server) GetUpdatesCalls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.getUpdatesCalls
+}
+
+func (s *Server) handle(w http.ResponseWriter, r *http.Request) {
+	token, method, found := strings.Cut(strings.TrimPrefix(r.URL.Path, "/bot"), "/")
+	if !found || token != s.Token {
+		writeResponse(w, http.StatusUnauthorized, "Unauthorized", nil)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch method {
+	case "getUpdates":
+		s.getUpdates(w, r)
+	case "sendMessage":
+		s.sendMessage(w, r)
+	case "sendDocument":
+		s.sendDocument(w, r)
+	case "setWebhook":
+		s.webhook = Webhook{Url: r.FormValue("url"), SecretToken: r.FormValue("secret_token")}
+		writeResponse(w, http.StatusOK, "", true)
+	case "deleteWebhook":
+		s.webhook = Webhook{}
+		writeResponse(w, http.StatusOK, "", true)
+	default:
+		writeResponse(w, http.StatusNotFound, "Not Found: method not found", nil)
+	}
+}
+
+func (s *Server) getUpdates(w http.ResponseWriter, r *http.Request) {
+	if s.webhook.Url != "" {
+		writeResponse(w, http.StatusConflict, "Conflict: can't use getUpdates method while webhook is active", nil)
+		return
+	}
+	if r.FormValue("offset") == "-1" {
+		updates := []interface{}{}
+		if s.lastUpdate != nil {
+			updates = append(updates, s.lastUpdate)
+		}
+		writeResponse(w, http.StatusOK, "", updates)
+		return
+	}
+
+	s.getUpdatesCalls++
+	if len(s.responses) == 0 {
+		writeResponse(w, http.StatusOK, "", []interface{}{})
+		return
+	}
+	resp := s.responses[0]
+	s.responses = s.responses[1:]
+	if resp.statusCode != 0 {
+		writeResponse(w, resp.statusCode, resp.description, nil)
+		return
+	}
+	writeResponse(w, http.StatusOK, "", resp.updates)
+}
+
+// Reads message sent either as JSON or as form (query) parameters.
+func (s *Server) sendMessage(w http.ResponseWriter, r *http.Request) {
+	if len(s.sendErrors) > 0 {
+		sErr := s.sendErrors[0]
+		s.sendErrors = s.sendErrors[1:]
+		writeErrorResponse(w, sErr.statusCode, sErr.description, sErr.retryAfter)
+		return
+	}
+
+	msg := SentMessage{
+		ChatId:    r.FormValue("chat_id"),
+		Text:      r.FormValue("text"),
+		ParseMode: r.FormValue("parse_mode"),
+	}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json") {
+		var req struct {
+			ChatId    json.Number `json:"chat_id"`
+			Text      string      `json:"text"`
+			ParseMode string      `json:"parse_mode"`
+		}
+		if dErr := json.NewDecoder(r.Body).Decode(&req); dErr != nil {
+			writeResponse(w, http.StatusBadRequest, fmt.Sprintf("Bad Request: %s", dErr), nil)
+			return
+		}
+		msg = SentMessage{ChatId: req.ChatId.String(), Text: req.Text, ParseMode: req.ParseMode}
+	}
+	if msg.Text == "" {
+		writeResponse(w, http.StatusBadRequest, "Bad Request: message text is empty", nil)
+		return
+	}
+	s.messages = append(s.messages, msg)
+	writeResponse(w, http.StatusOK, "", map[string]interface{}{"message_id": len(s.messages)})
+}
+
+// Reads document sent as multipart upload.
+func (s *Server) sendDocument(w http.ResponseWriter, r *http.Request) {
+	file, header, fErr := r.FormFile("document")
+	if fErr != nil {
+		writeResponse(w, http.StatusBadRequest, "Bad Request: there is no document in the request", nil)
+		return
+	}
+	defer file.Close()
+	content, rErr := io.ReadAll(file)
+	if rErr != nil {
+		writeResponse(w, http.StatusBadRequest, fmt.Sprintf("Bad Request: %s", rErr), nil)
+		return
+	}
+	s.documents = append(s.documents, SentDocument{
+		ChatId:   r.FormValue("chat_id"),
+		FileName: header.Filename,
+		Content:  content,
+		Caption:  r.FormValue("caption"),
+	})
+	writeResponse(w, http.StatusOK, "", map[string]interface{}{"message_id": len(s.documents)})
+}
+
+// Writes unsuccessful response with retry_after parameter, when it's positive.
+func writeErrorResponse(w http.ResponseWriter, statusCode int, description string, retryAfter int) {
+	resp := map[string]interface{}{"ok": false, "error_code": statusCode, "description": description}
+	if retryAfter > 0 {
+		resp["parameters"] = map[string]int{"retry_after": retryAfter}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(resp)
+}
+
+// Writes response in Bot API format. Result is set only for successful
+// responses.
+func writeResponse(w http.ResponseWriter, statusCode int, description string, result interface{}) {
+	resp := map[string]interface{}{"ok": statusCode == http.StatusOK}
+	if statusCode == http.StatusOK {
+		resp["result"] = result
+	} else {
+		resp["error_code"] = statusCode
+		resp["description"] = description
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	json.NewEncoder(w).Encode(resp)
+}
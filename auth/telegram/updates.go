@@ -0,0 +1,108 @@
+package telegram
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	telePollPrefix     = "telegram/poll"
+	pollTimeoutSeconds = 30
+)
+
+// UpdateHandler handles single Telegram update. It's called synchronously by
+// the Dispatcher, so it should return quickly.
+type UpdateHandler func(Update)
+
+// Dispatcher passes Telegram updates to every subscribed handler (2FA check,
+// bot commands). Thanks to that there's only single source of updates - either
+// polling getUpdates or webhook.
+type Dispatcher struct {
+	sync.Mutex
+	handlers map[int]UpdateHandler
+	nextId   int
+}
+
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{handlers: make(map[int]UpdateHandler)}
+}
+
+// Subscribe adds handler of updates. Returned function removes the handler.
+func (d *Dispatcher) Subscribe(handler UpdateHandler) func() {
+	d.Lock()
+	defer d.Unlock()
+	id := d.nextId
+	d.nextId++
+	d.handlers[id] = handler
+
+	return func() {
+		d.Lock()
+		defer d.Unlock()
+		delete(d.handlers, id)
+	}
+}
+
+// Dispatch passes update to every subscribed handler.
+func (d *Dispatcher) Dispatch(update Update) {
+	d.Lock()
+	handlers := make([]UpdateHandler, 0, len(d.handlers))
+	for _, handler := range d.handlers {
+		handlers = append(handlers, handler)
+	}
+	d.Unlock()
+
+	for _, handler := range handlers {
+		handler(update)
+	}
+}
+
+// Subscribe adds handler of updates received by the client. Updates are
+// received only after StartPolling or StartWebhook.
+func (c *Client) Subscribe(handler UpdateHandler) func() {
+	return c.dispatcher.Subscribe(handler)
+}
+
+// StartPolling starts long polling of getUpdates in the background. Updates
+// are passed to subscribed handlers. Updates posted before the start are
+// skipped, so old commands are not executed again after restart. Webhook left
+// by previous run (StartWebhook) is removed, because getUpdates doesn't work
+// while webhook is set.
+func (c *Client) StartPolling() {
+	c.receivesUpdates.Store(true)
+	go c.pollUpdates()
+}
+
+func (c *Client) pollUpdates() {
+	if dErr := c.deleteWebhook(); dErr != nil {
+		log.Error().Err(dErr).Msgf("[%s] cannot delete webhook", telePollPrefix)
+	}
+	var offset *int
+	if lastUpdateId := c.getLastUpdateId(); lastUpdateId != nil {
+		next := *lastUpdateId + 1
+		offset = &next
+	}
+	log.Info().Msgf("[%s] start polling Telegram updates", telePollPrefix)
+
+	for {
+		apiResp, reqErr := c.getRequest(c.getUpdatesUrl(updatesLimit, pollTimeoutSeconds, offset), "getUpdates")
+		if reqErr != nil {
+			time.Sleep(c.retryDelay)
+			continue
+		}
+		var updates []Update
+		if jErr := json.Unmarshal(apiResp.Result, &updates); jErr != nil {
+			log.Error().Err(jErr).Msgf("[%s] cannot unmarshal updates", telePollPrefix)
+			time.Sleep(c.retryDelay)
+			continue
+		}
+
+		for _, update := range updates {
+			c.dispatcher.Dispatch(update)
+			next := update.UpdateID + 1
+			offset = &next
+		}
+	}
+}
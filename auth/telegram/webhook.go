@@ -0,0 +1,101 @@
+package telegram
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	teleWebhookPrefix     = "telegram/webhook"
+	WebhookSecretHeader   = "X-Telegram-Bot-Api-Secret-Token"
+	webhookSecretLength   = 32
+	maxWebhookRequestSize = 1 << 20 // 1 MiB, updates are small JSON documents
+)
+
+// StartWebhook registers given HTTPS URL as the bot webhook and returns
+// handler which should be served on that URL. Updates received by the handler
+// are passed to subscribed handlers, the same way as in case of polling. New
+// secret token is generated on each start, so only Telegram knows it. Pending
+// updates are dropped, so old commands are not executed again after restart.
+func (c *Client) StartWebhook(webhookUrl string) (http.HandlerFunc, error) {
+	secretToken := rand.AlphanumStr(webhookSecretLength)
+	params := url.Values{}
+	params.Set("url", webhookUrl)
+	params.Set("secret_token", secretToken)
+	params.Set("drop_pending_updates", "true")
+
+	apiResp, reqErr := c.getRequest(c.methodUrl("setWebhook", params), "setWebhook")
+	if reqErr != nil {
+		return nil, reqErr
+	}
+	if !apiResp.Ok {
+		return nil, fmt.Errorf("cannot set webhook: %s", apiResp.Description)
+	}
+
+	c.receivesUpdates.Store(true)
+	log.Info().Str("url", webhookUrl).Msgf("[%s] webhook registered", teleWebhookPrefix)
+	return c.webhookHandler(secretToken), nil
+}
+
+// Handles updates sent by Telegram to the webhook. Requests without correct
+// secret token header are rejected.
+func (c *Client) webhookHandler(secretToken string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		headerToken := r.Header.Get(WebhookSecretHeader)
+		if subtle.ConstantTimeCompare([]byte(headerToken), []byte(secretToken)) != 1 {
+			log.Warn().Str("remoteAddr", r.RemoteAddr).Msgf("[%s] request with incorrect secret token", teleWebhookPrefix)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, rErr := io.ReadAll(io.LimitReader(r.Body, maxWebhookRequestSize))
+		if rErr != nil {
+			log.Error().Err(rErr).Msgf("[%s] cannot read request body", teleWebhookPrefix)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var update Update
+		if jErr := json.Unmarshal(body, &update); jErr != nil {
+			log.Error().Err(jErr).Msgf("[%s] cannot unmarshal update", teleWebhookPrefix)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		c.dispatcher.Dispatch(update)
+		w.WriteHeader(http.StatusOK)
+	}
+}
+
+// Removes webhook, so updates can be read using getUpdates.
+func (c *Client) deleteWebhook() error {
+	_, reqErr := c.getRequest(c.methodUrl("deleteWebhook", url.Values{}), "deleteWebhook")
+	return reqErr
+}
+
+func (c *Client) methodUrl(method string, params url.Values) string {
+	return fmt.Sprintf("%s/bot%s/%s?%s", c.baseUrl, c.botToken, method, params.Encode())
+}
+
+// Transport errors of http.Client contain request URL, which includes the bot
+// token. They're replaced by errors without URL, so the token isn't leaked
+// into logs or stored notification errors.
+func withoutUrl(err error, method string) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return fmt.Errorf("%s [%s] request failed: %w", urlErr.Op, method, urlErr.Err)
+	}
+	return err
+}
@@ -0,0 +1,86 @@
+package telegram
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"homeApp/auth/telegram/telegramtest"
+)
+
+func postUpdate(handler http.HandlerFunc, secretToken string, update Update) int {
+	body, _ := json.Marshal(update)
+	req := httptest.NewRequest(http.MethodPost, "/telegram/webhook", bytes.NewReader(body))
+	req.Header.Set(WebhookSecretHeader, secretToken)
+	rec := httptest.NewRecorder()
+	handler(rec, req)
+	return rec.Code
+}
+
+func TestWebhookChecksSecretToken(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	client := newTestClient(server)
+
+	handler, whErr := client.StartWebhook("https://example.com/telegram/webhook")
+	if whErr != nil {
+		t.Fatalf("unexpected error: %v", whErr)
+	}
+	webhook := server.Webhook()
+	if webhook.Url != "https://example.com/telegram/webhook" || len(webhook.SecretToken) != webhookSecretLength {
+		t.Fatalf("expected webhook registered with secret token, got %+v", webhook)
+	}
+
+	received := make(chan Update, 1)
+	client.Subscribe(func(update Update) { received <- update })
+
+	if code := postUpdate(handler, "incorrect", channelPost(1, "text")); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 for incorrect secret token, got %d", code)
+	}
+	if code := postUpdate(handler, "", channelPost(1, "text")); code != http.StatusUnauthorized {
+		t.Errorf("expected 401 without secret token, got %d", code)
+	}
+	if len(received) != 0 {
+		t.Fatal("expected rejected updates not to be dispatched")
+	}
+
+	if code := postUpdate(handler, webhook.SecretToken, channelPost(2, "text")); code != http.StatusOK {
+		t.Errorf("expected 200 for correct secret token, got %d", code)
+	}
+	select {
+	case update := <-received:
+		if update.UpdateID != 2 {
+			t.Errorf("expected update 2 to be dispatched, got %d", update.UpdateID)
+		}
+	default:
+		t.Error("expected update to be dispatched")
+	}
+}
+
+func TestCheckMessageWithPatternWebhook(t *testing.T) {
+	server := telegramtest.NewServer(testBotToken)
+	defer server.Close()
+	client := newTestClient(server)
+	handler, _ := client.StartWebhook("https://example.com/telegram/webhook")
+	secretToken := server.Webhook().SecretToken
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		command := channelPost(1, "/home")
+		command.ChannelPost.Entities = []MessageEntity{{Type: "bot_command", Offset: 0, Length: 5}}
+		postUpdate(handler, secretToken, command)
+		postUpdate(handler, secretToken, channelPost(2, "wrong"))
+		postUpdate(handler, secretToken, channelPost(3, "ABC123"))
+	}()
+
+	isMatched, err := client.CheckMessageWithPattern("ABC123", 5*time.Second)
+	if !isMatched || err != nil {
+		t.Fatalf("expected match, got (%v, %v)", isMatched, err)
+	}
+	if calls := server.GetUpdatesCalls(); calls != 0 {
+		t.Errorf("expected no getUpdates calls in webhook mode, got %d", calls)
+	}
+}
@@ -0,0 +1,74 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test vectors from RFC 6238 Appendix B (SHA1), truncated to 6 digits.
+func TestTotpRfc6238Vectors(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	testCases := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unixTs, expected := range testCases {
+		code := hotpCode(secret, totpCounter(time.Unix(unixTs, 0)))
+		if code != expected {
+			t.Errorf("for time %d expected code %s, got %s", unixTs, expected, code)
+		}
+	}
+}
+
+func TestVerifyTotp(t *testing.T) {
+	secret := []byte("12345678901234567890")
+	now := time.Unix(1111111109, 0)
+
+	counter, ok := verifyTotp(secret, "081804", now, 0)
+	if !ok {
+		t.Fatal("expected code for current time step to be valid")
+	}
+	if counter != totpCounter(now) {
+		t.Errorf("expected counter %d, got %d", totpCounter(now), counter)
+	}
+
+	// Code from previous time step is accepted because of clock drift
+	prevCode := hotpCode(secret, totpCounter(now)-1)
+	if _, ok := verifyTotp(secret, prevCode, now, 0); !ok {
+		t.Error("expected code from previous time step to be valid")
+	}
+
+	// Code from two time steps ago is rejected
+	oldCode := hotpCode(secret, totpCounter(now)-2)
+	if _, ok := verifyTotp(secret, oldCode, now, 0); ok {
+		t.Error("expected code from two time steps ago to be invalid")
+	}
+
+	// Already used code is rejected
+	if _, ok := verifyTotp(secret, "081804", now, counter); ok {
+		t.Error("expected already used code to be invalid")
+	}
+
+	if _, ok := verifyTotp(secret, "12345", now, 0); ok {
+		t.Error("expected code of incorrect length to be invalid")
+	}
+}
+
+func TestTotpUri(t *testing.T) {
+	uri := totpUri("test user", []byte("12345678901234567890"))
+	expectedPrefix := "otpauth://totp/HomeApp:test%20user?"
+	if !strings.HasPrefix(uri, expectedPrefix) {
+		t.Errorf("expected URI to start with %s, got %s", expectedPrefix, uri)
+	}
+	if !strings.Contains(uri, "secret=GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ") {
+		t.Errorf("expected base32 encoded secret in URI, got %s", uri)
+	}
+	if !strings.Contains(uri, "issuer=HomeApp") {
+		t.Errorf("expected issuer in URI, got %s", uri)
+	}
+}
@@ -0,0 +1,288 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"time"
+
+	"homeApp/auth/telegram"
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authTwoFactorPrefix = "auth/twoFactor"
+	recoveryCodesCount  = 10
+	recoveryCodeLength  = 10
+)
+
+// Second factor methods which can be chosen by users.
+const (
+	TwoFactorNone     = "none"
+	TwoFactorTelegram = "telegram"
+	TwoFactorTotp     = "totp"
+)
+
+var (
+	ErrTwoFactorMethodUnavailable = errors.New("second factor method is not available")
+	ErrTotpNotConfigured          = errors.New("TOTP is not configured - secrets encryption key is not set")
+	ErrTotpNoPendingEnrollment    = errors.New("there is no pending TOTP enrollment")
+	ErrTotpInvalidCode            = errors.New("invalid TOTP code")
+)
+
+// TwoFactor manages per-user second factor settings and dispatches second
+// factor verification to provider of user's method. Users without explicit
+// settings use DefaultMethod.
+type TwoFactor struct {
+	DbClient      *db.Client
+	SecretBox     *SecretBox // nil when TOTP is not configured
+	Providers     map[string]TwoFactorProvider
+	DefaultMethod string
+}
+
+// NewTwoFactor prepares providers based on configuration. Telegram provider is
+// available only when telegramClient is not nil and TOTP provider only when
+// secretBox is not nil.
+func NewTwoFactor(dbClient *db.Client, secretBox *SecretBox, telegramClient *telegram.Client,
+	defaultMethod string) *TwoFactor {
+	providers := map[string]TwoFactorProvider{
+		TwoFactorNone: NoopProvider{},
+	}
+	if telegramClient != nil {
+		providers[TwoFactorTelegram] = TelegramProvider{Client: telegramClient, Timeout: twoFATimout}
+	}
+	if secretBox != nil {
+		providers[TwoFactorTotp] = TotpProvider{DbClient: dbClient, SecretBox: secretBox}
+	}
+
+	return &TwoFactor{
+		DbClient:      dbClient,
+		SecretBox:     secretBox,
+		Providers:     providers,
+		DefaultMethod: defaultMethod,
+	}
+}
+
+// TwoFactorStatus describes second factor settings of a user.
+type TwoFactorStatus struct {
+	Method            string
+	AvailableMethods  []string
+	TotpAvailable     bool
+	TotpPending       *TotpEnrollment
+	RecoveryCodesLeft int
+}
+
+// TotpEnrollment contains data required to add TOTP secret to authenticator
+// app.
+type TotpEnrollment struct {
+	Uri    string
+	Secret string
+}
+
+// AvailableMethods lists second factor methods which can be chosen directly.
+// TOTP is not included, because it's chosen by confirming TOTP enrollment.
+func (tf *TwoFactor) AvailableMethods() []string {
+	methods := []string{TwoFactorNone}
+	if _, ok := tf.Providers[TwoFactorTelegram]; ok {
+		methods = append(methods, TwoFactorTelegram)
+	}
+	return methods
+}
+
+// Status reads second factor settings of given user.
+func (tf *TwoFactor) Status(user db.User) (TwoFactorStatus, error) {
+	status := TwoFactorStatus{
+		Method:           tf.DefaultMethod,
+		AvailableMethods: tf.AvailableMethods(),
+		TotpAvailable:    tf.SecretBox != nil,
+	}
+
+	settings, sErr := tf.DbClient.UserTwoFactorById(user.UserId)
+	if sErr == sql.ErrNoRows {
+		return status, nil
+	}
+	if sErr != nil {
+		return TwoFactorStatus{}, sErr
+	}
+	status.Method = settings.Method
+
+	if settings.TotpPendingSecretEncrypted != nil && tf.SecretBox != nil {
+		secret, oErr := tf.SecretBox.Open(settings.TotpPendingSecretEncrypted)
+		if oErr != nil {
+			log.Error().Err(oErr).Int("userId", user.UserId).
+				Msgf("[%s] cannot decrypt pending TOTP secret", authTwoFactorPrefix)
+			return TwoFactorStatus{}, oErr
+		}
+		enrollment := newTotpEnrollment(user.Username, secret)
+		status.TotpPending = &enrollment
+	}
+
+	if settings.Method == TwoFactorTotp {
+		codesLeft, cErr := tf.DbClient.RecoveryCodesUnused(user.UserId)
+		if cErr != nil {
+			return TwoFactorStatus{}, cErr
+		}
+		status.RecoveryCodesLeft = codesLeft
+	}
+
+	return status, nil
+}
+
+// SetMethod sets second factor method which doesn't require enrollment (none
+// or Telegram). TOTP secret and recovery codes are removed.
+func (tf *TwoFactor) SetMethod(userId int, method string) error {
+	isAvailable := false
+	for _, m := range tf.AvailableMethods() {
+		if m == method {
+			isAvailable = true
+		}
+	}
+	if !isAvailable {
+		return ErrTwoFactorMethodUnavailable
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	upsertErr := tf.DbClient.UserTwoFactorUpsert(db.UserTwoFactor{
+		UserId:   userId,
+		Method:   method,
+		UpdateTs: nowTs,
+	})
+	if upsertErr != nil {
+		return upsertErr
+	}
+
+	log.Info().Int("userId", userId).Str("method", method).
+		Msgf("[%s] second factor method changed", authTwoFactorPrefix)
+	return tf.DbClient.RecoveryCodesReplace(userId, nil, nowTs)
+}
+
+// StartTotpEnrollment generates new TOTP secret for given user and stores it
+// as pending. Current second factor method is kept until enrollment is
+// confirmed.
+func (tf *TwoFactor) StartTotpEnrollment(user db.User) (TotpEnrollment, error) {
+	if tf.SecretBox == nil {
+		return TotpEnrollment{}, ErrTotpNotConfigured
+	}
+
+	settings, sErr := tf.DbClient.UserTwoFactorById(user.UserId)
+	if sErr == sql.ErrNoRows {
+		settings = db.UserTwoFactor{UserId: user.UserId, Method: tf.DefaultMethod}
+	} else if sErr != nil {
+		return TotpEnrollment{}, sErr
+	}
+
+	secret := rand.Bytes(totpSecretLength)
+	settings.TotpPendingSecretEncrypted = tf.SecretBox.Seal(secret)
+	settings.UpdateTs = time.Now().UTC().Format(db.TimestampFormat)
+
+	upsertErr := tf.DbClient.UserTwoFactorUpsert(settings)
+	if upsertErr != nil {
+		return TotpEnrollment{}, upsertErr
+	}
+
+	log.Info().Int("userId", user.UserId).Msgf("[%s] started TOTP enrollment", authTwoFactorPrefix)
+	return newTotpEnrollment(user.Username, secret), nil
+}
+
+// ConfirmTotpEnrollment verifies code generated by authenticator app against
+// pending TOTP secret. On success TOTP becomes user's second factor method
+// and new recovery codes are returned. Those are stored only as hashes, so
+// they can be presented to the user only once.
+func (tf *TwoFactor) ConfirmTotpEnrollment(userId int, code string) ([]string, error) {
+	if tf.SecretBox == nil {
+		return nil, ErrTotpNotConfigured
+	}
+
+	settings, sErr := tf.DbClient.UserTwoFactorById(userId)
+	if sErr == sql.ErrNoRows || (sErr == nil && settings.TotpPendingSecretEncrypted == nil) {
+		return nil, ErrTotpNoPendingEnrollment
+	}
+	if sErr != nil {
+		return nil, sErr
+	}
+
+	secret, oErr := tf.SecretBox.Open(settings.TotpPendingSecretEncrypted)
+	if oErr != nil {
+		log.Error().Err(oErr).Int("userId", userId).Msgf("[%s] cannot decrypt pending TOTP secret", authTwoFactorPrefix)
+		return nil, oErr
+	}
+	counter, isValid := verifyTotp(secret, code, time.Now(), 0)
+	if !isValid {
+		return nil, ErrTotpInvalidCode
+	}
+
+	now := time.Now().UTC()
+	upsertErr := tf.DbClient.UserTwoFactorUpsert(db.UserTwoFactor{
+		UserId:              userId,
+		Method:              TwoFactorTotp,
+		TotpSecretEncrypted: settings.TotpPendingSecretEncrypted,
+		TotpLastCounter:     counter,
+		UpdateTs:            now.Format(db.TimestampFormat),
+	})
+	if upsertErr != nil {
+		return nil, upsertErr
+	}
+
+	codes := make([]string, recoveryCodesCount)
+	codesHashed := make([]string, recoveryCodesCount)
+	for idx := range codes {
+		code := rand.AlphanumStr(recoveryCodeLength)
+		codes[idx] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		codesHashed[idx] = hashRecoveryCode(codes[idx])
+	}
+	rcErr := tf.DbClient.RecoveryCodesReplace(userId, codesHashed, now.Format(db.TimestampFormat))
+	if rcErr != nil {
+		return nil, rcErr
+	}
+
+	log.Info().Int("userId", userId).Msgf("[%s] TOTP enrollment confirmed", authTwoFactorPrefix)
+	return codes, nil
+}
+
+// Begin starts second step of login using provider of user's method.
+func (tf *TwoFactor) Begin(user db.User) (TwoFactorChallenge, error) {
+	method := tf.DefaultMethod
+	settings, sErr := tf.DbClient.UserTwoFactorById(user.UserId)
+	if sErr == nil {
+		method = settings.Method
+	} else if sErr != sql.ErrNoRows {
+		return TwoFactorChallenge{}, sErr
+	}
+
+	provider, ok := tf.Providers[method]
+	if !ok {
+		log.Error().Int("userId", user.UserId).Str("method", method).
+			Msgf("[%s] provider of user's 2FA method is not configured", authTwoFactorPrefix)
+		return TwoFactorChallenge{}, ErrTwoFactorMethodUnavailable
+	}
+	return provider.Begin(user)
+}
+
+// Verify checks user response to given challenge using provider which issued
+// the challenge.
+func (tf *TwoFactor) Verify(user db.User, challenge TwoFactorChallenge, response string) (bool, error) {
+	provider, ok := tf.Providers[challenge.Method]
+	if !ok {
+		return false, ErrTwoFactorMethodUnavailable
+	}
+	return provider.Verify(user, challenge, response)
+}
+
+func newTotpEnrollment(username string, secret []byte) TotpEnrollment {
+	return TotpEnrollment{
+		Uri:    totpUri(username, secret),
+		Secret: totpBase32.EncodeToString(secret),
+	}
+}
+
+// Recovery codes have enough entropy, so slow password hash is not needed.
+func hashRecoveryCode(code string) string {
+	normalized := strings.ReplaceAll(strings.TrimSpace(code), "-", "")
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
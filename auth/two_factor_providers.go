@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"homeApp/auth/telegram"
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog/log"
+)
+
+const telegramCodeLength = 6
+
+// TwoFactorProvider performs second step of login. Begin is called right after
+// successful password check and issues a challenge which is presented to the
+// user. Verify checks user response to that challenge.
+type TwoFactorProvider interface {
+	Begin(user db.User) (TwoFactorChallenge, error)
+	Verify(user db.User, challenge TwoFactorChallenge, response string) (bool, error)
+}
+
+// TwoFactorChallenge is issued by TwoFactorProvider for single login attempt.
+type TwoFactorChallenge struct {
+	Method         string
+	Prompt         string // Displayed to the user on 2FA page
+	NeedsResponse  bool   // Whenever user needs to type in response (code)
+	ExpectedAnswer string // Provider specific, never displayed
+	StartTs        time.Time
+}
+
+// NoopProvider is used when 2FA is turned off. Every challenge is
+// successfully verified.
+type NoopProvider struct{}
+
+func (NoopProvider) Begin(user db.User) (TwoFactorChallenge, error) {
+	return TwoFactorChallenge{Method: TwoFactorNone, StartTs: time.Now()}, nil
+}
+
+func (NoopProvider) Verify(user db.User, challenge TwoFactorChallenge, response string) (bool, error) {
+	return true, nil
+}
+
+// TelegramProvider verifies second factor by waiting for user's message on
+// Telegram channel. Random one-time code is presented on the login page and
+// user has to post exactly that code on the channel. Verify blocks until the
+// code is found, too many other messages are posted or timeout, so it should
+// be called in the background.
+type TelegramProvider struct {
+	Client  *telegram.Client
+	Timeout time.Duration
+}
+
+func (tp TelegramProvider) Begin(user db.User) (TwoFactorChallenge, error) {
+	// Code is shown only on the login page, so only the one who knows the
+	// password can post it on the channel.
+	code := rand.NumericStr(telegramCodeLength)
+	sendErr := tp.Client.SendMessage(fmt.Sprintf(
+		"[2FA] Login attempt of user [%s]. Post the code shown on the login page to confirm.", user.Username))
+	if sendErr != nil {
+		return TwoFactorChallenge{}, sendErr
+	}
+	return TwoFactorChallenge{
+		Method: TwoFactorTelegram,
+		Prompt: fmt.Sprintf("Post code %s on the Telegram channel. This page continues automatically once it's confirmed",
+			code),
+		ExpectedAnswer: code,
+		StartTs:        time.Now(),
+	}, nil
+}
+
+func (tp TelegramProvider) Verify(user db.User, challenge TwoFactorChallenge, response string) (bool, error) {
+	return tp.Client.CheckMessageWithPattern(challenge.ExpectedAnswer, tp.Timeout)
+}
+
+// TotpProvider verifies TOTP codes from authenticator app. One-time recovery
+// codes are accepted as well.
+type TotpProvider struct {
+	DbClient  *db.Client
+	SecretBox *SecretBox
+}
+
+func (tp TotpProvider) Begin(user db.User) (TwoFactorChallenge, error) {
+	return TwoFactorChallenge{
+		Method:        TwoFactorTotp,
+		Prompt:        "Enter code from your authenticator app or one of recovery codes",
+		NeedsResponse: true,
+		StartTs:       time.Now(),
+	}, nil
+}
+
+func (tp TotpProvider) Verify(user db.User, challenge TwoFactorChallenge, response string) (bool, error) {
+	code := strings.TrimSpace(response)
+	if code == "" {
+		return false, nil
+	}
+
+	settings, sErr := tp.DbClient.UserTwoFactorById(user.UserId)
+	if sErr != nil {
+		return false, sErr
+	}
+	secret, oErr := tp.SecretBox.Open(settings.TotpSecretEncrypted)
+	if oErr != nil {
+		log.Error().Err(oErr).Int("userId", user.UserId).Msgf("[%s] cannot decrypt TOTP secret", authTwoFactorPrefix)
+		return false, oErr
+	}
+
+	counter, isValid := verifyTotp(secret, code, time.Now(), settings.TotpLastCounter)
+	if isValid {
+		// Concurrent login might have used the same code in the meantime
+		return tp.DbClient.UserTotpCounterUpdate(user.UserId, counter)
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	isUsed, useErr := tp.DbClient.RecoveryCodeUse(user.UserId, hashRecoveryCode(code), nowTs)
+	if useErr != nil {
+		return false, useErr
+	}
+	if isUsed {
+		log.Warn().Int("userId", user.UserId).Msgf("[%s] recovery code was used", authTwoFactorPrefix)
+	}
+	return isUsed, nil
+}
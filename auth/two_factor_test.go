@@ -0,0 +1,105 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"homeApp/db"
+	"homeApp/rand"
+)
+
+// Creates TwoFactor with TOTP configured on top of given database.
+func newTestTwoFactor(t *testing.T, dbClient *db.Client) *TwoFactor {
+	t.Helper()
+	secretBox, sErr := NewSecretBox(rand.Bytes(SecretBoxKeyLength))
+	if sErr != nil {
+		t.Fatal(sErr)
+	}
+	return NewTwoFactor(dbClient, secretBox, nil, TwoFactorNone)
+}
+
+// Sets given second factor method of the user. For TOTP new secret is stored
+// and returned.
+func setTestTwoFactor(t *testing.T, tf *TwoFactor, userId int, method string) []byte {
+	t.Helper()
+	settings := db.UserTwoFactor{
+		UserId:   userId,
+		Method:   method,
+		UpdateTs: time.Now().UTC().Format(db.TimestampFormat),
+	}
+	var secret []byte
+	if method == TwoFactorTotp {
+		secret = rand.Bytes(totpSecretLength)
+		settings.TotpSecretEncrypted = tf.SecretBox.Seal(secret)
+	}
+	if uErr := tf.DbClient.UserTwoFactorUpsert(settings); uErr != nil {
+		t.Fatal(uErr)
+	}
+	return secret
+}
+
+func TestTwoFactorBegin(t *testing.T) {
+	dbClient := newTestDbClient(t)
+	tf := newTestTwoFactor(t, dbClient)
+
+	testCases := []struct {
+		method        string // empty for user without settings
+		challenge     string
+		needsResponse bool
+		err           error
+	}{
+		{"", TwoFactorNone, false, nil},
+		{TwoFactorNone, TwoFactorNone, false, nil},
+		{TwoFactorTotp, TwoFactorTotp, true, nil},
+		{TwoFactorTelegram, "", false, ErrTwoFactorMethodUnavailable},
+	}
+
+	for idx, tc := range testCases {
+		user := db.User{UserId: idx + 1, Username: "alice"}
+		if tc.method != "" {
+			setTestTwoFactor(t, tf, user.UserId, tc.method)
+		}
+
+		challenge, err := tf.Begin(user)
+		if err != tc.err {
+			t.Errorf("[%s] expected error %v, got %v", tc.method, tc.err, err)
+			continue
+		}
+		if challenge.Method != tc.challenge || challenge.NeedsResponse != tc.needsResponse {
+			t.Errorf("[%s] expected %s challenge (needs response %v), got %+v", tc.method, tc.challenge,
+				tc.needsResponse, challenge)
+		}
+	}
+}
+
+func TestTwoFactorVerify(t *testing.T) {
+	dbClient := newTestDbClient(t)
+	tf := newTestTwoFactor(t, dbClient)
+	user := db.User{UserId: 1, Username: "alice"}
+	secret := setTestTwoFactor(t, tf, user.UserId, TwoFactorTotp)
+	counter := totpCounter(time.Now())
+	code := hotpCode(secret, counter)
+	totpChallenge := TwoFactorChallenge{Method: TwoFactorTotp, NeedsResponse: true}
+
+	testCases := []struct {
+		name      string
+		challenge TwoFactorChallenge
+		response  string
+		isValid   bool
+		err       error
+	}{
+		{"noop provider", TwoFactorChallenge{Method: TwoFactorNone}, "", true, nil},
+		{"empty code", totpChallenge, "", false, nil},
+		{"incorrect code", totpChallenge, hotpCode(secret, counter+10), false, nil},
+		{"correct code", totpChallenge, code, true, nil},
+		{"reused code", totpChallenge, code, false, nil},
+		{"unknown method", TwoFactorChallenge{Method: "sms"}, code, false, ErrTwoFactorMethodUnavailable},
+	}
+
+	for _, tc := range testCases {
+		isValid, err := tf.Verify(user, tc.challenge, tc.response)
+		if isValid != tc.isValid || err != tc.err {
+			t.Errorf("[%s] expected (%v, %v), got (%v, %v)", tc.name, tc.isValid, tc.err, isValid, err)
+		}
+	}
+}
@@ -0,0 +1,198 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"homeApp/db"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	authAdminPrefix = "auth/admin"
+	RoleAdmin       = "admin"
+)
+
+var ErrUsernameRequired = errors.New("username is required")
+
+// UserAdmin performs user administration. It's used by both admin page and
+// "user" CLI subcommand.
+type UserAdmin struct {
+	DbClient      *db.Client
+	PendingLogins *PendingLogins // nil for CLI, which doesn't share the state
+}
+
+// UserSummary describes user on the users list.
+type UserSummary struct {
+	UserId     int
+	Username   string
+	Email      string
+	IsActive   bool
+	IsAdmin    bool
+	CreateDate string
+	Access     map[string]string // module -> access level
+}
+
+// Users lists all users.
+func (ua UserAdmin) Users() ([]UserSummary, error) {
+	users, uErr := ua.DbClient.UsersAll()
+	if uErr != nil {
+		return nil, uErr
+	}
+
+	summaries := make([]UserSummary, len(users))
+	for idx, user := range users {
+		permissions, pErr := UserPermissions(ua.DbClient, user.UserId)
+		if pErr != nil {
+			return nil, pErr
+		}
+		summaries[idx] = UserSummary{
+			UserId:     user.UserId,
+			Username:   user.Username,
+			Email:      user.Email,
+			IsActive:   user.IsActive,
+			IsAdmin:    permissions.IsAdmin,
+			CreateDate: user.CreateDate,
+			Access:     permissions.Access,
+		}
+	}
+	return summaries, nil
+}
+
+// AddUser creates new active user. Returns UserId of the new user.
+func (ua UserAdmin) AddUser(username, email, password string, isAdmin bool) (int, error) {
+	username = strings.TrimSpace(username)
+	if username == "" {
+		return 0, ErrUsernameRequired
+	}
+	if pErr := ValidatePassword(username, password); pErr != nil {
+		return 0, pErr
+	}
+
+	passwordHashed, salt, hErr := HashPassword(password)
+	if hErr != nil {
+		return 0, hErr
+	}
+
+	var roles []string
+	if isAdmin {
+		roles = append(roles, RoleAdmin)
+	}
+	userId, iErr := ua.DbClient.UserInsertNew(db.User{
+		Email:          strings.TrimSpace(email),
+		Username:       username,
+		PasswordHashed: passwordHashed,
+		Salt:           salt,
+	}, roles)
+	if iErr != nil {
+		return 0, iErr
+	}
+
+	log.Info().Str("username", username).Int("userId", userId).Bool("isAdmin", isAdmin).
+		Msgf("[%s] user added", authAdminPrefix)
+	return userId, nil
+}
+
+// SetActive enables or disables user. Sessions of disabled user are revoked
+// and their logins waiting for second factor are dropped. Sessions aren't
+// started for disabled users anyway (UserAuth.NewSession).
+func (ua UserAdmin) SetActive(username string, isActive bool) error {
+	user, uErr := ua.DbClient.UserByUsername(username)
+	if uErr != nil {
+		return uErr
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	if sErr := ua.DbClient.UserSetActive(user.UserId, isActive, nowTs); sErr != nil {
+		return sErr
+	}
+	if !isActive && ua.PendingLogins != nil {
+		ua.PendingLogins.removeUser(user.UserId)
+	}
+	log.Info().Str("username", username).Bool("isActive", isActive).Msgf("[%s] user active flag set", authAdminPrefix)
+	return nil
+}
+
+// ResetPassword sets new password for given user and revokes all user's
+// sessions.
+func (ua UserAdmin) ResetPassword(username, password string) error {
+	if pErr := ValidatePassword(username, password); pErr != nil {
+		return pErr
+	}
+	user, uErr := ua.DbClient.UserByUsername(username)
+	if uErr != nil {
+		return uErr
+	}
+
+	passwordHashed, salt, hErr := HashPassword(password)
+	if hErr != nil {
+		return hErr
+	}
+	if updErr := ua.DbClient.UserUpdatePassword(user.UserId, passwordHashed, salt); updErr != nil {
+		return updErr
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	if rErr := ua.DbClient.SessionsRevokeAll(user.UserId, nowTs, ""); rErr != nil {
+		return rErr
+	}
+	log.Info().Str("username", username).Msgf("[%s] user password reset", authAdminPrefix)
+	return nil
+}
+
+// SetPermission sets access level of given user to the module. AccessNone
+// removes access.
+func (ua UserAdmin) SetPermission(username, module, access string) error {
+	if !isKnownModule(module) {
+		return ErrUnknownModule
+	}
+	user, uErr := ua.DbClient.UserByUsername(username)
+	if uErr != nil {
+		return uErr
+	}
+
+	var setErr error
+	switch access {
+	case AccessNone:
+		setErr = ua.DbClient.UserPermissionDelete(user.UserId, module)
+	case AccessRead, AccessWrite:
+		setErr = ua.DbClient.UserPermissionSet(user.UserId, module, access)
+	default:
+		return ErrUnknownAccess
+	}
+	if setErr != nil {
+		return setErr
+	}
+	log.Info().Str("username", username).Str("module", module).Str("access", access).
+		Msgf("[%s] user permission set", authAdminPrefix)
+	return nil
+}
+
+// SetAdmin grants or revokes admin role of given user.
+func (ua UserAdmin) SetAdmin(username string, isAdmin bool) error {
+	user, uErr := ua.DbClient.UserByUsername(username)
+	if uErr != nil {
+		return uErr
+	}
+	if sErr := ua.DbClient.UserRoleSet(user.UserId, RoleAdmin, isAdmin); sErr != nil {
+		return sErr
+	}
+	log.Info().Str("username", username).Bool("isAdmin", isAdmin).Msgf("[%s] user admin role set", authAdminPrefix)
+	return nil
+}
+
+// IsAdmin checks whenever given user has admin role.
+func (ua UserAdmin) IsAdmin(userId int) (bool, error) {
+	roles, rErr := ua.DbClient.UserRoles(userId)
+	if rErr != nil {
+		return false, rErr
+	}
+	for _, role := range roles {
+		if role == RoleAdmin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,90 @@
+package auth
+
+import "testing"
+
+func TestDisabledUserIsRejected(t *testing.T) {
+	ua := newTestUserAuth(t)
+	admin := UserAdmin{DbClient: ua.DbClient}
+
+	testCases := []struct {
+		username string
+		isActive bool
+		err      error
+	}{
+		{"alice", true, nil},
+		{"bob", false, ErrUserDisabled},
+	}
+
+	for _, tc := range testCases {
+		user := addTestUser(t, ua.DbClient, tc.username, "user password 1")
+		if sErr := admin.SetActive(tc.username, tc.isActive); sErr != nil {
+			t.Fatalf("[%s] cannot set user active: %v", tc.username, sErr)
+		}
+
+		validUser, err := ua.IsUserValid(tc.username, "user password 1")
+		if err != tc.err || validUser.UserId != user.UserId {
+			t.Errorf("[%s] expected user %d with error %v, got user %d with %v", tc.username, user.UserId, tc.err,
+				validUser.UserId, err)
+		}
+		// Incorrect password doesn't reveal that the user is disabled
+		if _, err := ua.IsUserValid(tc.username, "incorrect"); err != ErrInvalidUsernameOrPass {
+			t.Errorf("[%s] expected ErrInvalidUsernameOrPass for incorrect password, got %v", tc.username, err)
+		}
+		if _, err := ua.NewSession(user.UserId, ClientInfo{}); err != tc.err {
+			t.Errorf("[%s] expected new session error %v, got %v", tc.username, tc.err, err)
+		}
+	}
+}
+
+func TestSetActiveRevokesSessions(t *testing.T) {
+	ua := newTestUserAuth(t)
+	pendingLogins := NewPendingLogins()
+	admin := UserAdmin{DbClient: ua.DbClient, PendingLogins: pendingLogins}
+	user := addTestUser(t, ua.DbClient, "alice", "alice password 1")
+	other := addTestUser(t, ua.DbClient, "bob", "bob password 1")
+
+	token, sErr := ua.NewSession(user.UserId, ClientInfo{})
+	if sErr != nil {
+		t.Fatalf("cannot start session: %v", sErr)
+	}
+	otherToken, sErr := ua.NewSession(other.UserId, ClientInfo{})
+	if sErr != nil {
+		t.Fatalf("cannot start session: %v", sErr)
+	}
+	pendingId := pendingLogins.add(user, TwoFactorChallenge{Method: TwoFactorTotp})
+	otherPendingId := pendingLogins.add(other, TwoFactorChallenge{Method: TwoFactorTotp})
+
+	if aErr := admin.SetActive("alice", false); aErr != nil {
+		t.Fatalf("cannot disable user: %v", aErr)
+	}
+	if status, _ := ua.IsJwtTokenValid(token); status.IsValid {
+		t.Error("expected session of disabled user to be revoked")
+	}
+	if _, exists := pendingLogins.get(pendingId); exists {
+		t.Error("expected pending login of disabled user to be dropped")
+	}
+	if status, _ := ua.IsJwtTokenValid(otherToken); !status.IsValid {
+		t.Error("expected session of other user to stay valid")
+	}
+	if _, exists := pendingLogins.get(otherPendingId); !exists {
+		t.Error("expected pending login of other user to be kept")
+	}
+
+	// Enabling the user again doesn't bring back revoked session
+	if aErr := admin.SetActive("alice", true); aErr != nil {
+		t.Fatalf("cannot enable user: %v", aErr)
+	}
+	if status, _ := ua.IsJwtTokenValid(token); status.IsValid {
+		t.Error("expected revoked session to stay invalid after enabling user")
+	}
+	if _, err := ua.NewSession(user.UserId, ClientInfo{}); err != nil {
+		t.Errorf("expected new session of enabled user, got %v", err)
+	}
+}
+
+func TestSetActiveUnknownUser(t *testing.T) {
+	admin := UserAdmin{DbClient: newTestDbClient(t), PendingLogins: NewPendingLogins()}
+	if err := admin.SetActive("unknown", false); err == nil {
+		t.Error("expected error for unknown user")
+	}
+}
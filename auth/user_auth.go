@@ -1,12 +1,10 @@
 package auth
 
 import (
-	"crypto/sha256"
 	"errors"
 	"fmt"
 	"time"
 
-	"homeApp/auth/telegram"
 	"homeApp/db"
 
 	"github.com/golang-jwt/jwt/v4"
@@ -18,37 +16,55 @@ const (
 	twoFATimout    = 60 * time.Second
 )
 
-var ErrInvalidUsernameOrPass = errors.New("invalid username or password")
+var (
+	ErrInvalidUsernameOrPass = errors.New("invalid username or password")
+	ErrUserDisabled          = errors.New("user is disabled")
+	ErrPasswordUnchanged     = errors.New("new password should be different from the current one")
+)
 
 // UserAuthenticator should perform user authentication based on their username
 // and password comparing it against the database. In case when authentication
-// succeeded, then user JWT in string form is returned. In case when
-// authentication failed, then error should be non empty.
+// succeeded, then user data is returned and new session can be started. In
+// case when authentication failed, then error should be non empty.
 type UserAuthenticator interface {
-	IsUserValid(username, password string) (string, error)
+	IsUserValid(username, password string) (db.User, error)
+	NewSession(userId int, client ClientInfo) (string, error)
 	IsJwtTokenValid(jwtString string) (TokenStatus, error)
 	RegenerateJwt(tokenStatus TokenStatus) (string, error)
-	Check2FA(username string) (bool, error)
+	RevokeSession(tokenStatus TokenStatus) error
+	Begin2FA(user db.User) (TwoFactorChallenge, error)
+	Verify2FA(user db.User, challenge TwoFactorChallenge, response string) (bool, error)
+	ChangePassword(tokenStatus TokenStatus, currentPassword, newPassword string) error
+	IsApiTokenValid(token string) (TokenStatus, error)
+	Permissions(userId int) (Permissions, error)
 }
 
 type TokenStatus struct {
 	IsValid      bool
 	UserId       int
+	SessionId    string
+	CsrfToken    string
 	TokenExpUnix int64
+	ApiTokenId   string            // set when authenticated by API token
+	ApiScopes    map[string]string // scopes of API token
 }
 
 // UserAuth performs user authentication against user data in the main
 // database.
 type UserAuth struct {
-	DbClient       *db.Client
-	JwtSigningKey  []byte
-	JwtExpMinutes  int
-	TelegramClient *telegram.Client
+	DbClient      *db.Client
+	SigningKeys   *SigningKeys
+	JwtExpMinutes int
+	TwoFactor     *TwoFactor
 }
 
-// IsUserValid perform user authentication. Password is combined with user's salt,
-// hashed (SHA256) and compared with originally set hashed password.
-func (ua UserAuth) IsUserValid(username, password string) (string, error) {
+// IsUserValid perform user authentication. Password is hashed (Argon2id) using
+// parameters and salt of the stored hash and compared with it. Users which
+// still have legacy SHA256 hash or hash with outdated parameters get their
+// hash upgraded after successful authentication. Disabled users are rejected
+// with ErrUserDisabled, but only after password check. User data is returned
+// along with ErrUserDisabled, so the attempt can be attributed to the user.
+func (ua UserAuth) IsUserValid(username, password string) (db.User, error) {
 	startTs := time.Now()
 	log.Info().Str("username", username).Msgf("[%s] start user authentication", authUserPrefix)
 
@@ -56,30 +72,38 @@ func (ua UserAuth) IsUserValid(username, password string) (string, error) {
 	if uErr != nil {
 		log.Error().Err(uErr).Str("username", username).
 			Msgf("[%s] cannot fetch user data", authUserPrefix)
-		return "", ErrInvalidUsernameOrPass
+		// Hashing anyway, so response time doesn't reveal whenever user exists
+		HashPassword(password)
+		return db.User{}, ErrInvalidUsernameOrPass
 	}
 
-	givenPassHashed := ua.prepHashedPassword(password, user.Salt)
-	if givenPassHashed != user.PasswordHashed {
-		return "", ErrInvalidUsernameOrPass
+	isValid, needsRehash, vErr := verifyPassword(password, user.PasswordHashed, user.Salt)
+	if vErr != nil {
+		log.Error().Err(vErr).Str("username", username).
+			Msgf("[%s] cannot verify password against stored hash", authUserPrefix)
+		return db.User{}, ErrInvalidUsernameOrPass
 	}
-
-	userToken, tokenErr := ua.prepJwtString(user.UserId)
-	if tokenErr != nil {
-		log.Error().Err(tokenErr).Str("username", username).
-			Msgf("[%s] cannot sign user token", authUserPrefix)
-		return "", tokenErr
+	if !isValid {
+		return db.User{}, ErrInvalidUsernameOrPass
+	}
+	if !user.IsActive {
+		log.Warn().Str("username", username).Msgf("[%s] disabled user tried to log in", authUserPrefix)
+		return user, ErrUserDisabled
+	}
+	if needsRehash {
+		ua.upgradePasswordHash(user, password)
 	}
 
 	elapsed := time.Since(startTs)
 	log.Info().Str("username", username).Dur("duration", elapsed).
 		Msgf("[%s] finished user authentication", authUserPrefix)
 
-	return userToken, nil
+	return user, nil
 }
 
 // IsJwtTokenValid verifies whenever given JWT string is valid. That means was
-// generated and signed by this backend for existing user.
+// generated and signed by this backend for existing user and its server-side
+// session was neither revoked nor expired.
 func (ua UserAuth) IsJwtTokenValid(jwtString string) (TokenStatus, error) {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start jwt validation", authUserPrefix)
@@ -88,11 +112,15 @@ func (ua UserAuth) IsJwtTokenValid(jwtString string) (TokenStatus, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
 		}
-		return ua.JwtSigningKey, nil
+		keyId, kidOk := token.Header["kid"].(string)
+		if !kidOk {
+			return nil, fmt.Errorf("token has no kid header")
+		}
+		return ua.SigningKeys.ByKeyId(keyId)
 	})
 	if err != nil {
 		log.Error().Err(err).Str("jwt", jwtString).
-			Msgf("[%s] cannot parse given JWT")
+			Msgf("[%s] cannot parse given JWT", authUserPrefix)
 		return TokenStatus{}, err
 	}
 
@@ -123,49 +151,80 @@ func (ua UserAuth) IsJwtTokenValid(jwtString string) (TokenStatus, error) {
 		return TokenStatus{}, noExp
 	}
 
+	sessionId, sidIsOk := claims["jti"].(string)
+	if !sidIsOk {
+		noJti := fmt.Errorf("token has not jti claim for session ID")
+		log.Error().Err(noJti).Msgf("[%s] jwt has no jti claim", authUserPrefix)
+		return TokenStatus{}, noJti
+	}
+
 	userIdInt := int(userId.(float64))
 	tokenExpInt := int64(tokenExp.(float64))
-	elapsed := time.Since(startTs)
-	log.Info().Int("userId", userIdInt).Int64("tokenExp", tokenExpInt).
-		Dur("duration", elapsed).Msgf("[%s] finished jwt validation", authUserPrefix)
-
-	return TokenStatus{
+	tokenStatus := TokenStatus{
 		IsValid:      true,
 		UserId:       userIdInt,
+		SessionId:    sessionId,
 		TokenExpUnix: tokenExpInt,
-	}, nil
+	}
+
+	session, sessErr := ua.activeSession(tokenStatus)
+	if sessErr != nil {
+		return TokenStatus{}, sessErr
+	}
+	isActive := session != nil
+	tokenStatus.IsValid = isActive
+	if isActive {
+		tokenStatus.CsrfToken = session.CsrfToken
+	}
+
+	elapsed := time.Since(startTs)
+	log.Info().Int("userId", userIdInt).Int64("tokenExp", tokenExpInt).Bool("isValid", isActive).
+		Dur("duration", elapsed).Msgf("[%s] finished jwt validation", authUserPrefix)
+
+	return tokenStatus, nil
 }
 
 // RegenerateJwt takes current session JWT and produce another one with shifted
 // timestamp of expiration. Other data stays without any change. This can be
 // used to prolong current user session.
 func (ua UserAuth) RegenerateJwt(tokenStatus TokenStatus) (string, error) {
-	return ua.prepJwtString(tokenStatus.UserId)
-}
+	expireTs := time.Now().UTC().Add(time.Duration(ua.JwtExpMinutes) * time.Minute)
 
-// Check2FA verifies second step in two-factor authentication which is via
-// Telegram channel. This step might be turned off via initial configuration.
-// In this case TelegramClient should be nil.
-func (ua UserAuth) Check2FA(username string) (bool, error) {
-	if ua.TelegramClient == nil { // 2FA is turned off in this case
-		return true, nil
+	prolongErr := ua.DbClient.SessionProlong(tokenStatus.SessionId, expireTs.Format(db.TimestampFormat))
+	if prolongErr != nil {
+		return "", prolongErr
 	}
 
-	twoFaPassed, twoFaErr := ua.TelegramClient.CheckMessageWithPattern(username, twoFATimout)
-	if twoFaErr != nil {
-		return false, twoFaErr
-	}
+	return ua.prepJwtString(tokenStatus.UserId, tokenStatus.SessionId, expireTs)
+}
 
-	return twoFaPassed, nil
+// Begin2FA starts second step of two-factor authentication using provider
+// of the method chosen by the user (Telegram channel, TOTP or none).
+func (ua UserAuth) Begin2FA(user db.User) (TwoFactorChallenge, error) {
+	return ua.TwoFactor.Begin(user)
 }
 
-func (ua UserAuth) prepJwtString(userId int) (string, error) {
+// Verify2FA checks user response to the second factor challenge.
+func (ua UserAuth) Verify2FA(user db.User, challenge TwoFactorChallenge, response string) (bool, error) {
+	return ua.TwoFactor.Verify(user, challenge, response)
+}
+
+// Permissions reads roles and module permissions of given user.
+func (ua UserAuth) Permissions(userId int) (Permissions, error) {
+	return UserPermissions(ua.DbClient, userId)
+}
+
+func (ua UserAuth) prepJwtString(userId int, sessionId string, expireTs time.Time) (string, error) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"userId": userId,
-		"exp":    time.Now().UTC().Add(time.Duration(ua.JwtExpMinutes) * time.Minute).UnixMilli(),
+		"jti":    sessionId,
+		"exp":    expireTs.UnixMilli(),
 	})
 
-	tokenString, err := token.SignedString(ua.JwtSigningKey)
+	keyId, signingKey := ua.SigningKeys.Current()
+	token.Header["kid"] = keyId
+
+	tokenString, err := token.SignedString(signingKey)
 	if err != nil {
 		return "", err
 	}
@@ -173,7 +232,58 @@ func (ua UserAuth) prepJwtString(userId int) (string, error) {
 	return tokenString, nil
 }
 
-func (ua UserAuth) prepHashedPassword(givenPassword, salt string) string {
-	givenPassHashed := sha256.Sum256([]byte(givenPassword + salt))
-	return fmt.Sprintf("%x", givenPassHashed)
+// ChangePassword sets new password of logged in user after checking the
+// current one. New password has to meet the password policy and is hashed
+// with fresh salt. All other sessions of the user are revoked, so only the
+// current session stays logged in.
+func (ua UserAuth) ChangePassword(tokenStatus TokenStatus, currentPassword, newPassword string) error {
+	user, uErr := ua.DbClient.UserByUserId(tokenStatus.UserId)
+	if uErr != nil {
+		return uErr
+	}
+	if _, authErr := ua.IsUserValid(user.Username, currentPassword); authErr != nil {
+		return authErr
+	}
+	if newPassword == currentPassword {
+		return ErrPasswordUnchanged
+	}
+	if pErr := ValidatePassword(user.Username, newPassword); pErr != nil {
+		return pErr
+	}
+
+	passwordHashed, salt, hErr := HashPassword(newPassword)
+	if hErr != nil {
+		return hErr
+	}
+	if updErr := ua.DbClient.UserUpdatePassword(user.UserId, passwordHashed, salt); updErr != nil {
+		return updErr
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	if rErr := ua.DbClient.SessionsRevokeAll(user.UserId, nowTs, tokenStatus.SessionId); rErr != nil {
+		return rErr
+	}
+	log.Info().Int("userId", user.UserId).Msgf("[%s] password changed, other sessions revoked", authUserPrefix)
+	return nil
+}
+
+// Replaces user's password hash by Argon2id hash with default parameters. This
+// is done in place after successful authentication, so users don't need to
+// reset passwords. Failure is only logged, because user is already
+// authenticated at this point.
+func (ua UserAuth) upgradePasswordHash(user db.User, password string) {
+	passwordHashed, salt, hErr := HashPassword(password)
+	if hErr != nil {
+		log.Error().Err(hErr).Int("userId", user.UserId).
+			Msgf("[%s] cannot hash password for upgrade", authUserPrefix)
+		return
+	}
+
+	updErr := ua.DbClient.UserUpdatePassword(user.UserId, passwordHashed, salt)
+	if updErr != nil {
+		log.Error().Err(updErr).Int("userId", user.UserId).
+			Msgf("[%s] cannot upgrade password hash", authUserPrefix)
+		return
+	}
+	log.Info().Int("userId", user.UserId).Msgf("[%s] upgraded password hash to Argon2id", authUserPrefix)
 }
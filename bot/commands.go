@@ -0,0 +1,233 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"homeApp/auth/telegram"
+	"homeApp/db"
+	"homeApp/finance"
+)
+
+const (
+	dateLayout        = "2006-01-02"
+	monthLayout       = "2006-01"
+	maxDocumentsFound = 10
+)
+
+var (
+	ErrUnexpectedArgument = errors.New("unexpected argument")
+	ErrMissingArgument    = errors.New("missing argument")
+)
+
+// Replies with the same summary as the home page.
+func (r *Router) homeSummary(_ []string) (string, error) {
+	summary, sErr := r.DbClient.HomeSummary()
+	if sErr != nil {
+		return "", errors.New("could not read summary from the database")
+	}
+	return fmt.Sprintf("Home database summary\n"+
+		"Counters (weekly avg): cold water %.2f l, hot water %.2f l, energy %.2f kWh\n"+
+		"Documents: %d (%.2f MB), latest upload %s\n"+
+		"E-books: %d (%.2f MB), latest upload %s\n"+
+		"Books: %d, latest upload %s\n"+
+		"Finance: latest transaction %s",
+		summary.ColdWaterWeeklyAvg, summary.HotWaterWeeklyAvg, summary.EnergyWeeklyAvg,
+		summary.DocumentsNumber, summary.DocumentsSizeMb, summary.DocumentLatestUploadDate,
+		summary.EbooksNumber, summary.EbooksSizeMb, summary.EbookLatestUploadDate,
+		summary.BooksNumber, summary.BookLatestUploadDate,
+		summary.FinancialLatestOrderDate), nil
+}
+
+// Inserts new counters state, e.g. "2024-05-01 cold=1200 hot=800 kwh=41.5".
+func (r *Router) countersInsert(args []string) (string, error) {
+	water, energy, pErr := parseCountersArgs(args, time.Now())
+	if pErr != nil {
+		return "", pErr
+	}
+	if dbErr := r.DbClient.CountersInsertNew(water, energy, nil); dbErr != nil {
+		return "", errors.New("could not insert counters data into database")
+	}
+	return fmt.Sprintf("Uploaded new counters state for [%s]: Water{Cold: %d liters, Hot: %d liters}, Energy %.2f kWh.",
+		water.Date, water.ColdWaterLiters, water.HotWaterLiters, energy.EnergyKwh), nil
+}
+
+// Replies with aggregated financial transactions of given month, e.g.
+// "month 2024-04". Previous month is taken by default.
+func (r *Router) financeMonth(args []string) (string, error) {
+	if len(args) == 0 || args[0] != "month" {
+		return "", ErrMissingArgument
+	}
+	month, pErr := parseMonthArg(args[1:], time.Now())
+	if pErr != nil {
+		return "", pErr
+	}
+
+	transactions, dbErr := r.DbClient.FinTransMonthly(month.Year(), int(month.Month()))
+	if dbErr != nil {
+		return "", errors.New("could not read transactions from the database")
+	}
+	monthStr := month.Format(monthLayout)
+	if len(transactions) == 0 {
+		return fmt.Sprintf("There are no transactions in %s.", monthStr), nil
+	}
+
+	var agg finance.MonthlyAgg
+	for _, monthAgg := range finance.AggregateMonthly(transactions, "PLN") {
+		agg = monthAgg
+	}
+	return fmt.Sprintf("Finance %s\nTransactions: %d\nInflows: %d, %.2f zł\nOutflows: %d, %.2f zł\n"+
+		"Top outflow: %.2f zł %s",
+		monthStr, agg.NumOfTransactions, agg.NumOfInflows, agg.InflowsAmountSum,
+		agg.NumOfOutflows, agg.OutflowsAmountSum,
+		agg.TopOutflow.AmountValue, agg.TopOutflow.Description), nil
+}
+
+// Finds documents by full text search, e.g. "find insurance".
+func (r *Router) documentsFind(args []string) (string, error) {
+	if len(args) < 2 || args[0] != "find" {
+		return "", ErrMissingArgument
+	}
+	// Quotes would break FTS query phrase
+	phrase := strings.ReplaceAll(strings.Join(args[1:], " "), `"`, "")
+
+	documents, dbErr := r.DbClient.DocumentsFiltered(phrase)
+	if dbErr != nil {
+		return "", errors.New("could not search documents in the database")
+	}
+	if len(documents) == 0 {
+		return fmt.Sprintf("No documents found for [%s].", phrase), nil
+	}
+
+	var reply strings.Builder
+	fmt.Fprintf(&reply, "Found %d documents for [%s]:", len(documents), phrase)
+	for idx, doc := range documents {
+		if idx == maxDocumentsFound {
+			fmt.Fprintf(&reply, "\n... and %d more", len(documents)-maxDocumentsFound)
+			break
+		}
+		fmt.Fprintf(&reply, "\n[%d] %s (%s, uploaded %s)", doc.Id, doc.Name, doc.Category, doc.UploadDate)
+	}
+	return reply.String(), nil
+}
+
+// Replies with stored document file, e.g. "12".
+func (r *Router) documentFile(args []string) (file, error) {
+	documentId, pErr := parseIdArg(args)
+	if pErr != nil {
+		return file{}, pErr
+	}
+	fileSize, sizeErr := r.DbClient.DocumentFileSize(documentId)
+	if sizeErr != nil {
+		return file{}, fmt.Errorf("could not load document %d from the database", documentId)
+	}
+	if fileSize > telegram.MaxUploadSize {
+		return file{}, fileTooLargeError{name: fmt.Sprintf("document %d", documentId), size: fileSize}
+	}
+	content, name, fileExt, dbErr := r.DbClient.DocumentFile(documentId)
+	if dbErr != nil {
+		return file{}, fmt.Errorf("could not load document %d from the database", documentId)
+	}
+	return file{name: name + "." + fileExt, content: content, caption: name}, nil
+}
+
+// Replies with stored e-book file, e.g. "7".
+func (r *Router) bookFile(args []string) (file, error) {
+	bookId, pErr := parseIdArg(args)
+	if pErr != nil {
+		return file{}, pErr
+	}
+	fileSize, sizeErr := r.DbClient.BookFileSize(bookId)
+	if sizeErr != nil {
+		return file{}, fmt.Errorf("could not load e-book file of book %d from the database", bookId)
+	}
+	if fileSize > telegram.MaxUploadSize {
+		return file{}, fileTooLargeError{name: fmt.Sprintf("book %d", bookId), size: fileSize}
+	}
+	content, title, fileExt, dbErr := r.DbClient.BookFile(bookId)
+	if dbErr != nil || len(content) == 0 {
+		return file{}, fmt.Errorf("could not load e-book file of book %d from the database", bookId)
+	}
+	fileName := strings.ReplaceAll(title, " ", "_") + "." + fileExt
+	return file{name: fileName, content: content, caption: title}, nil
+}
+
+// Parses single positive ID argument.
+func parseIdArg(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, fmt.Errorf("%w: id", ErrMissingArgument)
+	}
+	if len(args) > 1 {
+		return 0, fmt.Errorf("%w: %s", ErrUnexpectedArgument, args[1])
+	}
+	id, cErr := strconv.Atoi(args[0])
+	if cErr != nil || id <= 0 {
+		return 0, fmt.Errorf("incorrect id [%s], expected positive number", args[0])
+	}
+	return id, nil
+}
+
+// Parses counters command arguments. Date is optional, today is assumed by
+// default. All of cold, hot and kwh values are required.
+func parseCountersArgs(args []string, now time.Time) (db.WaterCounterEntry, db.EnergyCounterEntry, error) {
+	var water db.WaterCounterEntry
+	var energy db.EnergyCounterEntry
+	date := now.Format(dateLayout)
+	values := make(map[string]string, 3)
+
+	for idx, arg := range args {
+		key, value, isKeyValue := strings.Cut(arg, "=")
+		if !isKeyValue {
+			if idx != 0 {
+				return water, energy, fmt.Errorf("%w: %s", ErrUnexpectedArgument, arg)
+			}
+			if _, dErr := time.Parse(dateLayout, arg); dErr != nil {
+				return water, energy, fmt.Errorf("incorrect date [%s], expected YYYY-MM-DD", arg)
+			}
+			date = arg
+			continue
+		}
+		switch key {
+		case "cold", "hot", "kwh":
+			values[key] = value
+		default:
+			return water, energy, fmt.Errorf("%w: %s", ErrUnexpectedArgument, arg)
+		}
+	}
+	for _, key := range []string{"cold", "hot", "kwh"} {
+		if _, exists := values[key]; !exists {
+			return water, energy, fmt.Errorf("%w: %s", ErrMissingArgument, key)
+		}
+	}
+
+	coldWater, cwErr := strconv.Atoi(values["cold"])
+	hotWater, hwErr := strconv.Atoi(values["hot"])
+	energyKwh, eErr := strconv.ParseFloat(values["kwh"], 64)
+	if cwErr != nil || hwErr != nil || eErr != nil || coldWater < 0 || hotWater < 0 || energyKwh < 0 {
+		return water, energy, errors.New("counters values should be non-negative numbers")
+	}
+
+	water = db.WaterCounterEntry{Date: date, ColdWaterLiters: coldWater, HotWaterLiters: hotWater}
+	energy = db.EnergyCounterEntry{Date: date, EnergyKwh: energyKwh}
+	return water, energy, nil
+}
+
+// Parses optional YYYY-MM month argument. Previous month is the default.
+func parseMonthArg(args []string, now time.Time) (time.Time, error) {
+	switch len(args) {
+	case 0:
+		firstDay := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return firstDay.AddDate(0, -1, 0), nil
+	case 1:
+		month, pErr := time.Parse(monthLayout, args[0])
+		if pErr != nil {
+			return time.Time{}, fmt.Errorf("incorrect month [%s], expected YYYY-MM", args[0])
+		}
+		return month, nil
+	default:
+		return time.Time{}, fmt.Errorf("%w: %s", ErrUnexpectedArgument, args[1])
+	}
+}
@@ -0,0 +1,169 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"homeApp/auth/telegram"
+	"homeApp/db"
+
+	"github.com/rs/zerolog/log"
+)
+
+const botPrefix = "bot"
+
+// ChatSender sends a text message or a file to given Telegram chat.
+type ChatSender interface {
+	SendMessageToChat(chatId int64, text string) error
+	SendDocumentToChat(chatId int64, fileName string, content []byte, caption string) error
+}
+
+// Single bot command. Handler gets command arguments (split by whitespaces)
+// and returns the reply. Commands which reply with a file have fileHandler
+// instead.
+type command struct {
+	usage       string
+	handler     func(args []string) (string, error)
+	fileHandler func(args []string) (file, error)
+}
+
+// File sent as a reply to command.
+type file struct {
+	name    string
+	content []byte
+	caption string
+}
+
+// Returned when file is too large to be sent by the bot. File commands return
+// it based on stored file size, before the file is loaded from the database.
+type fileTooLargeError struct {
+	name string
+	size int64
+}
+
+func (e fileTooLargeError) Error() string {
+	return fmt.Sprintf("file [%s] is too large (%.2f MB) to be sent by the bot", e.name, float64(e.size)/1000000.0)
+}
+
+// Router handles bot commands (e.g. "/home") sent from authorized Telegram
+// chats and replies to the chat where the command came from. Commands from
+// other chats and other messages are ignored.
+type Router struct {
+	DbClient     *db.Client
+	Sender       ChatSender
+	AllowedChats map[int64]struct{}
+	commands     map[string]command
+}
+
+// NewRouter creates command router which accepts commands only from given
+// chats.
+func NewRouter(dbClient *db.Client, sender ChatSender, allowedChatIds []int64) *Router {
+	router := &Router{
+		DbClient:     dbClient,
+		Sender:       sender,
+		AllowedChats: make(map[int64]struct{}, len(allowedChatIds)),
+	}
+	for _, chatId := range allowedChatIds {
+		router.AllowedChats[chatId] = struct{}{}
+	}
+	router.commands = map[string]command{
+		"help":     {usage: "/help", handler: router.help},
+		"home":     {usage: "/home", handler: router.homeSummary},
+		"counters": {usage: "/counters [YYYY-MM-DD] cold=<liters> hot=<liters> kwh=<kWh>", handler: router.countersInsert},
+		"finance":  {usage: "/finance month [YYYY-MM]", handler: router.financeMonth},
+		"docs":     {usage: "/docs find <phrase>", handler: router.documentsFind},
+		"docfile":  {usage: "/docfile <document id>", fileHandler: router.documentFile},
+		"bookfile": {usage: "/bookfile <book id>", fileHandler: router.bookFile},
+	}
+	return router
+}
+
+// HandleUpdate handles Telegram update. It's meant to be subscribed to
+// telegram.Client updates.
+func (r *Router) HandleUpdate(update telegram.Update) {
+	message := update.Message
+	if message == nil {
+		message = update.ChannelPost
+	}
+	if message == nil || message.Chat == nil || !message.IsCommand() {
+		return
+	}
+	chatId := message.Chat.ID
+	name := message.Command()
+	if _, allowed := r.AllowedChats[chatId]; !allowed {
+		log.Warn().Int64("chatId", chatId).Str("command", name).
+			Msgf("[%s] command from unauthorized chat ignored", botPrefix)
+		return
+	}
+
+	// Commands read or write the database, so they shouldn't block receiving
+	// other updates
+	go r.reply(chatId, name, strings.Fields(message.CommandArguments()))
+}
+
+func (r *Router) reply(chatId int64, name string, args []string) {
+	startTs := time.Now()
+	log.Info().Int64("chatId", chatId).Str("command", name).Msgf("[%s] start handling command", botPrefix)
+
+	var reply string
+	cmd, exists := r.commands[name]
+	if !exists {
+		reply = fmt.Sprintf("Unknown command /%s. Try /help.", name)
+	} else if cmd.fileHandler != nil {
+		reply = r.replyWithFile(chatId, cmd, args)
+		if reply == "" {
+			log.Info().Str("command", name).Dur("duration", time.Since(startTs)).
+				Msgf("[%s] finished handling command", botPrefix)
+			return
+		}
+	} else {
+		response, cmdErr := cmd.handler(args)
+		if cmdErr != nil {
+			log.Warn().Err(cmdErr).Str("command", name).Msgf("[%s] command failed", botPrefix)
+			response = fmt.Sprintf("Error: %s\nUsage: %s", cmdErr.Error(), cmd.usage)
+		}
+		reply = response
+	}
+
+	if sendErr := r.Sender.SendMessageToChat(chatId, reply); sendErr != nil {
+		log.Error().Err(sendErr).Int64("chatId", chatId).Msgf("[%s] cannot send reply", botPrefix)
+		return
+	}
+	log.Info().Str("command", name).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished handling command", botPrefix)
+}
+
+// Sends file returned by the command. Returns text reply when the file
+// cannot be sent, otherwise empty string.
+func (r *Router) replyWithFile(chatId int64, cmd command, args []string) string {
+	f, cmdErr := cmd.fileHandler(args)
+	var tooLarge fileTooLargeError
+	if errors.As(cmdErr, &tooLarge) {
+		return fmt.Sprintf("Error: %s.", tooLarge.Error())
+	}
+	if cmdErr != nil {
+		log.Warn().Err(cmdErr).Str("usage", cmd.usage).Msgf("[%s] command failed", botPrefix)
+		return fmt.Sprintf("Error: %s\nUsage: %s", cmdErr.Error(), cmd.usage)
+	}
+	sendErr := r.Sender.SendDocumentToChat(chatId, f.name, f.content, f.caption)
+	if errors.Is(sendErr, telegram.ErrFileTooLarge) {
+		return fmt.Sprintf("Error: %s.", fileTooLargeError{name: f.name, size: int64(len(f.content))}.Error())
+	}
+	if sendErr != nil {
+		log.Error().Err(sendErr).Int64("chatId", chatId).Msgf("[%s] cannot send file", botPrefix)
+		return fmt.Sprintf("Error: could not send file [%s].", f.name)
+	}
+	return ""
+}
+
+func (r *Router) help(_ []string) (string, error) {
+	usages := make([]string, 0, len(r.commands))
+	for _, cmd := range r.commands {
+		usages = append(usages, cmd.usage)
+	}
+	sort.Strings(usages)
+	return "Available commands:\n" + strings.Join(usages, "\n"), nil
+}
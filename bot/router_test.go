@@ -0,0 +1,211 @@
+package bot
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"homeApp/auth/telegram"
+	"homeApp/auth/telegram/telegramtest"
+	"homeApp/db"
+)
+
+type fakeSender struct {
+	sync.Mutex
+	messages map[int64][]string
+	sent     chan struct{}
+}
+
+func (fs *fakeSender) SendMessageToChat(chatId int64, text string) error {
+	fs.Lock()
+	fs.messages[chatId] = append(fs.messages[chatId], text)
+	fs.Unlock()
+	fs.sent <- struct{}{}
+	return nil
+}
+
+func (fs *fakeSender) SendDocumentToChat(chatId int64, fileName string, _ []byte, _ string) error {
+	return fs.SendMessageToChat(chatId, "file: "+fileName)
+}
+
+func commandUpdate(chatId int64, text string) telegram.Update {
+	name, _, _ := strings.Cut(text, " ")
+	return telegram.Update{
+		Message: &telegram.Message{
+			Chat:     &telegram.Chat{ID: chatId},
+			Text:     text,
+			Entities: []telegram.MessageEntity{{Type: "bot_command", Offset: 0, Length: len(name)}},
+		},
+	}
+}
+
+func TestRouterAuthorizesChats(t *testing.T) {
+	const allowedChat, otherChat = int64(100), int64(200)
+	sender := &fakeSender{messages: make(map[int64][]string), sent: make(chan struct{}, 10)}
+	router := NewRouter(nil, sender, []int64{allowedChat})
+
+	router.HandleUpdate(commandUpdate(otherChat, "/help"))
+	router.HandleUpdate(telegram.Update{Message: &telegram.Message{Chat: &telegram.Chat{ID: allowedChat}, Text: "hi"}})
+	router.HandleUpdate(commandUpdate(allowedChat, "/help"))
+	router.HandleUpdate(commandUpdate(allowedChat, "/unknown"))
+	router.HandleUpdate(commandUpdate(allowedChat, "/docfile x"))
+
+	for i := 0; i < 3; i++ {
+		select {
+		case <-sender.sent:
+		case <-time.After(time.Second):
+			t.Fatal("expected reply to commands from allowed chat")
+		}
+	}
+
+	sender.Lock()
+	defer sender.Unlock()
+	if len(sender.messages[otherChat]) != 0 {
+		t.Errorf("expected no reply to unauthorized chat, got %v", sender.messages[otherChat])
+	}
+	replies := strings.Join(sender.messages[allowedChat], "\n")
+	if !strings.Contains(replies, "/counters") || !strings.Contains(replies, "Unknown command /unknown") {
+		t.Errorf("expected help and unknown command replies, got %q", replies)
+	}
+	if !strings.Contains(replies, "incorrect id [x]") || strings.Contains(replies, "file: ") {
+		t.Errorf("expected error reply instead of file, got %q", replies)
+	}
+}
+
+// Creates database with the schema from sql/schema.sql in temporary directory.
+func newTestDb(t *testing.T) *db.Client {
+	t.Helper()
+	schema, rErr := os.ReadFile(filepath.Join("..", "sql", "schema.sql"))
+	if rErr != nil {
+		t.Fatal(rErr)
+	}
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	conn, oErr := sql.Open("sqlite", dbPath)
+	if oErr != nil {
+		t.Fatal(oErr)
+	}
+	defer conn.Close()
+	if _, eErr := conn.Exec(string(schema)); eErr != nil {
+		t.Fatalf("cannot create schema: %v", eErr)
+	}
+	dbClient, cErr := db.NewClient(dbPath)
+	if cErr != nil {
+		t.Fatal(cErr)
+	}
+	return dbClient
+}
+
+func TestRouterWithFakeBotApi(t *testing.T) {
+	const allowedChat, otherChat = int64(-100123), int64(200)
+	server := telegramtest.NewServer("123:test")
+	defer server.Close()
+	dbClient := newTestDb(t)
+	newDoc := db.NewDocument{Name: "Umowa", Category: "inne", FileExtension: "pdf", DocumentFile: []byte("%PDF-1.4")}
+	if iErr := dbClient.DocumentInsertNew(newDoc, nil); iErr != nil {
+		t.Fatal(iErr)
+	}
+
+	client := telegram.NewClient(server.Client(), server.URL, server.Token, strconv.FormatInt(allowedChat, 10))
+	router := NewRouter(dbClient, client, []int64{allowedChat})
+	client.Subscribe(router.HandleUpdate)
+	server.AddUpdates(commandUpdate(otherChat, "/help"), commandUpdate(allowedChat, "/help"),
+		commandUpdate(allowedChat, "/docfile 1"))
+	client.StartPolling()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(server.Messages()) == 0 || len(server.Documents()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected reply and file, got %v and %v", server.Messages(), server.Documents())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	messages := server.Messages()
+	if len(messages) != 1 || messages[0].ChatId != "-100123" || !strings.Contains(messages[0].Text, "/counters") {
+		t.Errorf("expected single help reply to allowed chat, got %+v", messages)
+	}
+	documents := server.Documents()
+	if len(documents) != 1 || documents[0].ChatId != "-100123" || documents[0].FileName != "Umowa.pdf" ||
+		string(documents[0].Content) != "%PDF-1.4" || documents[0].Caption != "Umowa" {
+		t.Errorf("unexpected sent document: %+v", documents)
+	}
+}
+
+func TestParseCountersArgs(t *testing.T) {
+	now := time.Date(2024, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	water, energy, pErr := parseCountersArgs([]string{"2024-05-01", "cold=1200", "hot=800", "kwh=41.5"}, now)
+	if pErr != nil {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+	if water.Date != "2024-05-01" || water.ColdWaterLiters != 1200 || water.HotWaterLiters != 800 {
+		t.Errorf("unexpected water entry: %+v", water)
+	}
+	if energy.Date != "2024-05-01" || energy.EnergyKwh != 41.5 {
+		t.Errorf("unexpected energy entry: %+v", energy)
+	}
+
+	water, _, pErr = parseCountersArgs([]string{"kwh=1", "hot=2", "cold=3"}, now)
+	if pErr != nil || water.Date != "2024-05-10" {
+		t.Errorf("expected today's date by default, got %+v, %v", water, pErr)
+	}
+
+	failing := [][]string{
+		{"cold=1", "hot=2"},
+		{"2024-13-01", "cold=1", "hot=2", "kwh=3"},
+		{"cold=1", "2024-05-01", "hot=2", "kwh=3"},
+		{"cold=x", "hot=2", "kwh=3"},
+		{"cold=-1", "hot=2", "kwh=3"},
+		{"cold=1", "hot=2", "kwh=3", "gas=4"},
+	}
+	for _, args := range failing {
+		if _, _, err := parseCountersArgs(args, now); err == nil {
+			t.Errorf("expected error for %v", args)
+		}
+	}
+	if _, _, err := parseCountersArgs([]string{"cold=1"}, now); !errors.Is(err, ErrMissingArgument) {
+		t.Errorf("expected ErrMissingArgument, got %v", err)
+	}
+}
+
+func TestParseIdArg(t *testing.T) {
+	if id, pErr := parseIdArg([]string{"12"}); pErr != nil || id != 12 {
+		t.Errorf("expected id 12, got %d, %v", id, pErr)
+	}
+	if _, err := parseIdArg(nil); !errors.Is(err, ErrMissingArgument) {
+		t.Errorf("expected ErrMissingArgument, got %v", err)
+	}
+	if _, err := parseIdArg([]string{"1", "2"}); !errors.Is(err, ErrUnexpectedArgument) {
+		t.Errorf("expected ErrUnexpectedArgument, got %v", err)
+	}
+	for _, arg := range []string{"0", "-3", "abc"} {
+		if _, err := parseIdArg([]string{arg}); err == nil {
+			t.Errorf("expected error for %s", arg)
+		}
+	}
+}
+
+func TestParseMonthArg(t *testing.T) {
+	now := time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+	month, pErr := parseMonthArg(nil, now)
+	if pErr != nil || month.Format(monthLayout) != "2023-12" {
+		t.Errorf("expected previous month by default, got %v, %v", month, pErr)
+	}
+	month, pErr = parseMonthArg([]string{"2024-04"}, now)
+	if pErr != nil || month.Format(monthLayout) != "2024-04" {
+		t.Errorf("expected 2024-04, got %v, %v", month, pErr)
+	}
+	if _, err := parseMonthArg([]string{"04-2024"}, now); err == nil {
+		t.Error("expected error for incorrect month")
+	}
+	if _, err := parseMonthArg([]string{"2024-04", "x"}, now); !errors.Is(err, ErrUnexpectedArgument) {
+		t.Errorf("expected ErrUnexpectedArgument, got %v", err)
+	}
+}
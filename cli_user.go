@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/rand"
+
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	cliPasswordLength = 16
+	userCmdUsage      = `Usage: homeApp user <add|disable|enable|reset-password|grant|set-admin|list> [flags]
+
+Password for "add" and "reset-password" is read from standard input. When
+"reset-password" gets empty input, random password is generated and printed.
+`
+)
+
+// Runs "user" subcommand for users administration without running the
+// server. Returns process exit code.
+func runUserCmd(args []string) int {
+	zerolog.SetGlobalLevel(zerolog.WarnLevel)
+	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr})
+
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, userCmdUsage)
+		return 2
+	}
+	subcommand := args[0]
+
+	fs := flag.NewFlagSet("user "+subcommand, flag.ContinueOnError)
+	dbPath := fs.String("dbPath", "test.db", "Path to SQLite Home DB")
+	username := fs.String("username", "", "Username")
+	email := fs.String("email", "", "Email of the new user (add)")
+	isAdmin := fs.Bool("admin", false, "Admin role of the user (add, set-admin)")
+	module := fs.String("module", "", "Module: counters, finance, documents or books (grant)")
+	access := fs.String("access", auth.AccessRead, "Access level to the module: none, read or write (grant)")
+	if pErr := fs.Parse(args[1:]); pErr != nil {
+		return 2
+	}
+
+	dbClient, dbErr := db.NewClient(fmt.Sprintf("file:%s?cache=shared&mode=rw", *dbPath))
+	if dbErr != nil {
+		fmt.Fprintf(os.Stderr, "cannot connect to database: %v\n", dbErr)
+		return 1
+	}
+	if mErr := dbClient.Migrate(schemaSql); mErr != nil {
+		fmt.Fprintf(os.Stderr, "cannot migrate database schema: %v\n", mErr)
+		return 1
+	}
+	userAdmin := auth.UserAdmin{DbClient: dbClient}
+
+	if subcommand != "list" && *username == "" {
+		fmt.Fprintln(os.Stderr, "-username is required")
+		return 2
+	}
+
+	var cmdErr error
+	switch subcommand {
+	case "list":
+		cmdErr = listUsers(userAdmin)
+	case "add":
+		password := readPassword()
+		_, cmdErr = userAdmin.AddUser(*username, *email, password, *isAdmin)
+	case "disable":
+		cmdErr = userAdmin.SetActive(*username, false)
+	case "enable":
+		cmdErr = userAdmin.SetActive(*username, true)
+	case "reset-password":
+		password := readPassword()
+		if password == "" {
+			password = rand.AlphanumStr(cliPasswordLength)
+			fmt.Printf("New password: %s\n", password)
+		}
+		cmdErr = userAdmin.ResetPassword(*username, password)
+	case "grant":
+		cmdErr = userAdmin.SetPermission(*username, *module, *access)
+	case "set-admin":
+		cmdErr = userAdmin.SetAdmin(*username, *isAdmin)
+	default:
+		fmt.Fprint(os.Stderr, userCmdUsage)
+		return 2
+	}
+
+	if cmdErr != nil {
+		fmt.Fprintf(os.Stderr, "user %s failed: %v\n", subcommand, cmdErr)
+		return 1
+	}
+	return 0
+}
+
+func listUsers(userAdmin auth.UserAdmin) error {
+	users, uErr := userAdmin.Users()
+	if uErr != nil {
+		return uErr
+	}
+	fmt.Printf("%-6s %-20s %-30s %-7s %-6s %-11s %s\n", "ID", "USERNAME", "EMAIL", "ACTIVE", "ADMIN", "CREATED",
+		"PERMISSIONS")
+	for _, u := range users {
+		permissions := make([]string, 0, len(u.Access))
+		for _, module := range auth.Modules {
+			if access, exists := u.Access[module]; exists {
+				permissions = append(permissions, module+":"+access)
+			}
+		}
+		fmt.Printf("%-6d %-20s %-30s %-7t %-6t %-11s %s\n", u.UserId, u.Username, u.Email, u.IsActive, u.IsAdmin,
+			u.CreateDate, strings.Join(permissions, ","))
+	}
+	return nil
+}
+
+// Reads single line from standard input.
+func readPassword() string {
+	fmt.Fprint(os.Stderr, "Password: ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	return strings.TrimRight(line, "\r\n")
+}
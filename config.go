@@ -2,11 +2,18 @@ package main
 
 import (
 	_ "embed"
+	"encoding/base64"
 	"flag"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
+	"homeApp/auth/telegram"
+	"homeApp/digest"
+	"homeApp/notify"
+
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 )
@@ -15,6 +22,11 @@ const (
 	SessionTimeoutMinutes = 10
 	TelegramBotTokenEnv   = "HOMEAPP_TELEGRAM_BOT_TOKEN"
 	TelegramChannelIdEnv  = "HOMEAPP_TELEGRAM_CHANNEL_ID"
+	SecretsKeyEnv         = "HOMEAPP_SECRETS_KEY"
+	SmtpUsernameEnv       = "HOMEAPP_SMTP_USERNAME"
+	SmtpPasswordEnv       = "HOMEAPP_SMTP_PASSWORD"
+	NtfyTokenEnv          = "HOMEAPP_NTFY_TOKEN"
+	GotifyTokenEnv        = "HOMEAPP_GOTIFY_TOKEN"
 )
 
 //go:generate sh -c "head -1 CHANGELOG.md > VERSION.txt"
@@ -22,22 +34,75 @@ const (
 //go:embed VERSION.txt
 var versionFile string
 
+//go:embed sql/schema.sql
+var schemaSql string
+
 type Config struct {
 	Port                  int
 	DatabasePath          string
-	UseTelegram2FA        bool
+	UseTelegram           bool
 	Telegram              *TelegramConfig
+	Default2FAMethod      string
+	SecretsKey            []byte
 	SessionTimeoutMinutes int
 	HttpClientTimeout     time.Duration
 	Logger                LoggerConfig
 	AppVersion            string
 	CurrentCommitSHA      string
 	PublishViewsAfter     time.Duration
+	JwtKeyRotation        time.Duration
+	JwtKeyGracePeriod     time.Duration
+	LoginMaxFailures      int
+	LoginLockout          time.Duration
+	SecureCookies         bool
+	LoginAlertNewIp       bool
+	Tls                   *TlsConfig
+	Notify                NotifyConfig
+	Digest                DigestConfig
+}
+
+// TlsConfig is set when HomeApp serves HTTPS itself.
+type TlsConfig struct {
+	CertPath     string
+	KeyPath      string
+	RedirectPort int           // Plain HTTP port redirecting to HTTPS, 0 when disabled
+	HstsMaxAge   time.Duration // 0 when HSTS is disabled
 }
 
 type TelegramConfig struct {
-	BotToken  string
-	ChannelId string
+	ApiUrl      string
+	BotToken    string
+	ChannelId   string
+	BotCommands bool    // Handle bot commands, Telegram updates are polled in the background
+	BotChatIds  []int64 // Chats from which bot commands are accepted
+	WebhookUrl  string  // Updates are received by webhook instead of polling when set
+	WebhookPath string  // Path of WebhookUrl on which webhook handler is registered
+}
+
+// NotifyConfig configures notification channels. Channel is enabled when its
+// URL (address) is set. Rules map event types to channel names.
+type NotifyConfig struct {
+	Rules        map[string][]string
+	SmtpAddr     string
+	SmtpFrom     string
+	SmtpTo       []string
+	SmtpUsername string
+	SmtpPassword string
+	WebhookUrl   string
+	NtfyUrl      string
+	NtfyToken    string
+	GotifyUrl    string
+	GotifyToken  string
+
+	OutboxMaxAttempts int // Delivery attempts before message is dead-lettered
+}
+
+// DigestConfig configures scheduled digests. Digests list is empty when none
+// is scheduled.
+type DigestConfig struct {
+	Digests      []digest.Digest
+	TemplatesDir string
+	ExpiryDays   int
 }
 
 type LoggerConfig struct {
@@ -47,11 +112,60 @@ type LoggerConfig struct {
 
 // Parse or fail.
 func ParseConfigFlags() Config {
-	telegram2fa := flag.Bool("telegram2fa", false, "Use Telegram for two-factor authentication")
+	useTelegram := flag.Bool("telegram", false, "Use Telegram channel for 2FA and notifications")
+	telegramApiUrl := flag.String("telegramApiUrl", telegram.DefaultBaseUrl,
+		"Base URL of Telegram Bot API, e.g. of self-hosted Bot API server")
+	telegramBot := flag.Bool("telegramBot", false, "Handle Telegram bot commands (e.g. /home). Requires -telegram")
+	telegramWebhookUrl := flag.String("telegramWebhookUrl", "",
+		"Public HTTPS URL of HomeApp Telegram webhook (e.g. https://example.com/telegram/webhook). When set, Telegram updates are received by webhook instead of polling")
+	telegramBotChats := flag.String("telegramBotChats", "",
+		"Comma separated IDs of Telegram chats from which bot commands are accepted. Telegram channel by default")
+	telegram2fa := flag.Bool("telegram2fa", false,
+		"Deprecated: equivalent of '-telegram -default2fa telegram'")
+	default2fa := flag.String("default2fa", "none",
+		"2FA method for users who haven't chosen one. Either 'none' or 'telegram'")
 	dbPath := flag.String("dbPath", "test.db", "Path to SQLite Home DB")
 	port := flag.Int("port", 8080, "Port on which HomeApp is listening")
 	publishViewsAfter := flag.Int("publishViewsAfter", 300,
 		"After each 'x' minutes endpoints views statistics will be published")
+	jwtKeyRotationHours := flag.Int("jwtKeyRotationHours", 168,
+		"After each 'x' hours new JWT signing key is generated")
+	jwtKeyGraceMinutes := flag.Int("jwtKeyGraceMinutes", 60,
+		"Tokens signed by previous JWT signing key are accepted for 'x' minutes after key rotation")
+	loginMaxFailures := flag.Int("loginMaxFailures", 10,
+		"Login is locked after 'x' failed attempts for username (per IP address 3 times more)")
+	loginLockoutMinutes := flag.Int("loginLockoutMinutes", 15,
+		"Login lockout lasts 'x' minutes")
+	loginAlertNewIp := flag.Bool("loginAlertNewIp", false,
+		"Send alert when user logs in from IP address which wasn't used for login before")
+	secureCookies := flag.Bool("secureCookies", false,
+		"Mark cookies as Secure (sent only over HTTPS). Use when HomeApp is served over HTTPS. Implied by -tlsCert")
+	tlsCert := flag.String("tlsCert", "", "Path to TLS certificate (PEM). When set, HomeApp serves HTTPS")
+	tlsKey := flag.String("tlsKey", "", "Path to TLS private key (PEM) of -tlsCert certificate")
+	httpRedirectPort := flag.Int("httpRedirectPort", 0,
+		"Port on which plain HTTP requests are redirected to HTTPS. Requires -tlsCert. 0 disables redirect")
+	hstsMaxAgeDays := flag.Int("hstsMaxAgeDays", 180,
+		"Strict-Transport-Security max-age in days sent over HTTPS. 0 disables HSTS")
+
+	notifyRules := flag.String("notifyRules", "",
+		"Notification routing rules, e.g. 'upload=telegram,email;security=ntfy;*=log'. All notifications are sent over Telegram (if enabled) by default")
+	smtpAddr := flag.String("smtpAddr", "", "SMTP server (host:port) for email notifications. Enables 'email' channel")
+	smtpFrom := flag.String("smtpFrom", "", "Sender address of email notifications")
+	smtpTo := flag.String("smtpTo", "", "Comma separated recipients of email notifications")
+	notifyWebhookUrl := flag.String("notifyWebhookUrl", "",
+		"URL to which notifications are posted as JSON. Enables 'webhook' channel")
+	ntfyUrl := flag.String("ntfyUrl", "", "ntfy topic URL, e.g. https://ntfy.sh/homeapp. Enables 'ntfy' channel")
+	gotifyUrl := flag.String("gotifyUrl", "", "Gotify server URL. Enables 'gotify' channel")
+	outboxMaxAttempts := flag.Int("outboxMaxAttempts", 10,
+		"Number of notification delivery attempts (with exponential backoff) before it's marked as dead")
+	digestWeekly := flag.String("digestWeekly", "",
+		"Cron-like schedule of weekly digest, e.g. '0 8 * * 1' (Mondays at 8:00). Disabled when empty")
+	digestMonthly := flag.String("digestMonthly", "",
+		"Cron-like schedule of monthly digest, e.g. '0 8 1 * *'. Disabled when empty")
+	digestTemplates := flag.String("digestTemplates", "",
+		"Directory with digest templates (weekly.tmpl, monthly.tmpl, common.tmpl) overriding default ones")
+	digestExpiryDays := flag.Int("digestExpiryDays", 30,
+		"Documents expiring within this many days are listed in digests")
 
 	logDebugLevel := flag.Bool("logDebug", true,
 		"Log events on at least debug level. Otherwise info level is assumed.")
@@ -62,20 +176,129 @@ func ParseConfigFlags() Config {
 	var telegramConfig *TelegramConfig
 
 	if *telegram2fa {
+		log.Warn().Msg("[config] -telegram2fa is deprecated, use '-telegram -default2fa telegram' instead")
+		*useTelegram = true
+		*default2fa = "telegram"
+	}
+
+	if *useTelegram {
 		telegramBotToken := os.Getenv(TelegramBotTokenEnv)
 		telegramChannelId := os.Getenv(TelegramChannelIdEnv)
 		if telegramBotToken == "" {
-			log.Fatal().Msgf("[config] Telegram 2FA is on, %s env variable should be set", TelegramBotTokenEnv)
+			log.Fatal().Msgf("[config] Telegram is on, %s env variable should be set", TelegramBotTokenEnv)
 		}
 		if telegramChannelId == "" {
-			log.Fatal().Msgf("[config] Telegram 2FA is on, %s env variable should be set", TelegramChannelIdEnv)
+			log.Fatal().Msgf("[config] Telegram is on, %s env variable should be set", TelegramChannelIdEnv)
 		}
 		telegramConfig = &TelegramConfig{
-			BotToken:  telegramBotToken,
-			ChannelId: telegramChannelId,
+			ApiUrl:      *telegramApiUrl,
+			BotToken:    telegramBotToken,
+			ChannelId:   telegramChannelId,
+			BotCommands: *telegramBot,
+		}
+		if *telegramBot {
+			telegramConfig.BotChatIds = parseChatIds(*telegramBotChats, telegramChannelId)
+		}
+		if *telegramWebhookUrl != "" {
+			webhookUrl, uErr := url.Parse(*telegramWebhookUrl)
+			if uErr != nil || webhookUrl.Scheme != "https" || webhookUrl.Path == "" || webhookUrl.Path == "/" {
+				log.Fatal().Msgf("[config] Telegram webhook URL should be HTTPS URL with path, got: %s",
+					*telegramWebhookUrl)
+			}
+			telegramConfig.WebhookUrl = *telegramWebhookUrl
+			telegramConfig.WebhookPath = webhookUrl.Path
+		}
+	} else if *telegramBot || *telegramWebhookUrl != "" {
+		log.Fatal().Msg("[config] -telegramBot and -telegramWebhookUrl require -telegram flag")
+	}
+
+	var tlsConfig *TlsConfig
+	if (*tlsCert == "") != (*tlsKey == "") {
+		log.Fatal().Msg("[config] -tlsCert and -tlsKey should be set together")
+	}
+	if *tlsCert != "" {
+		if *httpRedirectPort < 0 || *httpRedirectPort == *port {
+			log.Fatal().Msgf("[config] incorrect HTTP redirect port: %d", *httpRedirectPort)
+		}
+		if *hstsMaxAgeDays < 0 {
+			log.Fatal().Msg("[config] HSTS max age should not be negative")
+		}
+		tlsConfig = &TlsConfig{
+			CertPath:     *tlsCert,
+			KeyPath:      *tlsKey,
+			RedirectPort: *httpRedirectPort,
+			HstsMaxAge:   time.Duration(*hstsMaxAgeDays) * 24 * time.Hour,
+		}
+		*secureCookies = true
+	} else if *httpRedirectPort != 0 {
+		log.Fatal().Msg("[config] -httpRedirectPort requires -tlsCert and -tlsKey")
+	}
+
+	if *loginMaxFailures < 1 || *loginLockoutMinutes < 1 {
+		log.Fatal().Msg("[config] login max failures and lockout minutes should be positive")
+	}
+
+	switch *default2fa {
+	case "none":
+	case "telegram":
+		if !*useTelegram {
+			log.Fatal().Msg("[config] default 2FA method 'telegram' requires -telegram flag")
+		}
+	default:
+		log.Fatal().Msgf("[config] unsupported default 2FA method: %s", *default2fa)
+	}
+
+	var secretsKey []byte
+	if secretsKeyB64 := os.Getenv(SecretsKeyEnv); secretsKeyB64 != "" {
+		key, decErr := base64.StdEncoding.DecodeString(secretsKeyB64)
+		if decErr != nil || len(key) != 32 {
+			log.Fatal().Msgf("[config] %s env variable should contain base64 encoded 32 bytes key", SecretsKeyEnv)
+		}
+		secretsKey = key
+	} else {
+		log.Warn().Msgf("[config] %s env variable is not set - TOTP 2FA is not available", SecretsKeyEnv)
+	}
+
+	if *jwtKeyGraceMinutes < SessionTimeoutMinutes {
+		log.Fatal().Msgf("[config] JWT signing key grace period (%d minutes) should not be shorter than session timeout (%d minutes)",
+			*jwtKeyGraceMinutes, SessionTimeoutMinutes)
+	}
+
+	notifyConfig := parseNotifyConfig(*notifyRules, *useTelegram)
+	notifyConfig.WebhookUrl = *notifyWebhookUrl
+	notifyConfig.NtfyUrl = *ntfyUrl
+	notifyConfig.NtfyToken = os.Getenv(NtfyTokenEnv)
+	notifyConfig.GotifyUrl = *gotifyUrl
+	notifyConfig.GotifyToken = os.Getenv(GotifyTokenEnv)
+	if *outboxMaxAttempts < 1 {
+		log.Fatal().Msg("[config] -outboxMaxAttempts should be positive")
+	}
+	notifyConfig.OutboxMaxAttempts = *outboxMaxAttempts
+	if *gotifyUrl != "" && notifyConfig.GotifyToken == "" {
+		log.Fatal().Msgf("[config] Gotify is on, %s env variable should be set", GotifyTokenEnv)
+	}
+	if *smtpAddr != "" {
+		if *smtpFrom == "" || strings.TrimSpace(*smtpTo) == "" {
+			log.Fatal().Msg("[config] -smtpAddr requires -smtpFrom and -smtpTo")
 		}
+		notifyConfig.SmtpAddr = *smtpAddr
+		notifyConfig.SmtpFrom = *smtpFrom
+		for _, to := range strings.Split(*smtpTo, ",") {
+			if to = strings.TrimSpace(to); to != "" {
+				notifyConfig.SmtpTo = append(notifyConfig.SmtpTo, to)
+			}
+		}
+		notifyConfig.SmtpUsername = os.Getenv(SmtpUsernameEnv)
+		notifyConfig.SmtpPassword = os.Getenv(SmtpPasswordEnv)
 	}
 
+	digestConfig := parseDigestConfig(map[string]string{digest.Weekly: *digestWeekly, digest.Monthly: *digestMonthly})
+	digestConfig.TemplatesDir = *digestTemplates
+	if *digestExpiryDays < 0 {
+		log.Fatal().Msg("[config] -digestExpiryDays cannot be negative")
+	}
+	digestConfig.ExpiryDays = *digestExpiryDays
+
 	loggerConfig := LoggerConfig{
 		UseDebugLevel:    *logDebugLevel,
 		UseConsoleWriter: *logUseConsoleWriter,
@@ -84,16 +307,27 @@ func ParseConfigFlags() Config {
 	appVersion, commitSha := parseVersions(versionFile)
 
 	return Config{
-		Port:           *port,
-		DatabasePath:   *dbPath,
-		UseTelegram2FA: *telegram2fa,
-		Telegram:       telegramConfig,
+		Port:             *port,
+		DatabasePath:     *dbPath,
+		UseTelegram:      *useTelegram,
+		Telegram:         telegramConfig,
+		Default2FAMethod: *default2fa,
+		SecretsKey:       secretsKey,
 
 		SessionTimeoutMinutes: SessionTimeoutMinutes,
 		HttpClientTimeout:     60 * time.Second,
 		Logger:                loggerConfig,
 
 		PublishViewsAfter: time.Duration(*publishViewsAfter) * time.Minute,
+		JwtKeyRotation:    time.Duration(*jwtKeyRotationHours) * time.Hour,
+		JwtKeyGracePeriod: time.Duration(*jwtKeyGraceMinutes) * time.Minute,
+		LoginMaxFailures:  *loginMaxFailures,
+		LoginLockout:      time.Duration(*loginLockoutMinutes) * time.Minute,
+		SecureCookies:     *secureCookies,
+		LoginAlertNewIp:   *loginAlertNewIp,
+		Tls:               tlsConfig,
+		Notify:            notifyConfig,
+		Digest:            digestConfig,
 
 		AppVersion:       appVersion,
 		CurrentCommitSHA: commitSha,
@@ -113,6 +347,56 @@ func (c *Config) setupZerolog() {
 	}
 }
 
+// Parses notification routing rules. By default all notifications are sent
+// over Telegram, when it's enabled. Otherwise they're only logged.
+func parseNotifyConfig(rulesSpec string, useTelegram bool) NotifyConfig {
+	if rulesSpec == "" {
+		rulesSpec = notify.AnyEvent + "=log"
+		if useTelegram {
+			rulesSpec = notify.AnyEvent + "=telegram"
+		}
+	}
+	rules, rErr := notify.ParseRules(rulesSpec)
+	if rErr != nil {
+		log.Fatal().Err(rErr).Msg("[config] incorrect notification rules")
+	}
+	return NotifyConfig{Rules: rules}
+}
+
+// Parses digest schedules by digest kind. Digests with empty schedule are
+// disabled.
+func parseDigestConfig(schedules map[string]string) DigestConfig {
+	var config DigestConfig
+	for _, kind := range digest.Kinds {
+		if schedules[kind] == "" {
+			continue
+		}
+		schedule, pErr := digest.ParseSchedule(schedules[kind])
+		if pErr != nil {
+			log.Fatal().Err(pErr).Msgf("[config] incorrect %s digest schedule", kind)
+		}
+		config.Digests = append(config.Digests, digest.Digest{Kind: kind, Schedule: schedule})
+	}
+	return config
+}
+
+// Parses comma separated chat IDs. Given default chat is used when the list is
+// empty.
+func parseChatIds(chatIds, defaultChatId string) []int64 {
+	if strings.TrimSpace(chatIds) == "" {
+		chatIds = defaultChatId
+	}
+	ids := make([]int64, 0)
+	for _, chatId := range strings.Split(chatIds, ",") {
+		id, pErr := strconv.ParseInt(strings.TrimSpace(chatId), 10, 64)
+		if pErr != nil {
+			log.Fatal().Msgf("[config] incorrect Telegram chat ID: %s", chatId)
+		}
+		ids = append(ids, id)
+	}
+	return ids
+}
+
 func parseVersions(input string) (string, string) {
 	const firstSHAChars = 8
 
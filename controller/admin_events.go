@@ -0,0 +1,89 @@
+package controller
+
+import (
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/front"
+	"net/http"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	contrAdminEventsPrefix = "controller/adminEvents"
+	authEventsLimit        = 200
+	filterDateLayout       = "2006-01-02"
+)
+
+type AdminEvents struct {
+	Audit *auth.AuthAudit
+}
+
+type AdminEventsPage struct {
+	Events  []db.AuthEvent
+	Filter  AuthEventsFilterForm
+	Types   []string
+	Results []string
+	Limit   int
+	Error   *string
+}
+
+// AuthEventsFilterForm holds values of events filter form.
+type AuthEventsFilterForm struct {
+	Username  string
+	EventType string
+	Result    string
+	IpAddress string
+	FromDate  string
+	ToDate    string
+}
+
+// EventsView renders the latest authentication events matching filters given
+// in query parameters.
+func (ae *AdminEvents) EventsView(w http.ResponseWriter, r *http.Request) {
+	tmpl := front.AdminEvents(commonFromRequest(r))
+	query := r.URL.Query()
+	form := AuthEventsFilterForm{
+		Username:  query.Get("username"),
+		EventType: query.Get("type"),
+		Result:    query.Get("result"),
+		IpAddress: query.Get("ip"),
+		FromDate:  query.Get("from"),
+		ToDate:    query.Get("to"),
+	}
+	page := AdminEventsPage{
+		Filter:  form,
+		Types:   auth.EventTypes,
+		Results: auth.EventResults,
+		Limit:   authEventsLimit,
+	}
+
+	filter := db.AuthEventsFilter{
+		Username:  form.Username,
+		EventType: form.EventType,
+		Result:    form.Result,
+		IpAddress: form.IpAddress,
+		Limit:     authEventsLimit,
+	}
+	if fromDate, pErr := time.Parse(filterDateLayout, form.FromDate); pErr == nil {
+		filter.FromTs = fromDate.Format(db.TimestampFormat)
+	}
+	if toDate, pErr := time.Parse(filterDateLayout, form.ToDate); pErr == nil {
+		filter.ToTs = toDate.Add(24*time.Hour - time.Second).Format(db.TimestampFormat)
+	}
+
+	events, eErr := ae.Audit.Events(filter)
+	if eErr != nil {
+		log.Error().Err(eErr).Msgf("[%s] cannot load auth events", contrAdminEventsPrefix)
+		displayError := "could not read authentication events from database"
+		page.Error = &displayError
+	}
+	page.Events = events
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render auth events view", contrAdminEventsPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
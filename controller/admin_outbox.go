@@ -0,0 +1,93 @@
+package controller
+
+import (
+	"homeApp/db"
+	"homeApp/front"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	contrAdminOutboxPrefix = "controller/adminOutbox"
+	outboxMessagesLimit    = 200
+)
+
+type AdminOutbox struct {
+	DbClient *db.Client
+}
+
+type AdminOutboxPage struct {
+	Messages []db.OutboxMessage
+	Status   string
+	Statuses []string
+	Limit    int
+	Error    *string
+	Info     *string
+}
+
+// OutboxView renders the latest notifications from the outbox, optionally
+// filtered by status given in query parameter.
+func (ao *AdminOutbox) OutboxView(w http.ResponseWriter, r *http.Request) {
+	ao.render(w, r, nil, nil)
+}
+
+// RequeueHandler moves dead notification back to pending ones, so the outbox
+// worker retries its delivery.
+func (ao *AdminOutbox) RequeueHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin/outbox", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	messageId, pErr := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if pErr != nil {
+		displayError := "incorrect message ID"
+		ao.render(w, r, nil, &displayError)
+		return
+	}
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	requeued, rErr := ao.DbClient.OutboxRequeue(messageId, nowTs)
+	if rErr != nil {
+		displayError := "cannot requeue notification"
+		ao.render(w, r, nil, &displayError)
+		return
+	}
+	if !requeued {
+		displayError := "there is no dead notification with given ID"
+		ao.render(w, r, nil, &displayError)
+		return
+	}
+	log.Info().Int64("messageId", messageId).Msgf("[%s] notification requeued", contrAdminOutboxPrefix)
+	info := "Notification requeued, it will be delivered shortly"
+	ao.render(w, r, &info, nil)
+}
+
+func (ao *AdminOutbox) render(w http.ResponseWriter, r *http.Request, info, displayError *string) {
+	tmpl := front.AdminOutbox(commonFromRequest(r))
+	status := r.URL.Query().Get("status")
+	page := AdminOutboxPage{
+		Status:   status,
+		Statuses: db.OutboxStatuses,
+		Limit:    outboxMessagesLimit,
+		Error:    displayError,
+		Info:     info,
+	}
+
+	messages, mErr := ao.DbClient.OutboxMessages(status, outboxMessagesLimit)
+	if mErr != nil {
+		log.Error().Err(mErr).Msgf("[%s] cannot load outbox messages", contrAdminOutboxPrefix)
+		loadError := "could not read notifications from database"
+		page.Error = &loadError
+	}
+	page.Messages = messages
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render outbox view", contrAdminOutboxPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,170 @@
+package controller
+
+import (
+	"fmt"
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/front"
+	"homeApp/rand"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	contrAdminUsersPrefix = "controller/adminUsers"
+	resetPasswordLength   = 16
+)
+
+type AdminUsers struct {
+	UserAdmin auth.UserAdmin
+	DbClient  *db.Client
+}
+
+type AdminUsersPage struct {
+	Users   []auth.UserSummary
+	Modules []string
+	Message *string
+	Error   *string
+}
+
+// UsersView renders list of all users with administration actions.
+func (au *AdminUsers) UsersView(w http.ResponseWriter, r *http.Request) {
+	au.render(w, r, nil, nil)
+}
+
+// AddHandler creates new user.
+func (au *AdminUsers) AddHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	username := r.FormValue("username")
+
+	_, addErr := au.UserAdmin.AddUser(username, r.FormValue("email"), r.FormValue("password"),
+		r.FormValue("isAdmin") != "")
+	if addErr != nil {
+		log.Error().Err(addErr).Str("username", username).Msgf("[%s] cannot add user", contrAdminUsersPrefix)
+		displayError := fmt.Sprintf("cannot add user: %s", addErr.Error())
+		au.render(w, r, nil, &displayError)
+		return
+	}
+
+	msg := fmt.Sprintf("User [%s] added", username)
+	au.render(w, r, &msg, nil)
+}
+
+// SetActiveHandler enables or disables user. Admin cannot disable themselves.
+func (au *AdminUsers) SetActiveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	username := r.FormValue("username")
+	isActive := r.FormValue("active") == "1"
+
+	if au.isCurrentUser(r, username) && !isActive {
+		displayError := "you cannot disable yourself"
+		au.render(w, r, nil, &displayError)
+		return
+	}
+
+	setErr := au.UserAdmin.SetActive(username, isActive)
+	if setErr != nil {
+		log.Error().Err(setErr).Str("username", username).Msgf("[%s] cannot set user active", contrAdminUsersPrefix)
+		displayError := "cannot update user"
+		au.render(w, r, nil, &displayError)
+		return
+	}
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// PermissionsHandler sets module permissions and admin role of the user. Admin
+// cannot revoke their own admin role.
+func (au *AdminUsers) PermissionsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	username := r.FormValue("username")
+	isAdmin := r.FormValue("isAdmin") != ""
+
+	if au.isCurrentUser(r, username) && !isAdmin {
+		displayError := "you cannot revoke your own admin role"
+		au.render(w, r, nil, &displayError)
+		return
+	}
+
+	for _, module := range auth.Modules {
+		access := r.FormValue(module)
+		if access == "" {
+			access = auth.AccessNone
+		}
+		if setErr := au.UserAdmin.SetPermission(username, module, access); setErr != nil {
+			log.Error().Err(setErr).Str("username", username).Str("module", module).
+				Msgf("[%s] cannot set user permission", contrAdminUsersPrefix)
+			displayError := fmt.Sprintf("cannot set permission to %s", module)
+			au.render(w, r, nil, &displayError)
+			return
+		}
+	}
+	if setErr := au.UserAdmin.SetAdmin(username, isAdmin); setErr != nil {
+		log.Error().Err(setErr).Str("username", username).Msgf("[%s] cannot set admin role", contrAdminUsersPrefix)
+		displayError := "cannot set admin role"
+		au.render(w, r, nil, &displayError)
+		return
+	}
+	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+}
+
+// ResetPasswordHandler sets new random password for the user and revokes
+// user's sessions. The password is displayed only once.
+func (au *AdminUsers) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	username := r.FormValue("username")
+
+	newPassword := rand.AlphanumStr(resetPasswordLength)
+	resetErr := au.UserAdmin.ResetPassword(username, newPassword)
+	if resetErr != nil {
+		log.Error().Err(resetErr).Str("username", username).Msgf("[%s] cannot reset password", contrAdminUsersPrefix)
+		displayError := "cannot reset password"
+		au.render(w, r, nil, &displayError)
+		return
+	}
+
+	msg := fmt.Sprintf("New password of [%s]: %s", username, newPassword)
+	au.render(w, r, &msg, nil)
+}
+
+func (au *AdminUsers) render(w http.ResponseWriter, r *http.Request, msg, displayError *string) {
+	tmpl := front.AdminUsers(commonFromRequest(r))
+	page := AdminUsersPage{Modules: auth.Modules, Message: msg, Error: displayError}
+
+	users, uErr := au.UserAdmin.Users()
+	if uErr != nil {
+		log.Error().Err(uErr).Msgf("[%s] cannot load users", contrAdminUsersPrefix)
+		errMsg := "could not read users from database"
+		page.Error = &errMsg
+	}
+	page.Users = users
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render users view", contrAdminUsersPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
+
+// Checks whenever given username belongs to the logged in user.
+func (au *AdminUsers) isCurrentUser(r *http.Request, username string) bool {
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+	currentUser, uErr := au.DbClient.UserByUserId(tokenStatus.UserId)
+	return uErr == nil && currentUser.Username == username
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"homeApp/auth"
+	"homeApp/db"
+)
+
+// Templates and schema are read relative to repository root, the same way as
+// when HomeApp is run.
+func TestMain(m *testing.M) {
+	if cdErr := os.Chdir(".."); cdErr != nil {
+		panic(cdErr)
+	}
+	os.Exit(m.Run())
+}
+
+// Creates AdminUsers on top of new test database with logged in admin. Session
+// cookie of the admin is returned.
+func newTestAdminUsers(t *testing.T) (*AdminUsers, auth.UserAuth, *http.Cookie) {
+	t.Helper()
+	schema, rErr := os.ReadFile(filepath.Join("sql", "schema.sql"))
+	if rErr != nil {
+		t.Fatal(rErr)
+	}
+	dbClient, cErr := db.NewClient(filepath.Join(t.TempDir(), "test.db"))
+	if cErr != nil {
+		t.Fatal(cErr)
+	}
+	if mErr := dbClient.Migrate(string(schema)); mErr != nil {
+		t.Fatalf("cannot create schema: %v", mErr)
+	}
+	keys, kErr := auth.NewSigningKeys(dbClient, time.Hour, time.Hour)
+	if kErr != nil {
+		t.Fatal(kErr)
+	}
+	ua := auth.UserAuth{DbClient: dbClient, SigningKeys: keys, JwtExpMinutes: 10}
+
+	au := &AdminUsers{
+		UserAdmin: auth.UserAdmin{DbClient: dbClient, PendingLogins: auth.NewPendingLogins()},
+		DbClient:  dbClient,
+	}
+	adminId, aErr := au.UserAdmin.AddUser("admin", "admin@example.com", "correct horse battery 1", true)
+	if aErr != nil {
+		t.Fatal(aErr)
+	}
+	token, sErr := ua.NewSession(adminId, auth.ClientInfo{})
+	if sErr != nil {
+		t.Fatal(sErr)
+	}
+	return au, ua, auth.SessionCookie(token, false)
+}
+
+// Posts given form to the handler as logged in user, so the session is checked
+// the same way as for registered endpoints.
+func postAsUser(ua auth.UserAuth, handler http.HandlerFunc, cookie *http.Cookie, form url.Values) *httptest.ResponseRecorder {
+	hm := auth.HandlerManager{UserAuthenticator: ua}
+	r := httptest.NewRequest(http.MethodPost, "/admin/users", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.AddCookie(cookie)
+	w := httptest.NewRecorder()
+	hm.CheckAuth(handler)(w, r)
+	return w
+}
+
+func TestSetActiveHandler(t *testing.T) {
+	au, ua, adminCookie := newTestAdminUsers(t)
+	bobId, aErr := au.UserAdmin.AddUser("bob", "bob@example.com", "staple horse battery 2", false)
+	if aErr != nil {
+		t.Fatal(aErr)
+	}
+	bobToken, sErr := ua.NewSession(bobId, auth.ClientInfo{})
+	if sErr != nil {
+		t.Fatal(sErr)
+	}
+
+	testCases := []struct {
+		name     string
+		username string
+		active   string
+		error    string // empty when redirected back to users list
+		isActive bool
+	}{
+		{"disable other user", "bob", "0", "", false},
+		{"enable other user", "bob", "1", "", true},
+		{"disable yourself", "admin", "0", "you cannot disable yourself", true},
+		{"enable yourself", "admin", "1", "", true},
+		{"unknown user", "unknown", "0", "cannot update user", false},
+	}
+
+	for _, tc := range testCases {
+		w := postAsUser(ua, au.SetActiveHandler, adminCookie, url.Values{"username": {tc.username}, "active": {tc.active}})
+		if tc.error == "" {
+			if location := w.Header().Get("Location"); w.Code != http.StatusSeeOther || location != "/admin/users" {
+				t.Errorf("[%s] expected redirect to users list, got %d to %s", tc.name, w.Code, location)
+			}
+		} else if !strings.Contains(w.Body.String(), tc.error) {
+			t.Errorf("[%s] expected error [%s] to be rendered", tc.name, tc.error)
+		}
+
+		user, uErr := au.DbClient.UserByUsername(tc.username)
+		if uErr == nil && user.IsActive != tc.isActive {
+			t.Errorf("[%s] expected user active=%v, got %v", tc.name, tc.isActive, user.IsActive)
+		}
+	}
+
+	// Session of disabled user was revoked and enabling doesn't bring it back
+	if status, _ := ua.IsJwtTokenValid(bobToken); status.IsValid {
+		t.Error("expected session of disabled user to be revoked")
+	}
+}
+
+func TestPermissionsHandler(t *testing.T) {
+	au, ua, adminCookie := newTestAdminUsers(t)
+	if _, aErr := au.UserAdmin.AddUser("bob", "bob@example.com", "staple horse battery 2", false); aErr != nil {
+		t.Fatal(aErr)
+	}
+
+	testCases := []struct {
+		name     string
+		username string
+		isAdmin  string
+		error    string // empty when redirected back to users list
+		hasRole  bool
+	}{
+		{"grant admin role", "bob", "1", "", true},
+		{"revoke admin role", "bob", "", "", false},
+		{"revoke own admin role", "admin", "", "you cannot revoke your own admin role", true},
+	}
+
+	for _, tc := range testCases {
+		form := url.Values{"username": {tc.username}, "isAdmin": {tc.isAdmin}, auth.ModuleFinance: {auth.AccessRead}}
+		w := postAsUser(ua, au.PermissionsHandler, adminCookie, form)
+		if tc.error == "" {
+			if location := w.Header().Get("Location"); w.Code != http.StatusSeeOther || location != "/admin/users" {
+				t.Errorf("[%s] expected redirect to users list, got %d to %s", tc.name, w.Code, location)
+			}
+		} else if !strings.Contains(w.Body.String(), tc.error) {
+			t.Errorf("[%s] expected error [%s] to be rendered", tc.name, tc.error)
+		}
+
+		user, _ := au.DbClient.UserByUsername(tc.username)
+		if isAdmin, _ := au.UserAdmin.IsAdmin(user.UserId); isAdmin != tc.hasRole {
+			t.Errorf("[%s] expected admin role %v, got %v", tc.name, tc.hasRole, isAdmin)
+		}
+	}
+}
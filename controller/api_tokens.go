@@ -0,0 +1,112 @@
+package controller
+
+import (
+	"fmt"
+	"homeApp/auth"
+	"homeApp/front"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const contrApiTokensPrefix = "controller/apiTokens"
+
+type ApiTokens struct {
+	ApiTokens auth.ApiTokens
+}
+
+type ApiTokensPage struct {
+	Tokens   []auth.ApiTokenInfo
+	Modules  []ApiTokenModule
+	NewToken *string
+	Error    *string
+}
+
+// ApiTokenModule is a module which can be included in new API token scopes.
+type ApiTokenModule struct {
+	Name     string
+	CanWrite bool
+}
+
+// ApiTokensView renders API tokens settings page of the current user.
+func (at *ApiTokens) ApiTokensView(w http.ResponseWriter, r *http.Request) {
+	at.render(w, r, nil, nil)
+}
+
+// CreateHandler creates new API token with scopes chosen in the form. The
+// token is displayed only once.
+func (at *ApiTokens) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	scopes := make(map[string]string, len(auth.Modules))
+	for _, module := range auth.Modules {
+		if access := r.FormValue(module); access != "" {
+			scopes[module] = access
+		}
+	}
+
+	token, createErr := at.ApiTokens.Create(tokenStatus.UserId, r.FormValue("name"), scopes)
+	if createErr != nil {
+		log.Error().Err(createErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot create API token", contrApiTokensPrefix)
+		displayError := fmt.Sprintf("cannot create API token: %s", createErr.Error())
+		at.render(w, r, nil, &displayError)
+		return
+	}
+	at.render(w, r, &token, nil)
+}
+
+// RevokeHandler revokes API token of the current user.
+func (at *ApiTokens) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	revokeErr := at.ApiTokens.Revoke(tokenStatus.UserId, r.FormValue("tokenId"))
+	if revokeErr != nil {
+		log.Error().Err(revokeErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot revoke API token", contrApiTokensPrefix)
+		displayError := "cannot revoke API token"
+		at.render(w, r, nil, &displayError)
+		return
+	}
+	http.Redirect(w, r, "/settings/tokens", http.StatusSeeOther)
+}
+
+func (at *ApiTokens) render(w http.ResponseWriter, r *http.Request, newToken, displayError *string) {
+	tmpl := front.ApiTokens(commonFromRequest(r))
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+	permissions, _ := auth.PermissionsFromRequest(r)
+	page := ApiTokensPage{NewToken: newToken, Error: displayError}
+
+	for _, module := range auth.Modules {
+		if permissions.CanRead(module) {
+			page.Modules = append(page.Modules, ApiTokenModule{
+				Name:     module,
+				CanWrite: permissions.Can(module, auth.AccessWrite),
+			})
+		}
+	}
+
+	tokens, tErr := at.ApiTokens.Tokens(tokenStatus.UserId)
+	if tErr != nil {
+		log.Error().Err(tErr).Int("userId", tokenStatus.UserId).Msgf("[%s] cannot load API tokens", contrApiTokensPrefix)
+		errMsg := "could not read API tokens from database"
+		page.Error = &errMsg
+	}
+	page.Tokens = tokens
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render API tokens view", contrApiTokensPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
@@ -24,8 +24,7 @@ const (
 
 type Books struct {
 	DbClient       *db.Client
-	TelegramClient *telegram.Client
-	UserAuth       auth.UserAuthenticator
+	TelegramClient *telegram.Client // Used for sending files, nil when Telegram is off
 }
 
 type Book struct {
@@ -41,14 +40,16 @@ type Book struct {
 }
 
 type BookList struct {
-	Books []Book
-	Error *string
+	Books           []Book
+	TelegramEnabled bool
+	Info            *string
+	Error           *string
 }
 
 func (b *Books) BooksViewHandler(w http.ResponseWriter, r *http.Request) {
 	r.ParseForm()
 	filterPhrase := r.FormValue("bookFilter")
-	tmpl := front.Books()
+	tmpl := front.Books(commonFromRequest(r))
 
 	var books []db.Book
 	var bErr error
@@ -70,7 +71,13 @@ func (b *Books) BooksViewHandler(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	execErr := tmpl.Execute(w, BookList{Books: booksToDisplay(books)})
+	info, displayError := telegramFileMessages(r)
+	execErr := tmpl.Execute(w, BookList{
+		Books:           booksToDisplay(books),
+		TelegramEnabled: b.TelegramClient != nil,
+		Info:            info,
+		Error:           displayError,
+	})
 	if execErr != nil {
 		log.Error().Err(execErr).Msgf("[%s] cannot render books view", contrBookPrefix)
 		http.Redirect(w, r, "/home", http.StatusSeeOther)
@@ -78,7 +85,7 @@ func (b *Books) BooksViewHandler(w http.ResponseWriter, r *http.Request) {
 }
 
 func (b *Books) BooksInsertForm(w http.ResponseWriter, r *http.Request) {
-	tmpl := front.BooksNewForm()
+	tmpl := front.BooksNewForm(commonFromRequest(r))
 
 	execErr := tmpl.Execute(w, nil)
 	if execErr != nil {
@@ -90,7 +97,7 @@ func (b *Books) BooksInsertForm(w http.ResponseWriter, r *http.Request) {
 func (b *Books) InsertNewBook(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start parsing new book form", contrBookPrefix)
-	tmpl := front.Books()
+	tmpl := front.Books(commonFromRequest(r))
 
 	r.ParseMultipartForm(maxBookSize)
 	var buf bytes.Buffer
@@ -102,14 +109,6 @@ func (b *Books) InsertNewBook(w http.ResponseWriter, r *http.Request) {
 
 	newBook := formValuesToNewBook(r, buf.Bytes())
 
-	dErr := b.DbClient.BookInsertNew(newBook)
-	if dErr != nil {
-		log.Error().Err(dErr).Msgf("[%s] uploading new book failed", contrBookPrefix)
-		msg := "Could not insert new book"
-		tmpl.Execute(w, BookList{Error: &msg})
-		return
-	}
-
 	var msg string
 	if buf.Len() > 0 {
 		msg = fmt.Sprintf("Uploaded new e-book [%s] by [%s] of size %.2f MB in [%s] category.",
@@ -118,9 +117,14 @@ func (b *Books) InsertNewBook(w http.ResponseWriter, r *http.Request) {
 		msg = fmt.Sprintf("Uploaded new book info [%s] by [%s] in [%s] category.",
 			newBook.Title, newBook.Authors, newBook.Category)
 	}
-	teleErr := SendTelegramMsgForUser(r, b.UserAuth, b.TelegramClient, b.DbClient, msg)
-	if teleErr != nil {
-		log.Error().Err(teleErr).Msgf("[%s] sending message to Telegram failed", contrFinPrefix)
+	notification := uploadNotification(r, b.DbClient, msg)
+
+	dErr := b.DbClient.BookInsertNew(newBook, notification)
+	if dErr != nil {
+		log.Error().Err(dErr).Msgf("[%s] uploading new book failed", contrBookPrefix)
+		errMsg := "Could not insert new book"
+		tmpl.Execute(w, BookList{Error: &errMsg})
+		return
 	}
 
 	log.Info().Dur("duration", time.Since(startTs)).
@@ -155,8 +159,7 @@ func (b *Books) DownloadBook(w http.ResponseWriter, r *http.Request) {
 	}
 
 	newTitle := strings.ReplaceAll(title, " ", "_")
-	w.Header().Set("Content-Disposition", "attachment; filename="+newTitle+"."+fileExt)
-	w.Header().Set("Content-type", fileExtToContentType(fileExt))
+	auth.SetFileHeaders(w, fileExtToContentType(fileExt), newTitle+"."+fileExt, false)
 	buff := bytes.NewBuffer(docFile)
 	bytesWritten, writeErr := buff.WriteTo(w)
 	if writeErr != nil {
@@ -177,6 +180,45 @@ func (b *Books) DownloadBook(w http.ResponseWriter, r *http.Request) {
 		Msgf("[%s] finished preparing book preview", contrBookPrefix)
 }
 
+// Loads e-book content from database and sends it onto the Telegram channel.
+func (b *Books) SendBookToTelegram(w http.ResponseWriter, r *http.Request) {
+	startTs := time.Now()
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+		return
+	}
+
+	bookId := r.FormValue("id")
+	id, convErr := strconv.ParseInt(bookId, 10, 32)
+	if convErr != nil {
+		log.Error().Str("id", bookId).Msgf("[%s] cannot convert form value 'id' to int", contrBookPrefix)
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+		return
+	}
+	fileSize, sizeErr := b.DbClient.BookFileSize(int(id))
+	if sizeErr != nil {
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+		return
+	}
+	if exceedsTelegramLimit(fileSize) {
+		http.Redirect(w, r, "/books?telegram="+telegramFileTooLarge, http.StatusSeeOther)
+		return
+	}
+	bookFile, title, fileExt, dbErr := b.DbClient.BookFile(int(id))
+	if dbErr != nil || len(bookFile) == 0 {
+		log.Error().Err(dbErr).Str("id", bookId).
+			Msgf("[%s] cannot load book content from database", contrBookPrefix)
+		http.Redirect(w, r, "/books", http.StatusSeeOther)
+		return
+	}
+
+	fileName := strings.ReplaceAll(title, " ", "_") + "." + fileExt
+	result := sendFileToTelegram(b.TelegramClient, fileName, bookFile, title)
+	log.Info().Str("id", bookId).Str("result", result).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished sending book to Telegram", contrBookPrefix)
+	http.Redirect(w, r, "/books?telegram="+result, http.StatusSeeOther)
+}
+
 func formValuesToNewBook(r *http.Request, parsedBookFile []byte) db.NewBook {
 	category := r.FormValue("category")
 	titleFv := r.FormValue("title")
@@ -0,0 +1,24 @@
+package controller
+
+import (
+	"homeApp/auth"
+	"homeApp/front"
+	"net/http"
+)
+
+// Builds common templates data of logged in user based on session and
+// permissions put into the request context by auth middleware. For pages which
+// don't require login only CSP nonce is set.
+func commonFromRequest(r *http.Request) front.Common {
+	permissions, _ := auth.PermissionsFromRequest(r)
+	common := front.Common{
+		Modules:   make(map[string]bool, len(auth.Modules)),
+		IsAdmin:   permissions.IsAdmin,
+		CsrfToken: auth.CsrfTokenFromRequest(r),
+		CspNonce:  auth.CspNonceFromRequest(r),
+	}
+	for _, module := range auth.Modules {
+		common.Modules[module] = permissions.CanRead(module)
+	}
+	return common
+}
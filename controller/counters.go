@@ -6,8 +6,6 @@ import (
 	"strconv"
 	"time"
 
-	"homeApp/auth"
-	"homeApp/auth/telegram"
 	"homeApp/db"
 	"homeApp/front"
 
@@ -17,9 +15,7 @@ import (
 const contrCountPrefix = "controller/counter"
 
 type Counters struct {
-	DbClient       *db.Client
-	TelegramClient *telegram.Client
-	UserAuth       auth.UserAuthenticator
+	DbClient *db.Client
 }
 
 type CountersTemplateInput struct {
@@ -33,9 +29,9 @@ type CountersUpload struct {
 }
 
 // CountersViewHandler serves page with counter data.
-func (c *Counters) CountersViewHandler(w http.ResponseWriter, _ *http.Request) {
+func (c *Counters) CountersViewHandler(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
-	tmpl := front.Counters()
+	tmpl := front.Counters(commonFromRequest(r))
 	log.Info().Msgf("[%s] start reading counters data", contrCountPrefix)
 
 	waterData, wdErr := c.DbClient.WaterData(1, 10) // TODO: paging
@@ -58,15 +54,15 @@ func (c *Counters) CountersViewHandler(w http.ResponseWriter, _ *http.Request) {
 }
 
 // CountersInsertForm renders counters insert form page.
-func (c *Counters) CountersInsertForm(w http.ResponseWriter, _ *http.Request) {
-	tmpl := front.CountersNewForm()
+func (c *Counters) CountersInsertForm(w http.ResponseWriter, r *http.Request) {
+	tmpl := front.CountersNewForm(commonFromRequest(r))
 	tmpl.Execute(w, nil)
 }
 
 // CountersUploadNew takes counter insert form data and put it into database.
 func (c *Counters) CountersUploadNew(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
-	tmpl := front.CountersNewForm()
+	tmpl := front.CountersNewForm(commonFromRequest(r))
 	log.Info().Msgf("[%s] start inserting new counters data", contrCountPrefix)
 
 	r.ParseForm()
@@ -96,7 +92,11 @@ func (c *Counters) CountersUploadNew(w http.ResponseWriter, r *http.Request) {
 		EnergyKwh: ene,
 	}
 
-	dbErr := c.DbClient.CountersInsertNew(waterInput, energyInput)
+	msg := fmt.Sprintf("Uploaded new counters state for [%s]: Water{Cold: %d liters, Hot: %d liters}"+
+		", Energy %.2f kWh.", date, int(coldWater), int(hotWater), ene)
+	notification := uploadNotification(r, c.DbClient, msg)
+
+	dbErr := c.DbClient.CountersInsertNew(waterInput, energyInput, notification)
 	if dbErr != nil {
 		log.Error().Err(dbErr).Msgf("[%s] cannot insert counters data into database", contrCountPrefix)
 		displayError := "could not insert counters data into database"
@@ -104,13 +104,6 @@ func (c *Counters) CountersUploadNew(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	msg := fmt.Sprintf("Uploaded new counters state for [%s]: Water{Cold: %d liters, Hot: %d liters}"+
-		", Energy %.2f kWh.", date, int(coldWater), int(hotWater), ene)
-	teleErr := SendTelegramMsgForUser(r, c.UserAuth, c.TelegramClient, c.DbClient, msg)
-	if teleErr != nil {
-		log.Error().Err(teleErr).Msgf("[%s] sending message to Telegram failed", contrFinPrefix)
-	}
-
 	log.Info().Dur("duration", time.Since(startTs)).Msgf("[%s] finished inserting counters data",
 		contrCountPrefix)
 
@@ -24,12 +24,14 @@ const (
 
 type Documents struct {
 	DbClient       *db.Client
-	TelegramClient *telegram.Client
-	UserAuth       auth.UserAuthenticator
+	TelegramClient *telegram.Client // Used for sending files, nil when Telegram is off
 }
 
 type DocumentsList struct {
-	Documents []db.DocumentInfo
+	Documents       []db.DocumentInfo
+	TelegramEnabled bool
+	Info            *string
+	Error           *string
 }
 
 func (d *Documents) DocumentsViewHandler(w http.ResponseWriter, r *http.Request) {
@@ -56,8 +58,14 @@ func (d *Documents) DocumentsViewHandler(w http.ResponseWriter, r *http.Request)
 		}
 	}
 
-	tmpl := front.Documents()
-	execErr := tmpl.Execute(w, DocumentsList{Documents: documents})
+	info, displayError := telegramFileMessages(r)
+	tmpl := front.Documents(commonFromRequest(r))
+	execErr := tmpl.Execute(w, DocumentsList{
+		Documents:       documents,
+		TelegramEnabled: d.TelegramClient != nil,
+		Info:            info,
+		Error:           displayError,
+	})
 	if execErr != nil {
 		log.Error().Err(execErr).Msgf("[%s] cannot render document list view", contrDocPrefix)
 		http.Redirect(w, r, "/home", http.StatusSeeOther)
@@ -66,7 +74,7 @@ func (d *Documents) DocumentsViewHandler(w http.ResponseWriter, r *http.Request)
 }
 
 func (d *Documents) DocumentsInsertForm(w http.ResponseWriter, r *http.Request) {
-	tmpl := front.DocumentsNewForm()
+	tmpl := front.DocumentsNewForm(commonFromRequest(r))
 	execErr := tmpl.Execute(w, nil)
 	if execErr != nil {
 		log.Error().Err(execErr).Msgf("[%s] cannot render document insert form", contrDocPrefix)
@@ -95,6 +103,7 @@ func (d *Documents) InsertNewDocument(w http.ResponseWriter, r *http.Request) {
 	docCategory := r.FormValue("category")
 	docPerson := r.FormValue("person")
 	docExt := r.FormValue("fileExt")
+	docExpiry := r.FormValue("expiryDate")
 
 	newDoc := db.NewDocument{
 		Name:           docName,
@@ -102,23 +111,21 @@ func (d *Documents) InsertNewDocument(w http.ResponseWriter, r *http.Request) {
 		Category:       docCategory,
 		PersonInvolved: docPerson,
 		FileExtension:  docExt,
+		ExpiryDate:     docExpiry,
 		DocumentFile:   buf.Bytes(),
 	}
 
-	dErr := d.DbClient.DocumentInsertNew(newDoc)
+	msg := fmt.Sprintf("Uploaded new document [%s][%s] of size %.2f MB", docName, docExt,
+		float64(buf.Len())/1000000.0)
+	notification := uploadNotification(r, d.DbClient, msg)
+
+	dErr := d.DbClient.DocumentInsertNew(newDoc, notification)
 	if dErr != nil {
 		log.Error().Err(dErr).Msgf("[%s] uploading new document failed", contrDocPrefix)
 		http.Redirect(w, r, "/documents", http.StatusSeeOther)
 		return
 	}
 
-	msg := fmt.Sprintf("Uploaded new document [%s][%s] of size %.2f MB", docName, docExt,
-		float64(buf.Len())/1000000.0)
-	teleErr := SendTelegramMsgForUser(r, d.UserAuth, d.TelegramClient, d.DbClient, msg)
-	if teleErr != nil {
-		log.Error().Err(teleErr).Msgf("[%s] sending message to Telegram failed", contrFinPrefix)
-	}
-
 	log.Info().Dur("duration", time.Since(startTs)).
 		Msgf("[%s] finished parsing and saving new document", contrDocPrefix)
 
@@ -142,7 +149,7 @@ func (d *Documents) PreviewDocument(w http.ResponseWriter, r *http.Request) {
 		http.Redirect(w, r, "/documents", http.StatusSeeOther)
 		return
 	}
-	docFile, fileExt, dbErr := d.DbClient.DocumentFile(int(docId))
+	docFile, name, fileExt, dbErr := d.DbClient.DocumentFile(int(docId))
 	if dbErr != nil {
 		log.Error().Err(dbErr).Str("id", documentId).
 			Msgf("[%s] cannot load document content from database", contrDocPrefix)
@@ -150,7 +157,8 @@ func (d *Documents) PreviewDocument(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	w.Header().Set("Content-type", fileExtToContentType(fileExt))
+	contentType := fileExtToContentType(fileExt)
+	auth.SetFileHeaders(w, contentType, name+"."+fileExt, isPreviewable(contentType))
 	buff := bytes.NewBuffer(docFile)
 	bytesWritten, writeErr := buff.WriteTo(w)
 	if writeErr != nil {
@@ -171,6 +179,55 @@ func (d *Documents) PreviewDocument(w http.ResponseWriter, r *http.Request) {
 		Msgf("[%s] finished preparing document preview", contrDocPrefix)
 }
 
+// Loads document content from database and sends it onto the Telegram channel.
+func (d *Documents) SendDocumentToTelegram(w http.ResponseWriter, r *http.Request) {
+	startTs := time.Now()
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/documents", http.StatusSeeOther)
+		return
+	}
+
+	documentId := r.FormValue("id")
+	docId, convErr := strconv.ParseInt(documentId, 10, 32)
+	if convErr != nil {
+		log.Error().Str("id", documentId).Msgf("[%s] cannot convert form value 'id' to int", contrDocPrefix)
+		http.Redirect(w, r, "/documents", http.StatusSeeOther)
+		return
+	}
+	fileSize, sizeErr := d.DbClient.DocumentFileSize(int(docId))
+	if sizeErr != nil {
+		http.Redirect(w, r, "/documents", http.StatusSeeOther)
+		return
+	}
+	if exceedsTelegramLimit(fileSize) {
+		http.Redirect(w, r, "/documents?telegram="+telegramFileTooLarge, http.StatusSeeOther)
+		return
+	}
+	docFile, name, fileExt, dbErr := d.DbClient.DocumentFile(int(docId))
+	if dbErr != nil {
+		log.Error().Err(dbErr).Str("id", documentId).
+			Msgf("[%s] cannot load document content from database", contrDocPrefix)
+		http.Redirect(w, r, "/documents", http.StatusSeeOther)
+		return
+	}
+
+	result := sendFileToTelegram(d.TelegramClient, name+"."+fileExt, docFile, name)
+	log.Info().Str("id", documentId).Str("result", result).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished sending document to Telegram", contrDocPrefix)
+	http.Redirect(w, r, "/documents?telegram="+result, http.StatusSeeOther)
+}
+
+// Checks whenever browsers can display file of given content type. Other files
+// are downloaded, instead of being previewed.
+func isPreviewable(contentType string) bool {
+	switch contentType {
+	case "application/pdf", "image/gif", "image/png", "image/jpeg", "video/mp4", "text/plain":
+		return true
+	default:
+		return false
+	}
+}
+
 func fileExtToContentType(fileExt string) string {
 	fe := strings.ToLower(fileExt)
 
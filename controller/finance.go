@@ -4,14 +4,13 @@ import (
 	"bytes"
 	"errors"
 	"fmt"
-	"homeApp/auth"
-	"homeApp/auth/telegram"
 	"homeApp/db"
 	"homeApp/finance"
 	"homeApp/front"
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/rs/zerolog/log"
@@ -23,9 +22,7 @@ const (
 )
 
 type Finance struct {
-	DbClient       *db.Client
-	TelegramClient *telegram.Client
-	UserAuth       auth.UserAuthenticator
+	DbClient *db.Client
 }
 
 type FinanceData struct {
@@ -42,6 +39,7 @@ type FinancialMonthlyAgg struct {
 type FinanceUpload struct {
 	Stats       *UploadStats
 	UploadError *string
+	CsvProfiles []db.CsvProfile
 }
 
 type UploadStats struct {
@@ -60,7 +58,7 @@ func (f *Finance) FinanceViewHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	tmpl := front.Finance()
+	tmpl := front.Finance(commonFromRequest(r))
 	execErr := tmpl.Execute(w, FinanceData{monthlyAgg})
 	if execErr != nil {
 		log.Error().Err(execErr).Msgf("[%s] cannot render finance view", contrFinPrefix)
@@ -71,8 +69,8 @@ func (f *Finance) FinanceViewHandler(w http.ResponseWriter, r *http.Request) {
 
 // FinanceInsertForm renders financial insert form for new files.
 func (f *Finance) FinanceInsertForm(w http.ResponseWriter, r *http.Request) {
-	tmpl := front.FinanceNewForm()
-	execErr := tmpl.Execute(w, nil)
+	tmpl := front.FinanceNewForm(commonFromRequest(r))
+	execErr := tmpl.Execute(w, f.withCsvProfiles(FinanceUpload{}))
 	if execErr != nil {
 		log.Error().Err(execErr).Msgf("[%s] cannot render finance insert form", contrFinPrefix)
 		http.Redirect(w, r, "/finance", http.StatusSeeOther)
@@ -84,7 +82,7 @@ func (f *Finance) FinanceInsertForm(w http.ResponseWriter, r *http.Request) {
 func (f *Finance) FinanceUploadFile(w http.ResponseWriter, r *http.Request) {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start parsing new transactions form", contrFinPrefix)
-	tmpl := front.FinanceNewForm()
+	tmpl := front.FinanceNewForm(commonFromRequest(r))
 
 	r.ParseMultipartForm(maxTranactinosFileSize)
 	parserType := r.FormValue("parser-type")
@@ -93,17 +91,17 @@ func (f *Finance) FinanceUploadFile(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		log.Error().Err(err).Msgf("[%s] couldn't get file from the form", contrDocPrefix)
 		errDisplay := "Could not get file from the form, please retry"
-		tmpl.Execute(w, FinanceUpload{UploadError: &errDisplay})
+		tmpl.Execute(w, f.withCsvProfiles(FinanceUpload{UploadError: &errDisplay}))
 		return
 	}
 	defer file.Close()
 	io.Copy(&buf, file)
 
-	transParser, parserErr := parserTypeToParser(parserType)
+	transParser, parserErr := f.parserTypeToParser(parserType)
 	if parserErr != nil {
 		log.Error().Err(parserErr).Msgf("[%s] parser selection failed", contrFinPrefix)
 		errDisplay := "Could not parse given file. Please check if file is in correct format."
-		tmpl.Execute(w, FinanceUpload{UploadError: &errDisplay})
+		tmpl.Execute(w, f.withCsvProfiles(FinanceUpload{UploadError: &errDisplay}))
 		return
 	}
 
@@ -111,39 +109,58 @@ func (f *Finance) FinanceUploadFile(w http.ResponseWriter, r *http.Request) {
 	if tErr != nil {
 		log.Error().Err(tErr).Msgf("[%s] couldn't parse file into list of transactions", contrFinPrefix)
 		errDisplay := "Could not parse given file. Please check if file is in correct format."
-		tmpl.Execute(w, FinanceUpload{UploadError: &errDisplay})
+		if _, isCsv := transParser.(finance.CsvParser); isCsv {
+			errDisplay = fmt.Sprintf("Could not parse given file using CSV profile: %s.", tErr.Error())
+		}
+		tmpl.Execute(w, f.withCsvProfiles(FinanceUpload{UploadError: &errDisplay}))
 		return
 	}
 
-	dbErr := f.DbClient.FinInsertTransactions(TransactionsToDbTransactions(transactions))
+	uploadStats := prepUploadStats(transactions)
+	msg := fmt.Sprintf("Uploaded %d financial transactions from %s to %s.",
+		uploadStats.NumOfTransactions, uploadStats.MinExecutionDate, uploadStats.MaxExecutionDate)
+	notification := uploadNotification(r, f.DbClient, msg)
+
+	dbErr := f.DbClient.FinInsertTransactions(TransactionsToDbTransactions(transactions), notification)
 	if dbErr != nil {
 		log.Error().Err(dbErr).Msgf("[%s] couldn't insert transactions into database", contrFinPrefix)
 		errDisplay := "Insertion into database failed, please contact administrator"
-		tmpl.Execute(w, FinanceUpload{UploadError: &errDisplay})
+		tmpl.Execute(w, f.withCsvProfiles(FinanceUpload{UploadError: &errDisplay}))
 		return
 	}
 
-	uploadStats := prepUploadStats(transactions)
 	stats := FinanceUpload{Stats: &uploadStats}
 
-	msg := fmt.Sprintf("Uploaded %d financial transactions from %s to %s.",
-		uploadStats.NumOfTransactions, uploadStats.MinExecutionDate, uploadStats.MaxExecutionDate)
-	teleErr := SendTelegramMsgForUser(r, f.UserAuth, f.TelegramClient, f.DbClient, msg)
-	if teleErr != nil {
-		log.Error().Err(teleErr).Msgf("[%s] sending message to Telegram failed", contrFinPrefix)
-	}
-
 	log.Info().Dur("duration", time.Since(startTs)).Msgf("[%s] finished parsing new transactions form", contrFinPrefix)
-	tmpl.Execute(w, stats)
+	tmpl.Execute(w, f.withCsvProfiles(stats))
 }
 
-func parserTypeToParser(ptype string) (finance.TransactionParser, error) {
+// Parser type is either "pkoxml" or "csv:<profile ID>" for saved CSV profiles.
+func (f *Finance) parserTypeToParser(ptype string) (finance.TransactionParser, error) {
 	if ptype == "pkoxml" {
 		return finance.PkoBankXmlParser{}, nil
 	}
+	if strings.HasPrefix(ptype, "csv:") {
+		profileId := strings.TrimPrefix(ptype, "csv:")
+		id, pErr := strconv.ParseInt(profileId, 10, 64)
+		if pErr != nil {
+			return nil, fmt.Errorf("incorrect CSV profile ID [%s]", profileId)
+		}
+		return f.csvParser(id)
+	}
 	return nil, errors.New("unsupported parser")
 }
 
+// Adds saved CSV profiles, so they can be chosen in the upload form.
+func (f *Finance) withCsvProfiles(upload FinanceUpload) FinanceUpload {
+	profiles, pErr := f.DbClient.CsvProfiles()
+	if pErr != nil {
+		log.Error().Err(pErr).Msgf("[%s] cannot read CSV profiles", contrFinPrefix)
+	}
+	upload.CsvProfiles = profiles
+	return upload
+}
+
 func prepUploadStats(newTransactions []finance.Transaction) UploadStats {
 	minExecDate := "2900-01-01"
 	maxExecDate := "1900-01-01"
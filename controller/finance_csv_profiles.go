@@ -0,0 +1,389 @@
+package controller
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"homeApp/db"
+	"homeApp/finance"
+	"homeApp/front"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	contrCsvProfilesPrefix = "controller/csvProfiles"
+	csvPreviewRows         = 15
+	csvSampleLines         = 50
+	maxCsvSampleSize       = 64 * 1024
+)
+
+// FinanceCsvProfiles manages saved mappings of CSV files with transactions.
+type FinanceCsvProfiles struct {
+	DbClient *db.Client
+}
+
+type CsvProfilesPage struct {
+	Profiles      []db.CsvProfile
+	Profile       db.CsvProfile // Edited profile
+	Delimiters    []CsvDelimiter
+	Encodings     []string
+	AmountSigns   []string
+	Preview       *finance.CsvPreview
+	ColumnNumbers []int  // Headers of preview table
+	Sample        string // Base64 encoded beginning of previewed file, so it's not uploaded again
+	Error         *string
+	Info          *string
+}
+
+type CsvDelimiter struct {
+	Value string
+	Label string
+}
+
+var csvDelimiters = []CsvDelimiter{
+	{Value: ",", Label: "Comma (,)"},
+	{Value: ";", Label: "Semicolon (;)"},
+	{Value: "\t", Label: "Tab"},
+	{Value: "|", Label: "Pipe (|)"},
+}
+
+// ProfilesView renders list of saved profiles and profile editor. Profile
+// given by id query parameter is loaded into the editor.
+func (cp *FinanceCsvProfiles) ProfilesView(w http.ResponseWriter, r *http.Request) {
+	profile := newCsvProfile()
+	if idParam := r.URL.Query().Get("id"); idParam != "" {
+		profileId, pErr := strconv.ParseInt(idParam, 10, 64)
+		if pErr != nil {
+			displayError := "incorrect profile ID"
+			cp.render(w, r, CsvProfilesPage{Profile: profile, Error: &displayError})
+			return
+		}
+		saved, sErr := cp.DbClient.CsvProfileById(profileId)
+		if sErr != nil {
+			displayError := "cannot read CSV profile"
+			cp.render(w, r, CsvProfilesPage{Profile: profile, Error: &displayError})
+			return
+		}
+		profile = saved
+	}
+	cp.render(w, r, CsvProfilesPage{Profile: profile})
+}
+
+// PreviewHandler shows the first rows of uploaded sample file read using
+// profile from the form. Previously uploaded sample is used when no file is
+// given, so the profile can be adjusted step by step.
+func (cp *FinanceCsvProfiles) PreviewHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/finance/csv-profiles", http.StatusSeeOther)
+		return
+	}
+	if pErr := r.ParseMultipartForm(maxTranactinosFileSize); pErr != nil {
+		log.Error().Err(pErr).Msgf("[%s] cannot parse CSV profile preview form", contrCsvProfilesPrefix)
+		cp.renderFormError(w, r)
+		return
+	}
+	page := cp.previewPage(r)
+	cp.render(w, r, page)
+}
+
+// SaveHandler validates and saves profile from the form. Sample preview is
+// rendered again, if there is any.
+func (cp *FinanceCsvProfiles) SaveHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/finance/csv-profiles", http.StatusSeeOther)
+		return
+	}
+	if pErr := r.ParseMultipartForm(maxTranactinosFileSize); pErr != nil {
+		log.Error().Err(pErr).Msgf("[%s] cannot parse CSV profile save form", contrCsvProfilesPrefix)
+		cp.renderFormError(w, r)
+		return
+	}
+	page := cp.previewPage(r)
+	if page.Error != nil {
+		cp.render(w, r, page)
+		return
+	}
+
+	profile := page.Profile
+	mapping, mErr := csvProfileToMapping(profile)
+	if mErr == nil {
+		mErr = mapping.Validate()
+	}
+	if mErr == nil && strings.TrimSpace(profile.Name) == "" {
+		mErr = errors.New("profile name should be provided")
+	}
+	if mErr != nil {
+		displayError := fmt.Sprintf("profile cannot be saved: %s", mErr.Error())
+		page.Error = &displayError
+		cp.render(w, r, page)
+		return
+	}
+
+	profile.Name = strings.TrimSpace(profile.Name)
+	profile.UpdatedTs = time.Now().UTC().Format(db.TimestampFormat)
+	profileId, sErr := cp.DbClient.CsvProfileSave(profile)
+	if sErr != nil {
+		displayError := "could not save profile, please check if its name is unique"
+		page.Error = &displayError
+		cp.render(w, r, page)
+		return
+	}
+	log.Info().Int64("profileId", profileId).Str("name", profile.Name).
+		Msgf("[%s] CSV profile saved", contrCsvProfilesPrefix)
+
+	page.Profile.ProfileId = profileId
+	info := fmt.Sprintf("Profile [%s] saved. It can be chosen when uploading new transactions.", profile.Name)
+	page.Info = &info
+	cp.render(w, r, page)
+}
+
+// DeleteHandler deletes profile given in the form.
+func (cp *FinanceCsvProfiles) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/finance/csv-profiles", http.StatusSeeOther)
+		return
+	}
+	if fErr := r.ParseForm(); fErr != nil {
+		log.Error().Err(fErr).Msgf("[%s] cannot parse CSV profile delete form", contrCsvProfilesPrefix)
+		cp.renderFormError(w, r)
+		return
+	}
+	profileId, pErr := strconv.ParseInt(r.FormValue("id"), 10, 64)
+	if pErr != nil {
+		displayError := "incorrect profile ID"
+		cp.render(w, r, CsvProfilesPage{Profile: newCsvProfile(), Error: &displayError})
+		return
+	}
+	if dErr := cp.DbClient.CsvProfileDelete(profileId); dErr != nil {
+		log.Error().Err(dErr).Int64("profileId", profileId).Msgf("[%s] cannot delete CSV profile", contrCsvProfilesPrefix)
+		displayError := "could not delete profile, please retry"
+		cp.render(w, r, CsvProfilesPage{Profile: newCsvProfile(), Error: &displayError})
+		return
+	}
+	log.Info().Int64("profileId", profileId).Msgf("[%s] CSV profile deleted", contrCsvProfilesPrefix)
+	http.Redirect(w, r, "/finance/csv-profiles", http.StatusSeeOther)
+}
+
+// Reads profile and sample from the form and prepares preview.
+func (cp *FinanceCsvProfiles) previewPage(r *http.Request) CsvProfilesPage {
+	profile, fErr := formToCsvProfile(r)
+	page := CsvProfilesPage{Profile: profile}
+	if fErr != nil {
+		displayError := fErr.Error()
+		page.Error = &displayError
+		return page
+	}
+
+	sample, sErr := csvSampleFromForm(r)
+	if sErr != nil {
+		log.Error().Err(sErr).Msgf("[%s] cannot read sample file", contrCsvProfilesPrefix)
+		displayError := "could not read sample file, please upload it again"
+		page.Error = &displayError
+		return page
+	}
+	if len(sample) == 0 {
+		return page
+	}
+	page.Sample = base64.StdEncoding.EncodeToString(sample)
+
+	mapping, mErr := csvProfileToMapping(profile)
+	if mErr != nil {
+		displayError := mErr.Error()
+		page.Error = &displayError
+		return page
+	}
+	preview, pErr := mapping.Preview(sample, csvPreviewRows)
+	if pErr != nil {
+		displayError := fmt.Sprintf("cannot read sample file: %s", pErr.Error())
+		page.Error = &displayError
+		return page
+	}
+	// Shorter rows are padded, so the preview table is aligned
+	for idx := range preview.Rows {
+		for len(preview.Rows[idx].Cells) < preview.Columns {
+			preview.Rows[idx].Cells = append(preview.Rows[idx].Cells, "")
+		}
+	}
+	for column := 1; column <= preview.Columns; column++ {
+		page.ColumnNumbers = append(page.ColumnNumbers, column)
+	}
+	page.Preview = &preview
+	return page
+}
+
+// Renders page with empty profile editor when the form cannot be read at all.
+func (cp *FinanceCsvProfiles) renderFormError(w http.ResponseWriter, r *http.Request) {
+	displayError := "could not read the form, please retry"
+	cp.render(w, r, CsvProfilesPage{Profile: newCsvProfile(), Error: &displayError})
+}
+
+func (cp *FinanceCsvProfiles) render(w http.ResponseWriter, r *http.Request, page CsvProfilesPage) {
+	tmpl := front.FinanceCsvProfiles(commonFromRequest(r))
+	profiles, pErr := cp.DbClient.CsvProfiles()
+	if pErr != nil && page.Error == nil {
+		displayError := "could not read saved profiles"
+		page.Error = &displayError
+	}
+	page.Profiles = profiles
+	page.Delimiters = csvDelimiters
+	page.Encodings = finance.Encodings
+	page.AmountSigns = finance.AmountSigns
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render CSV profiles view", contrCsvProfilesPrefix)
+		http.Redirect(w, r, "/finance", http.StatusSeeOther)
+	}
+}
+
+// Profile with defaults for the editor.
+func newCsvProfile() db.CsvProfile {
+	return db.CsvProfile{
+		Delimiter:           ",",
+		Encoding:            finance.EncodingUtf8,
+		HasHeader:           true,
+		DateLayout:          "2006-01-02",
+		AmountSign:          finance.AmountSigned,
+		DefaultCurrency:     "PLN",
+		ExecutionDateColumn: 1,
+	}
+}
+
+// Returns beginning of uploaded sample file or sample kept from the previous
+// preview.
+func csvSampleFromForm(r *http.Request) ([]byte, error) {
+	file, _, fErr := r.FormFile("sampleFile")
+	if fErr == nil {
+		defer file.Close()
+		var buf bytes.Buffer
+		if _, cErr := io.Copy(&buf, io.LimitReader(file, maxCsvSampleSize)); cErr != nil {
+			return nil, cErr
+		}
+		return firstLines(buf.Bytes(), csvSampleLines), nil
+	}
+	if !errors.Is(fErr, http.ErrMissingFile) {
+		return nil, fErr
+	}
+	return base64.StdEncoding.DecodeString(r.FormValue("sample"))
+}
+
+func firstLines(data []byte, lines int) []byte {
+	end := 0
+	for i := 0; i < lines; i++ {
+		next := bytes.IndexByte(data[end:], '\n')
+		if next == -1 {
+			return data
+		}
+		end = end + next + 1
+	}
+	return data[:end]
+}
+
+func formToCsvProfile(r *http.Request) (db.CsvProfile, error) {
+	profile := db.CsvProfile{
+		Name:               r.FormValue("name"),
+		Delimiter:          r.FormValue("delimiter"),
+		Encoding:           r.FormValue("encoding"),
+		HasHeader:          r.FormValue("hasHeader") != "",
+		DateLayout:         strings.TrimSpace(r.FormValue("dateLayout")),
+		DecimalComma:       r.FormValue("decimalComma") != "",
+		AmountSign:         r.FormValue("amountSign"),
+		DefaultCurrency:    strings.TrimSpace(r.FormValue("defaultCurrency")),
+		AccountNumber:      strings.TrimSpace(r.FormValue("accountNumber")),
+		DescriptionColumns: strings.TrimSpace(r.FormValue("descriptionColumns")),
+	}
+	var err error
+	numbers := []struct {
+		field string
+		value *int
+	}{
+		{"skipRows", &profile.SkipRows},
+		{"executionDateColumn", &profile.ExecutionDateColumn},
+		{"orderDateColumn", &profile.OrderDateColumn},
+		{"amountColumn", &profile.AmountColumn},
+		{"debitColumn", &profile.DebitColumn},
+		{"creditColumn", &profile.CreditColumn},
+		{"currencyColumn", &profile.CurrencyColumn},
+		{"typeColumn", &profile.TypeColumn},
+		{"endingBalanceColumn", &profile.EndingBalanceColumn},
+		{"accountColumn", &profile.AccountColumn},
+	}
+	for _, n := range numbers {
+		value := strings.TrimSpace(r.FormValue(n.field))
+		if value == "" {
+			continue
+		}
+		parsed, pErr := strconv.Atoi(value)
+		if pErr != nil && err == nil {
+			err = fmt.Errorf("[%s] is not a number", value)
+		}
+		*n.value = parsed
+	}
+	if idValue := r.FormValue("profileId"); idValue != "" {
+		profileId, pErr := strconv.ParseInt(idValue, 10, 64)
+		if pErr != nil && err == nil {
+			err = errors.New("incorrect profile ID")
+		}
+		profile.ProfileId = profileId
+	}
+	return profile, err
+}
+
+func csvProfileToMapping(p db.CsvProfile) (finance.CsvMapping, error) {
+	descriptionColumns := make([]int, 0)
+	for _, column := range strings.Split(p.DescriptionColumns, ",") {
+		if column = strings.TrimSpace(column); column == "" {
+			continue
+		}
+		number, pErr := strconv.Atoi(column)
+		if pErr != nil {
+			return finance.CsvMapping{}, fmt.Errorf("incorrect description column [%s]", column)
+		}
+		descriptionColumns = append(descriptionColumns, number)
+	}
+	return finance.CsvMapping{
+		Delimiter:           p.Delimiter,
+		Encoding:            p.Encoding,
+		SkipRows:            p.SkipRows,
+		HasHeader:           p.HasHeader,
+		DateLayout:          p.DateLayout,
+		DecimalComma:        p.DecimalComma,
+		AmountSign:          p.AmountSign,
+		DefaultCurrency:     p.DefaultCurrency,
+		AccountNumber:       p.AccountNumber,
+		ExecutionDateColumn: p.ExecutionDateColumn,
+		OrderDateColumn:     p.OrderDateColumn,
+		AmountColumn:        p.AmountColumn,
+		DebitColumn:         p.DebitColumn,
+		CreditColumn:        p.CreditColumn,
+		CurrencyColumn:      p.CurrencyColumn,
+		TypeColumn:          p.TypeColumn,
+		DescriptionColumns:  descriptionColumns,
+		EndingBalanceColumn: p.EndingBalanceColumn,
+		AccountColumn:       p.AccountColumn,
+	}, nil
+}
+
+// Loads saved CSV profile as transactions parser.
+func (f *Finance) csvParser(profileId int64) (finance.TransactionParser, error) {
+	profile, pErr := f.DbClient.CsvProfileById(profileId)
+	if errors.Is(pErr, sql.ErrNoRows) {
+		return nil, errors.New("CSV profile does not exist")
+	}
+	if pErr != nil {
+		return nil, pErr
+	}
+	mapping, mErr := csvProfileToMapping(profile)
+	if mErr != nil {
+		return nil, mErr
+	}
+	return finance.CsvParser{Mapping: mapping}, nil
+}
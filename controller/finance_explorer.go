@@ -83,6 +83,6 @@ func (fw *FinanceExplorer) FinanceExplorerViewHandler(w http.ResponseWriter, r *
 		MonthlyChartData: chartData,
 	}
 
-	tmpl := front.FinanceExplorer()
+	tmpl := front.FinanceExplorer(commonFromRequest(r))
 	tmpl.Execute(w, tmplData)
 }
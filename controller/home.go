@@ -40,7 +40,7 @@ type HomeSummary struct {
 }
 
 func (h *Home) HomeSummaryView(w http.ResponseWriter, r *http.Request) {
-	tmpl := front.Home()
+	tmpl := front.Home(commonFromRequest(r))
 
 	sessCookie, cookieErr := r.Cookie(auth.SessCookieName)
 	if cookieErr != nil {
@@ -2,7 +2,6 @@ package controller
 
 import (
 	"homeApp/auth"
-	"homeApp/auth/telegram"
 	"homeApp/db"
 	"homeApp/front"
 	"net/http"
@@ -13,19 +12,29 @@ import (
 const loginFormPrefix = "controller/loginForm"
 
 type LoginForm struct {
-	TelegramClient *telegram.Client
-	DbClient       *db.Client
-	AuthManager    auth.HandlerManager
-	AppVersion     string
-	CurrentHash    string
+	DbClient    *db.Client
+	AuthManager auth.HandlerManager
+	AppVersion  string
+	CurrentHash string
+}
+
+type LoginPage struct {
+	Error string
+}
+
+type TwoFactorForm struct {
+	Prompt        string
+	NeedsResponse bool
+	Failed        bool
+	StatusUrl     string
 }
 
 func (lf *LoginForm) LoginFormHandler(w http.ResponseWriter, r *http.Request) {
 	sessionCookieValid, err := lf.AuthManager.IsSessionCookieValid(r)
 	if err != nil || !sessionCookieValid {
 		log.Info().Msgf("[%s] no session cookie or invalid, rendering login form", loginFormPrefix)
-		tmpl := front.Login()
-		tmpl.Execute(w, lf)
+		tmpl := front.Login(commonFromRequest(r))
+		tmpl.Execute(w, LoginPage{Error: loginErrorMessage(r.URL.Query().Get("error"))})
 		return
 	}
 	homeController := Home{
@@ -36,3 +45,33 @@ func (lf *LoginForm) LoginFormHandler(w http.ResponseWriter, r *http.Request) {
 	}
 	homeController.HomeSummaryView(w, r)
 }
+
+// TwoFactorFormHandler renders second step of login for pending login. If
+// there is no pending login, user is redirected to login form.
+func (lf *LoginForm) TwoFactorFormHandler(w http.ResponseWriter, r *http.Request) {
+	challenge, exists := lf.AuthManager.PendingChallenge(r)
+	if !exists {
+		log.Info().Msgf("[%s] no pending login, redirecting to login form", loginFormPrefix)
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+
+	tmpl := front.Login2FA(commonFromRequest(r))
+	tmpl.Execute(w, TwoFactorForm{
+		Prompt:        challenge.Prompt,
+		NeedsResponse: challenge.NeedsResponse,
+		Failed:        r.URL.Query().Get("failed") != "",
+		StatusUrl:     "/login/2fa/status",
+	})
+}
+
+func loginErrorMessage(errorCode string) string {
+	switch errorCode {
+	case "invalid":
+		return "Invalid username or password"
+	case "blocked":
+		return "Too many failed login attempts. Please try again later"
+	default:
+		return ""
+	}
+}
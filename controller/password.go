@@ -0,0 +1,88 @@
+package controller
+
+import (
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/front"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const contrPasswordPrefix = "controller/password"
+
+type PasswordSettings struct {
+	DbClient     *db.Client
+	UserAuth     auth.UserAuthenticator
+	LoginLimiter *auth.LoginLimiter
+	Audit        *auth.AuthAudit
+}
+
+type PasswordPage struct {
+	Changed bool
+	Error   *string
+}
+
+// PasswordView renders password change form.
+func (ps *PasswordSettings) PasswordView(w http.ResponseWriter, r *http.Request) {
+	ps.render(w, r, r.URL.Query().Get("changed") == "1", nil)
+}
+
+// ChangeHandler changes password of current user. Incorrect current password
+// is treated as failed login attempt, so guessing it is throttled the same way
+// as login.
+func (ps *PasswordSettings) ChangeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/password", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+	client := auth.ClientInfoFromRequest(r)
+
+	user, uErr := ps.DbClient.UserByUserId(tokenStatus.UserId)
+	if uErr != nil {
+		displayError := "could not read user data"
+		ps.render(w, r, false, &displayError)
+		return
+	}
+	if blockedFor, blockErr := ps.LoginLimiter.Blocked(user.Username, client.IpAddress); blockErr != nil || blockedFor > 0 {
+		displayError := "too many failed attempts, try again later"
+		ps.render(w, r, false, &displayError)
+		return
+	}
+
+	newPassword := r.FormValue("newPass")
+	if newPassword != r.FormValue("newPassRepeat") {
+		displayError := "new passwords don't match"
+		ps.render(w, r, false, &displayError)
+		return
+	}
+
+	changeErr := ps.UserAuth.ChangePassword(tokenStatus, r.FormValue("pass"), newPassword)
+	if changeErr != nil {
+		log.Warn().Err(changeErr).Int("userId", tokenStatus.UserId).Msgf("[%s] password not changed", contrPasswordPrefix)
+		displayError := changeErr.Error()
+		if changeErr == auth.ErrInvalidUsernameOrPass {
+			ps.LoginLimiter.RegisterFailure(user.Username, client.IpAddress)
+			displayError = "incorrect current password"
+		}
+		ps.Audit.Record(auth.AuthEvent{Type: auth.EventPasswordChange, Result: auth.ResultFailure,
+			UserId: tokenStatus.UserId, Client: client, Details: displayError})
+		ps.render(w, r, false, &displayError)
+		return
+	}
+
+	ps.Audit.Record(auth.AuthEvent{Type: auth.EventPasswordChange, Result: auth.ResultSuccess,
+		UserId: tokenStatus.UserId, Client: client, Details: "other sessions revoked"})
+	http.Redirect(w, r, "/settings/password?changed=1", http.StatusSeeOther)
+}
+
+func (ps *PasswordSettings) render(w http.ResponseWriter, r *http.Request, isChanged bool, displayError *string) {
+	tmpl := front.Password(commonFromRequest(r))
+	execErr := tmpl.Execute(w, PasswordPage{Changed: isChanged, Error: displayError})
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render password view", contrPasswordPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
@@ -0,0 +1,70 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"homeApp/auth/telegram"
+
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	sendTelFilePrefix = "controller/sendTelegramFile"
+
+	// Results of sending file to Telegram. They're passed to the list page in
+	// the "telegram" URL parameter after redirect.
+	telegramFileSent     = "sent"
+	telegramFileTooLarge = "tooLarge"
+	telegramFileFailed   = "failed"
+)
+
+// Sends file onto the Telegram channel and returns the result which should be
+// passed to the list page.
+func sendFileToTelegram(tClient *telegram.Client, fileName string, content []byte, caption string) string {
+	if tClient == nil {
+		log.Warn().Msgf("[%s] Telegram is not configured, file will not be sent", sendTelFilePrefix)
+		return telegramFileFailed
+	}
+	sendErr := tClient.SendDocument(fileName, content, caption)
+	if errors.Is(sendErr, telegram.ErrFileTooLarge) {
+		return telegramFileTooLarge
+	}
+	if sendErr != nil {
+		log.Error().Err(sendErr).Str("fileName", fileName).
+			Msgf("[%s] sending file to Telegram failed", sendTelFilePrefix)
+		return telegramFileFailed
+	}
+	return telegramFileSent
+}
+
+// Checks size of stored file before it's loaded from the database, so files
+// which Telegram would reject aren't read into memory.
+func exceedsTelegramLimit(fileSize int64) bool {
+	if fileSize > telegram.MaxUploadSize {
+		log.Warn().Int64("size", fileSize).Msgf("[%s] file is too large to be sent", sendTelFilePrefix)
+		return true
+	}
+	return false
+}
+
+// Reads result of sending file to Telegram from URL parameter and returns
+// either info or error message to be displayed.
+func telegramFileMessages(r *http.Request) (*string, *string) {
+	var info, displayError string
+	switch r.URL.Query().Get("telegram") {
+	case telegramFileSent:
+		info = "File was sent to Telegram."
+		return &info, nil
+	case telegramFileTooLarge:
+		displayError = fmt.Sprintf("file is larger than %d MB and cannot be sent to Telegram",
+			telegram.MaxUploadSize/1000000)
+		return nil, &displayError
+	case telegramFileFailed:
+		displayError = "sending file to Telegram failed"
+		return nil, &displayError
+	default:
+		return nil, nil
+	}
+}
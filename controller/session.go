@@ -2,6 +2,8 @@ package controller
 
 import (
 	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/front"
 	"net/http"
 	"time"
 
@@ -11,7 +13,24 @@ import (
 const contrSessPrefix = "controller/session"
 
 type Session struct {
-	UserAuth auth.UserAuthenticator
+	UserAuth      auth.UserAuthenticator
+	DbClient      *db.Client
+	Audit         *auth.AuthAudit
+	SecureCookies bool
+}
+
+type ActiveSessions struct {
+	Sessions []ActiveSession
+	Error    *string
+}
+
+type ActiveSession struct {
+	SessionId  string
+	UserAgent  string
+	IpAddress  string
+	CreateTs   string
+	LastSeenTs string
+	IsCurrent  bool
 }
 
 // ProlongHandler handles user session prolongation based on current user JWT.
@@ -38,7 +57,7 @@ func (s *Session) ProlongHandler(w http.ResponseWriter, r *http.Request) {
 	if !tokenStatus.IsValid {
 		log.Error().Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] session expired or credentials are incorrect, redirecting to login", contrSessPrefix)
-		http.SetCookie(w, expiredSessionCookie())
+		http.SetCookie(w, auth.ExpiredSessionCookie(s.SecureCookies))
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
@@ -47,30 +66,105 @@ func (s *Session) ProlongHandler(w http.ResponseWriter, r *http.Request) {
 	if regErr != nil {
 		log.Error().Err(regErr).Int("userId", tokenStatus.UserId).Dur("duration", time.Since(startTs)).
 			Msgf("[%s] could not regenerate new JWT", contrSessPrefix)
+		s.Audit.Record(auth.AuthEvent{Type: auth.EventSessionProlong, Result: auth.ResultFailure,
+			UserId: tokenStatus.UserId, Client: auth.ClientInfoFromRequest(r), Details: regErr.Error()})
 		http.Redirect(w, r, "/", http.StatusSeeOther)
 		return
 	}
 
-	regSessCookie := &http.Cookie{
-		Name:     auth.SessCookieName,
-		Path:     "/",
-		Value:    regeneratedJwt,
-		Expires:  time.Now().Add(auth.SessCookieExpMinutes * time.Minute),
-		HttpOnly: true,
-	}
-	http.SetCookie(w, regSessCookie)
+	http.SetCookie(w, auth.SessionCookie(regeneratedJwt, s.SecureCookies))
+	s.Audit.Record(auth.AuthEvent{Type: auth.EventSessionProlong, Result: auth.ResultSuccess,
+		UserId: tokenStatus.UserId, Client: auth.ClientInfoFromRequest(r)})
 
 	log.Info().Dur("duration", time.Since(startTs)).Msgf("[%s] user session prolonged", contrSessPrefix)
 	http.Redirect(w, r, r.Header.Get("Referer"), http.StatusSeeOther)
 }
 
-// Setting cookie with the same name and expiring time stamp in the past is
-// equivalent of deleting the cookie.
-func expiredSessionCookie() *http.Cookie {
-	return &http.Cookie{
-		Name:     auth.SessCookieName,
-		Value:    "",
-		Expires:  time.Unix(0, 0),
-		HttpOnly: true,
+// ActiveSessionsView renders list of active sessions of current user.
+func (s *Session) ActiveSessionsView(w http.ResponseWriter, r *http.Request) {
+	tmpl := front.Sessions(commonFromRequest(r))
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	sessions, dbErr := s.DbClient.SessionsActive(tokenStatus.UserId, nowTs)
+	if dbErr != nil {
+		log.Error().Err(dbErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot load active sessions", contrSessPrefix)
+		displayError := "could not read active sessions from database"
+		tmpl.Execute(w, ActiveSessions{Error: &displayError})
+		return
+	}
+
+	activeSessions := make([]ActiveSession, len(sessions))
+	for idx, sess := range sessions {
+		activeSessions[idx] = ActiveSession{
+			SessionId:  sess.SessionId,
+			UserAgent:  sess.UserAgent,
+			IpAddress:  sess.IpAddress,
+			CreateTs:   sess.CreateTs,
+			LastSeenTs: sess.LastSeenTs,
+			IsCurrent:  sess.SessionId == tokenStatus.SessionId,
+		}
+	}
+
+	execErr := tmpl.Execute(w, ActiveSessions{Sessions: activeSessions})
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render active sessions view", contrSessPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
+
+// RevokeHandler revokes single session of current user ("log out this
+// device"). If it's the current session, then user is redirected to login.
+func (s *Session) RevokeHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+	sessionId := r.FormValue("sessionId")
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	revokeErr := s.DbClient.SessionRevoke(tokenStatus.UserId, sessionId, nowTs)
+	if revokeErr != nil {
+		log.Error().Err(revokeErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot revoke session", contrSessPrefix)
+		http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+		return
+	}
+	s.Audit.Record(auth.AuthEvent{Type: auth.EventLogout, Result: auth.ResultSuccess, UserId: tokenStatus.UserId,
+		Client: auth.ClientInfoFromRequest(r), Details: "session revoked from sessions page"})
+
+	if sessionId == tokenStatus.SessionId {
+		http.SetCookie(w, auth.ExpiredSessionCookie(s.SecureCookies))
+		http.Redirect(w, r, "/", http.StatusSeeOther)
+		return
+	}
+	http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+}
+
+// RevokeAllHandler revokes all sessions of current user, including the
+// current one ("log out everywhere").
+func (s *Session) RevokeAllHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+		return
 	}
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	nowTs := time.Now().UTC().Format(db.TimestampFormat)
+	revokeErr := s.DbClient.SessionsRevokeAll(tokenStatus.UserId, nowTs, "")
+	if revokeErr != nil {
+		log.Error().Err(revokeErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot revoke all sessions", contrSessPrefix)
+		http.Redirect(w, r, "/sessions", http.StatusSeeOther)
+		return
+	}
+
+	s.Audit.Record(auth.AuthEvent{Type: auth.EventLogout, Result: auth.ResultSuccess, UserId: tokenStatus.UserId,
+		Client: auth.ClientInfoFromRequest(r), Details: "logged out everywhere"})
+	log.Info().Int("userId", tokenStatus.UserId).Msgf("[%s] user logged out everywhere", contrSessPrefix)
+	http.SetCookie(w, auth.ExpiredSessionCookie(s.SecureCookies))
+	http.Redirect(w, r, "/", http.StatusSeeOther)
 }
@@ -0,0 +1,171 @@
+package controller
+
+import (
+	"errors"
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/front"
+	"html/template"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const contrTwoFactorPrefix = "controller/twoFactor"
+
+type TwoFactorSettings struct {
+	UserAuth     auth.UserAuthenticator
+	TwoFactor    *auth.TwoFactor
+	DbClient     *db.Client
+	LoginLimiter *auth.LoginLimiter
+}
+
+type TwoFactorSettingsPage struct {
+	Status        auth.TwoFactorStatus
+	RecoveryCodes []string
+	TotpUri       template.URL // otpauth scheme would be filtered out as unsafe
+	Error         *string
+}
+
+// TwoFactorView renders second factor settings of current user.
+func (tfs *TwoFactorSettings) TwoFactorView(w http.ResponseWriter, r *http.Request) {
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+	tfs.render(w, r, tokenStatus.UserId, nil, nil)
+}
+
+// SetMethodHandler changes second factor method of current user to one which
+// doesn't require enrollment. Current password is required.
+func (tfs *TwoFactorSettings) SetMethodHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	user, uErr := tfs.DbClient.UserByUserId(tokenStatus.UserId)
+	if uErr != nil {
+		displayError := "could not read user data"
+		tfs.render(w, r, tokenStatus.UserId, nil, &displayError)
+		return
+	}
+	if pErr := tfs.checkPassword(r, user); pErr != nil {
+		displayError := pErr.Error()
+		tfs.render(w, r, user.UserId, nil, &displayError)
+		return
+	}
+
+	setErr := tfs.TwoFactor.SetMethod(user.UserId, r.FormValue("method"))
+	if setErr != nil {
+		log.Error().Err(setErr).Int("userId", user.UserId).Msgf("[%s] cannot change 2FA method", contrTwoFactorPrefix)
+		displayError := setErr.Error()
+		tfs.render(w, r, user.UserId, nil, &displayError)
+		return
+	}
+	http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+}
+
+// TotpStartHandler generates new TOTP secret for current user. It has to be
+// confirmed with a code from authenticator app. Current password is required,
+// so session alone isn't enough to replace user's second factor.
+func (tfs *TwoFactorSettings) TotpStartHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	user, uErr := tfs.DbClient.UserByUserId(tokenStatus.UserId)
+	if uErr != nil {
+		displayError := "could not read user data"
+		tfs.render(w, r, tokenStatus.UserId, nil, &displayError)
+		return
+	}
+	if pErr := tfs.checkPassword(r, user); pErr != nil {
+		displayError := pErr.Error()
+		tfs.render(w, r, user.UserId, nil, &displayError)
+		return
+	}
+
+	_, startErr := tfs.TwoFactor.StartTotpEnrollment(user)
+	if startErr != nil {
+		log.Error().Err(startErr).Int("userId", user.UserId).
+			Msgf("[%s] cannot start TOTP enrollment", contrTwoFactorPrefix)
+		displayError := startErr.Error()
+		tfs.render(w, r, user.UserId, nil, &displayError)
+		return
+	}
+	http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+}
+
+// TotpConfirmHandler confirms TOTP enrollment using code from authenticator
+// app. Recovery codes are rendered only once, right after confirmation.
+func (tfs *TwoFactorSettings) TotpConfirmHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Redirect(w, r, "/settings/2fa", http.StatusSeeOther)
+		return
+	}
+	r.ParseForm()
+	tokenStatus, _ := auth.TokenStatusFromRequest(r)
+
+	recoveryCodes, confErr := tfs.TwoFactor.ConfirmTotpEnrollment(tokenStatus.UserId, r.FormValue("code"))
+	if confErr != nil {
+		log.Error().Err(confErr).Int("userId", tokenStatus.UserId).
+			Msgf("[%s] cannot confirm TOTP enrollment", contrTwoFactorPrefix)
+		displayError := confErr.Error()
+		tfs.render(w, r, tokenStatus.UserId, nil, &displayError)
+		return
+	}
+	tfs.render(w, r, tokenStatus.UserId, recoveryCodes, nil)
+}
+
+// Checks current password given in the form. Incorrect password is treated as
+// failed login attempt, so guessing it is throttled the same way as login.
+func (tfs *TwoFactorSettings) checkPassword(r *http.Request, user db.User) error {
+	client := auth.ClientInfoFromRequest(r)
+	if blockedFor, blockErr := tfs.LoginLimiter.Blocked(user.Username, client.IpAddress); blockErr != nil || blockedFor > 0 {
+		return errors.New("too many failed attempts, try again later")
+	}
+	if _, authErr := tfs.UserAuth.IsUserValid(user.Username, r.FormValue("pass")); authErr != nil {
+		log.Warn().Int("userId", user.UserId).Msgf("[%s] incorrect password on 2FA settings change", contrTwoFactorPrefix)
+		if authErr == auth.ErrInvalidUsernameOrPass {
+			tfs.LoginLimiter.RegisterFailure(user.Username, client.IpAddress)
+		}
+		return errors.New("incorrect password")
+	}
+	return nil
+}
+
+func (tfs *TwoFactorSettings) render(w http.ResponseWriter, r *http.Request, userId int, recoveryCodes []string,
+	displayError *string) {
+	tmpl := front.TwoFactor(commonFromRequest(r))
+	page := TwoFactorSettingsPage{RecoveryCodes: recoveryCodes, Error: displayError}
+
+	user, uErr := tfs.DbClient.UserByUserId(userId)
+	if uErr != nil {
+		errMsg := "could not read user data"
+		page.Error = &errMsg
+		tmpl.Execute(w, page)
+		return
+	}
+
+	status, sErr := tfs.TwoFactor.Status(user)
+	if sErr != nil {
+		log.Error().Err(sErr).Int("userId", userId).Msgf("[%s] cannot read 2FA settings", contrTwoFactorPrefix)
+		errMsg := "could not read 2FA settings"
+		page.Error = &errMsg
+		tmpl.Execute(w, page)
+		return
+	}
+	page.Status = status
+	if status.TotpPending != nil {
+		page.TotpUri = template.URL(status.TotpPending.Uri)
+	}
+
+	execErr := tmpl.Execute(w, page)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot render 2FA settings view", contrTwoFactorPrefix)
+		http.Redirect(w, r, "/home", http.StatusSeeOther)
+	}
+}
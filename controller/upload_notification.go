@@ -0,0 +1,45 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"homeApp/auth"
+	"homeApp/db"
+	"homeApp/notify"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+)
+
+const notifyUserPrefix = "controller/notify"
+
+// Prepares upload notification stating currently authenticated user (either
+// by session or API token). It should be passed to the DB insert method, so
+// it's stored in the outbox within the same transaction as uploaded data. Nil
+// is returned when user cannot be determined - data is uploaded without
+// notification then.
+func uploadNotification(r *http.Request, dbClient *db.Client, msg string) *db.NewOutboxMessage {
+	user, uErr := userForRequest(r, dbClient)
+	if uErr != nil {
+		log.Error().Err(uErr).Msgf("[%s] cannot prepare upload notification", notifyUserPrefix)
+		return nil
+	}
+	return &db.NewOutboxMessage{
+		Event: notify.EventUpload,
+		Text:  fmt.Sprintf("[Info] [%s] %s", user.Username, msg),
+	}
+}
+
+// Reads user authenticated by CheckAuth middleware.
+func userForRequest(r *http.Request, dbClient *db.Client) (db.User, error) {
+	tokenStatus, ok := auth.TokenStatusFromRequest(r)
+	if !ok || !tokenStatus.IsValid {
+		return db.User{}, errors.New("request is not authenticated")
+	}
+
+	user, uErr := dbClient.UserByUserId(tokenStatus.UserId)
+	if uErr != nil {
+		return db.User{}, fmt.Errorf("getting user info failed: %v", uErr)
+	}
+	return user, nil
+}
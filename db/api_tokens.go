@@ -0,0 +1,170 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbApiTokensPrefix = "db/apiTokens"
+
+// ApiToken represents personal API token of a user. Only hash of the token
+// is stored.
+type ApiToken struct {
+	TokenId    string
+	UserId     int
+	Name       string
+	TokenHash  string
+	Scopes     string
+	CreateTs   string
+	LastUsedTs *string
+	RevokeTs   *string
+}
+
+// ApiTokenInsertNew inserts new API token.
+func (c *Client) ApiTokenInsertNew(t ApiToken) error {
+	_, execErr := c.dbConn.Exec(apiTokenInsertQuery(), t.TokenId, t.UserId, t.Name, t.TokenHash, t.Scopes,
+		t.CreateTs)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", t.UserId).Msgf("[%s] cannot insert new API token", dbApiTokensPrefix)
+	}
+	return execErr
+}
+
+// ApiTokenByHash reads not revoked API token of given hash. If token does not
+// exist sql.ErrNoRows is returned.
+func (c *Client) ApiTokenByHash(tokenHash string) (ApiToken, error) {
+	var t ApiToken
+	row := c.dbConn.QueryRow(apiTokenByHashQuery(), tokenHash)
+	scanErr := row.Scan(&t.TokenId, &t.UserId, &t.Name, &t.TokenHash, &t.Scopes, &t.CreateTs, &t.LastUsedTs,
+		&t.RevokeTs)
+
+	switch scanErr {
+	case nil:
+		return t, nil
+	case sql.ErrNoRows:
+		log.Warn().Msgf("[%s] API token does not exist or is revoked", dbApiTokensPrefix)
+		return ApiToken{}, scanErr
+	default:
+		log.Error().Err(scanErr).Msgf("[%s] cannot read API token", dbApiTokensPrefix)
+		return ApiToken{}, scanErr
+	}
+}
+
+// ApiTokensActive reads not revoked API tokens of given user.
+func (c *Client) ApiTokensActive(userId int) ([]ApiToken, error) {
+	tokens := make([]ApiToken, 0, 5)
+	rows, qErr := c.dbConn.Query(apiTokensActiveQuery(), userId)
+	if qErr != nil {
+		log.Error().Err(qErr).Int("userId", userId).Msgf("[%s] apiTokensActiveQuery failed", dbApiTokensPrefix)
+		return tokens, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t ApiToken
+		sErr := rows.Scan(&t.TokenId, &t.UserId, &t.Name, &t.TokenHash, &t.Scopes, &t.CreateTs, &t.LastUsedTs,
+			&t.RevokeTs)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of apiTokensActiveQuery", dbApiTokensPrefix)
+			continue
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, nil
+}
+
+// ApiTokenUpdateLastUsed sets API token last used timestamp.
+func (c *Client) ApiTokenUpdateLastUsed(tokenId, lastUsedTs string) error {
+	_, execErr := c.dbConn.Exec(apiTokenUpdateLastUsedQuery(), lastUsedTs, tokenId)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot update API token last used timestamp", dbApiTokensPrefix)
+	}
+	return execErr
+}
+
+// ApiTokenRevoke revokes API token of given user. Returns sql.ErrNoRows if
+// there is no such active token.
+func (c *Client) ApiTokenRevoke(userId int, tokenId, revokeTs string) error {
+	res, execErr := c.dbConn.Exec(apiTokenRevokeQuery(), revokeTs, userId, tokenId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot revoke API token", dbApiTokensPrefix)
+		return execErr
+	}
+	if revoked, _ := res.RowsAffected(); revoked == 0 {
+		return sql.ErrNoRows
+	}
+	log.Info().Int("userId", userId).Str("tokenId", tokenId).Msgf("[%s] API token revoked", dbApiTokensPrefix)
+	return nil
+}
+
+func apiTokenInsertQuery() string {
+	return `
+		INSERT INTO apiTokens (TokenId, UserId, Name, TokenHash, Scopes, CreateTs)
+		VALUES (?, ?, ?, ?, ?, ?)
+	`
+}
+
+func apiTokenByHashQuery() string {
+	return `
+		SELECT
+			TokenId,
+			UserId,
+			Name,
+			TokenHash,
+			Scopes,
+			CreateTs,
+			LastUsedTs,
+			RevokeTs
+		FROM
+			apiTokens
+		WHERE
+			TokenHash = ?
+			AND RevokeTs IS NULL
+	`
+}
+
+func apiTokensActiveQuery() string {
+	return `
+		SELECT
+			TokenId,
+			UserId,
+			Name,
+			TokenHash,
+			Scopes,
+			CreateTs,
+			LastUsedTs,
+			RevokeTs
+		FROM
+			apiTokens
+		WHERE
+			UserId = ?
+			AND RevokeTs IS NULL
+		ORDER BY
+			CreateTs DESC
+	`
+}
+
+func apiTokenUpdateLastUsedQuery() string {
+	return `
+		UPDATE
+			apiTokens
+		SET
+			LastUsedTs = ?
+		WHERE
+			TokenId = ?
+	`
+}
+
+func apiTokenRevokeQuery() string {
+	return `
+		UPDATE
+			apiTokens
+		SET
+			RevokeTs = ?
+		WHERE
+			UserId = ?
+			AND TokenId = ?
+			AND RevokeTs IS NULL
+	`
+}
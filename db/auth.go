@@ -94,6 +94,167 @@ func (c *Client) UserByUserId(userId int) (User, error) {
 	return user, nil
 }
 
+// UserUpdatePassword sets new hashed password and salt for given user.
+func (c *Client) UserUpdatePassword(userId int, passwordHashed, salt string) error {
+	log.Info().Int("userId", userId).Msgf("[%s] start updating user password", dbAuthPerfix)
+	startTs := time.Now()
+
+	res, execErr := c.dbConn.Exec(userUpdatePasswordQuery(), passwordHashed, salt, userId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot update user password", dbAuthPerfix)
+		return execErr
+	}
+	rowsAffected, raErr := res.RowsAffected()
+	if raErr == nil && rowsAffected == 0 {
+		log.Error().Int("userId", userId).Msgf("[%s] user does not exist, password not updated", dbAuthPerfix)
+		return sql.ErrNoRows
+	}
+
+	log.Info().Int("userId", userId).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished updating user password", dbAuthPerfix)
+	return nil
+}
+
+// UsersAll reads all users ordered by UserId.
+func (c *Client) UsersAll() ([]User, error) {
+	startTs := time.Now()
+	users := make([]User, 0, 10)
+	log.Info().Msgf("[%s] start reading all users", dbAuthPerfix)
+
+	rows, qErr := c.dbConn.Query(usersAllQuery())
+	if qErr != nil {
+		log.Error().Err(qErr).Msgf("[%s] usersAllQuery failed", dbAuthPerfix)
+		return users, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var u User
+		var isActive int
+		sErr := rows.Scan(&u.UserId, &u.Email, &u.Username, &u.PasswordHashed, &u.Salt, &isActive, &u.CreateDate)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of usersAllQuery", dbAuthPerfix)
+			continue
+		}
+		u.IsActive = isActive == 1
+		users = append(users, u)
+	}
+
+	log.Info().Int("rowsLoaded", len(users)).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished reading all users", dbAuthPerfix)
+	return users, nil
+}
+
+// UserInsertNew inserts new active user with next available UserId and given
+// roles. New UserId is returned.
+func (c *Client) UserInsertNew(u User, roles []string) (int, error) {
+	startTs := time.Now()
+	log.Info().Str("username", u.Username).Msgf("[%s] start inserting new user", dbAuthPerfix)
+
+	tx, tErr := c.dbConn.Begin()
+	if tErr != nil {
+		log.Error().Err(tErr).Msgf("[%s] cannot start new transaction", dbAuthPerfix)
+		return 0, tErr
+	}
+
+	var userId int
+	row := tx.QueryRow(userInsertQuery(), u.Email, u.Username, u.PasswordHashed, u.Salt)
+	if iErr := row.Scan(&userId); iErr != nil {
+		log.Error().Err(iErr).Str("username", u.Username).Msgf("[%s] cannot insert new user", dbAuthPerfix)
+		tx.Rollback()
+		return 0, iErr
+	}
+
+	for _, role := range roles {
+		_, rErr := tx.Exec(userRoleInsertQuery(), userId, role)
+		if rErr != nil {
+			log.Error().Err(rErr).Str("username", u.Username).Msgf("[%s] cannot insert user role", dbAuthPerfix)
+			tx.Rollback()
+			return 0, rErr
+		}
+	}
+
+	commErr := tx.Commit()
+	if commErr != nil {
+		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbAuthPerfix)
+		tx.Rollback()
+		return 0, commErr
+	}
+
+	log.Info().Str("username", u.Username).Int("userId", userId).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished inserting new user", dbAuthPerfix)
+	return userId, nil
+}
+
+// UserSetActive enables or disables given user. When user is disabled, all
+// user's sessions are revoked within the same transaction.
+func (c *Client) UserSetActive(userId int, isActive bool, nowTs string) error {
+	startTs := time.Now()
+	log.Info().Int("userId", userId).Bool("isActive", isActive).Msgf("[%s] start setting user active", dbAuthPerfix)
+
+	tx, tErr := c.dbConn.Begin()
+	if tErr != nil {
+		log.Error().Err(tErr).Msgf("[%s] cannot start new transaction", dbAuthPerfix)
+		return tErr
+	}
+
+	isActiveInt := 0
+	if isActive {
+		isActiveInt = 1
+	}
+	res, uErr := tx.Exec(userSetActiveQuery(), isActiveInt, userId)
+	if uErr != nil {
+		log.Error().Err(uErr).Int("userId", userId).Msgf("[%s] cannot set user active", dbAuthPerfix)
+		tx.Rollback()
+		return uErr
+	}
+	if rowsAffected, _ := res.RowsAffected(); rowsAffected == 0 {
+		tx.Rollback()
+		return sql.ErrNoRows
+	}
+
+	if !isActive {
+		_, rErr := tx.Exec(sessionsRevokeAllQuery(), nowTs, userId, "")
+		if rErr != nil {
+			log.Error().Err(rErr).Int("userId", userId).Msgf("[%s] cannot revoke user sessions", dbAuthPerfix)
+			tx.Rollback()
+			return rErr
+		}
+	}
+
+	commErr := tx.Commit()
+	if commErr != nil {
+		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbAuthPerfix)
+		tx.Rollback()
+		return commErr
+	}
+
+	log.Info().Int("userId", userId).Bool("isActive", isActive).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished setting user active", dbAuthPerfix)
+	return nil
+}
+
+// UserRoles reads roles of given user.
+func (c *Client) UserRoles(userId int) ([]string, error) {
+	roles := make([]string, 0, 2)
+	rows, qErr := c.dbConn.Query(userRolesQuery(), userId)
+	if qErr != nil {
+		log.Error().Err(qErr).Int("userId", userId).Msgf("[%s] userRolesQuery failed", dbAuthPerfix)
+		return roles, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var role string
+		if sErr := rows.Scan(&role); sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of userRolesQuery", dbAuthPerfix)
+			continue
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
 func userQuery() string {
 	return `
 		SELECT
@@ -127,3 +288,75 @@ func userIdQuery() string {
 		;
 	`
 }
+
+func userUpdatePasswordQuery() string {
+	return `
+		UPDATE
+			users
+		SET
+			PasswordHashed = ?,
+			Salt = ?
+		WHERE
+			UserId = ?
+		;
+	`
+}
+
+func usersAllQuery() string {
+	return `
+		SELECT
+			UserId,
+			Email,
+			Username,
+			PasswordHashed,
+			Salt,
+			IsActive,
+			CreateDate
+		FROM
+			users
+		ORDER BY
+			UserId
+	`
+}
+
+func userInsertQuery() string {
+	return `
+		INSERT INTO users (UserId, Email, Username, PasswordHashed, Salt)
+		SELECT
+			COALESCE(MAX(UserId), 0) + 1, ?, ?, ?, ?
+		FROM
+			users
+		RETURNING UserId
+	`
+}
+
+func userSetActiveQuery() string {
+	return `
+		UPDATE
+			users
+		SET
+			IsActive = ?
+		WHERE
+			UserId = ?
+	`
+}
+
+func userRoleInsertQuery() string {
+	return `
+		INSERT INTO userRoles (UserId, Role)
+		VALUES (?, ?)
+	`
+}
+
+func userRolesQuery() string {
+	return `
+		SELECT
+			Role
+		FROM
+			userRoles
+		WHERE
+			UserId = ?
+		ORDER BY
+			Role
+	`
+}
@@ -0,0 +1,131 @@
+package db
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+const dbAuthEventsPrefix = "db/authEvents"
+
+// AuthEvent represents single authentication event in the audit log.
+type AuthEvent struct {
+	EventId   int64
+	Ts        string
+	EventType string
+	Result    string
+	UserId    *int
+	Username  string
+	IpAddress string
+	UserAgent string
+	Details   string
+}
+
+// AuthEventsFilter narrows down authentication events. Empty fields are not
+// used for filtering.
+type AuthEventsFilter struct {
+	Username  string
+	EventType string
+	Result    string
+	IpAddress string
+	FromTs    string
+	ToTs      string
+	Limit     int
+}
+
+// AuthEventInsert inserts new authentication event.
+func (c *Client) AuthEventInsert(e AuthEvent) error {
+	_, execErr := c.dbConn.Exec(authEventInsertQuery(), e.Ts, e.EventType, e.Result, e.UserId, e.Username,
+		e.IpAddress, e.UserAgent, e.Details)
+	if execErr != nil {
+		log.Error().Err(execErr).Str("eventType", e.EventType).Str("username", e.Username).
+			Msgf("[%s] cannot insert auth event", dbAuthEventsPrefix)
+	}
+	return execErr
+}
+
+// AuthEvents reads the latest authentication events matching the filter.
+func (c *Client) AuthEvents(f AuthEventsFilter) ([]AuthEvent, error) {
+	events := make([]AuthEvent, 0, f.Limit)
+	rows, qErr := c.dbConn.Query(authEventsQuery(), f.Username, f.Username, f.EventType, f.EventType, f.Result,
+		f.Result, f.IpAddress, f.IpAddress, f.FromTs, f.FromTs, f.ToTs, f.ToTs, f.Limit)
+	if qErr != nil {
+		log.Error().Err(qErr).Msgf("[%s] authEventsQuery failed", dbAuthEventsPrefix)
+		return events, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var e AuthEvent
+		sErr := rows.Scan(&e.EventId, &e.Ts, &e.EventType, &e.Result, &e.UserId, &e.Username, &e.IpAddress,
+			&e.UserAgent, &e.Details)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of authEventsQuery", dbAuthEventsPrefix)
+			continue
+		}
+		events = append(events, e)
+	}
+	return events, nil
+}
+
+// AuthEventExists checks whenever user has at least one event of given type
+// and result from given IP address.
+func (c *Client) AuthEventExists(userId int, eventType, result, ipAddress string) (bool, error) {
+	var exists bool
+	row := c.dbConn.QueryRow(authEventExistsQuery(), userId, eventType, result, ipAddress)
+	if scanErr := row.Scan(&exists); scanErr != nil {
+		log.Error().Err(scanErr).Int("userId", userId).Msgf("[%s] authEventExistsQuery failed", dbAuthEventsPrefix)
+		return false, scanErr
+	}
+	return exists, nil
+}
+
+func authEventInsertQuery() string {
+	return `
+		INSERT INTO authEvents (
+			Ts, EventType, Result, UserId, Username, IpAddress, UserAgent, Details
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+}
+
+func authEventsQuery() string {
+	return `
+		SELECT
+			EventId,
+			Ts,
+			EventType,
+			Result,
+			UserId,
+			Username,
+			IpAddress,
+			UserAgent,
+			Details
+		FROM
+			authEvents
+		WHERE
+			(? = '' OR Username = ?)
+			AND (? = '' OR EventType = ?)
+			AND (? = '' OR Result = ?)
+			AND (? = '' OR IpAddress = ?)
+			AND (? = '' OR Ts >= ?)
+			AND (? = '' OR Ts <= ?)
+		ORDER BY
+			EventId DESC
+		LIMIT ?
+	`
+}
+
+func authEventExistsQuery() string {
+	return `
+		SELECT EXISTS (
+			SELECT
+				1
+			FROM
+				authEvents
+			WHERE
+				UserId = ?
+				AND EventType = ?
+				AND Result = ?
+				AND IpAddress = ?
+		)
+	`
+}
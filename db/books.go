@@ -123,10 +123,41 @@ func (c *Client) BooksFiltered(phrase string) ([]Book, error) {
 	return books, nil
 }
 
+// BooksUploadedBetween reads metadata of books uploaded between given dates
+// (YYYY-MM-DD), including fromDate and excluding toDate.
+func (c *Client) BooksUploadedBetween(fromDate, toDate string) ([]Book, error) {
+	startTs := time.Now()
+	books := make([]Book, 0)
+
+	rows, qErr := c.dbConn.Query(booksUploadedBetweenQuery(), fromDate, toDate)
+	if qErr != nil {
+		log.Error().Err(qErr).Str("from", fromDate).Str("to", toDate).
+			Msgf("[%s] booksUploadedBetweenQuery failed", dbBooksPrefix)
+		return books, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var b Book
+		sErr := rows.Scan(&b.Id, &b.Title, &b.Authors, &b.Publisher, &b.PublishingYear, &b.Category,
+			&b.Language, &b.FileExtension, &b.FileSize, &b.UploadDate)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of booksUploadedBetweenQuery", dbBooksPrefix)
+			continue
+		}
+		books = append(books, b)
+	}
+	log.Info().Int("rowsLoaded", len(books)).Str("from", fromDate).Str("to", toDate).
+		Dur("duration", time.Since(startTs)).Msgf("[%s] finished reading books uploaded between dates", dbBooksPrefix)
+
+	return books, nil
+}
+
 // BookInsertNew uploads into database information about new book. This
 // is composed of two things - metadata about book and possibly (if e-book with
-// provided file) the content.
-func (c *Client) BookInsertNew(newBook NewBook) error {
+// provided file) the content. Notification (if not nil) is stored in the
+// outbox within the same transaction.
+func (c *Client) BookInsertNew(newBook NewBook, notification *NewOutboxMessage) error {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start inserting new book", dbBooksPrefix)
 
@@ -163,6 +194,13 @@ func (c *Client) BookInsertNew(newBook NewBook) error {
 		}
 	}
 
+	outboxErr := insertOutboxMessage(notification, tx)
+	if outboxErr != nil {
+		log.Error().Err(outboxErr).Msgf("[%s] cannot insert notification into outbox, rollback.", dbBooksPrefix)
+		tx.Rollback()
+		return outboxErr
+	}
+
 	commErr := tx.Commit()
 	if commErr != nil {
 		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbBooksPrefix)
@@ -198,6 +236,19 @@ func (c *Client) BookFile(bookId int) ([]byte, string, string, error) {
 	return fileBytes, title, fileExt, nil
 }
 
+// BookFileSize reads size in bytes of stored e-book file, without loading its
+// content. If book does not exist sql.ErrNoRows is returned.
+func (c *Client) BookFileSize(bookId int) (int64, error) {
+	var fileSize int64
+	scanErr := c.dbConn.QueryRow(bookFileSizeQuery(), bookId).Scan(&fileSize)
+	if scanErr != nil {
+		log.Error().Err(scanErr).Int("bookId", bookId).
+			Msgf("[%s] while reading book file size", dbBooksPrefix)
+		return 0, scanErr
+	}
+	return fileSize, nil
+}
+
 func getMaxBookId(tx *sql.Tx) (int, error) {
 	var maxBookId int
 	row := tx.QueryRow("SELECT CASE WHEN MAX(BookId) IS NULL THEN 0 ELSE MAX(BookId) END FROM books")
@@ -287,6 +338,29 @@ func booksQuery() string {
 	`
 }
 
+func booksUploadedBetweenQuery() string {
+	return `
+		SELECT
+			BookId,
+			Title,
+			Authors,
+			Publisher,
+			PublishingYear,
+			Category,
+			Language,
+			FileExtension,
+			FileSize,
+			UploadDate
+		FROM
+			books
+		WHERE
+			UploadDate >= ?
+			AND UploadDate < ?
+		ORDER BY
+			BookId
+	`
+}
+
 func booksFilteredQuery() string {
 	return `
 		SELECT
@@ -352,3 +426,18 @@ func bookFileQuery() string {
 			b.BookId = ?
 	`
 }
+
+// FileSize is optional in books, then length of the blob is used (SQLite
+// doesn't read blob content to compute its length).
+func bookFileSizeQuery() string {
+	return `
+		SELECT
+			COALESCE(b.FileSize, LENGTH(bf.FileBytes), 0)
+		FROM
+			books b
+		LEFT JOIN
+			bookFiles bf ON b.BookId = bf.BookId
+		WHERE
+			b.BookId = ?
+	`
+}
@@ -2,6 +2,10 @@ package db
 
 import "database/sql"
 
+// TimestampFormat is used for storing timestamps (always in UTC) as TEXT
+// columns. It's consistent with SQLite DATETIME function.
+const TimestampFormat = "2006-01-02 15:04:05"
+
 func toNullString(x *string) sql.NullString {
 	if x == nil {
 		return sql.NullString{Valid: false}
@@ -89,9 +89,10 @@ func (c *Client) EnergyData(page, pageSize int) ([]EnergyCounterEntry, error) {
 	return energyData, nil
 }
 
-// CountersInsertNew inserts new counter data into database. Both counters are
-// bundled into SQL transaction.
-func (c *Client) CountersInsertNew(water WaterCounterEntry, energy EnergyCounterEntry) error {
+// CountersInsertNew inserts new counter data into database. Both counters and
+// notification (if not nil) are bundled into SQL transaction.
+func (c *Client) CountersInsertNew(water WaterCounterEntry, energy EnergyCounterEntry,
+	notification *NewOutboxMessage) error {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start inserting counters data", dbCounterPerfix)
 
@@ -121,6 +122,16 @@ func (c *Client) CountersInsertNew(water WaterCounterEntry, energy EnergyCounter
 		return eErr
 	}
 
+	outboxErr := insertOutboxMessage(notification, tx)
+	if outboxErr != nil {
+		rollErr := tx.Rollback()
+		if rollErr != nil {
+			log.Error().Err(rollErr).Msgf("[%s] SQL TX rollback failed", dbFinPrefix)
+			return rollErr
+		}
+		return outboxErr
+	}
+
 	commErr := tx.Commit()
 	if commErr != nil {
 		log.Error().Err(commErr).Msgf("[%s] couldn't commit SQL transaction", dbFinPrefix)
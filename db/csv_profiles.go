@@ -0,0 +1,218 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbCsvProfilesPrefix = "db/csvProfiles"
+
+// CsvProfile is a saved mapping of CSV files with bank transactions. Column
+// numbers start from 1, zero means that the column isn't present.
+type CsvProfile struct {
+	ProfileId           int64
+	Name                string
+	Delimiter           string
+	Encoding            string
+	SkipRows            int
+	HasHeader           bool
+	DateLayout          string
+	DecimalComma        bool
+	AmountSign          string
+	DefaultCurrency     string
+	AccountNumber       string
+	ExecutionDateColumn int
+	OrderDateColumn     int
+	AmountColumn        int
+	DebitColumn         int
+	CreditColumn        int
+	CurrencyColumn      int
+	TypeColumn          int
+	DescriptionColumns  string // Comma separated column numbers
+	EndingBalanceColumn int
+	AccountColumn       int
+	UpdatedTs           string
+}
+
+// CsvProfiles reads all saved CSV profiles ordered by name.
+func (c *Client) CsvProfiles() ([]CsvProfile, error) {
+	profiles := make([]CsvProfile, 0, 10)
+	rows, qErr := c.dbConn.Query(csvProfilesQuery())
+	if qErr != nil {
+		log.Error().Err(qErr).Msgf("[%s] csvProfilesQuery failed", dbCsvProfilesPrefix)
+		return profiles, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		p, sErr := scanCsvProfile(rows)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of csvProfilesQuery", dbCsvProfilesPrefix)
+			continue
+		}
+		profiles = append(profiles, p)
+	}
+	return profiles, nil
+}
+
+// CsvProfileById reads single CSV profile. If profile does not exist
+// sql.ErrNoRows is returned.
+func (c *Client) CsvProfileById(profileId int64) (CsvProfile, error) {
+	p, scanErr := scanCsvProfile(c.dbConn.QueryRow(csvProfileByIdQuery(), profileId))
+	if scanErr != nil && scanErr != sql.ErrNoRows {
+		log.Error().Err(scanErr).Int64("profileId", profileId).Msgf("[%s] cannot read CSV profile", dbCsvProfilesPrefix)
+	}
+	return p, scanErr
+}
+
+// CsvProfileSave inserts new profile (when ProfileId is 0) or updates existing
+// one. Returns ID of the profile.
+func (c *Client) CsvProfileSave(p CsvProfile) (int64, error) {
+	args := []interface{}{p.Name, p.Delimiter, p.Encoding, p.SkipRows, p.HasHeader, p.DateLayout,
+		p.DecimalComma, p.AmountSign, p.DefaultCurrency, p.AccountNumber, p.ExecutionDateColumn,
+		p.OrderDateColumn, p.AmountColumn, p.DebitColumn, p.CreditColumn, p.CurrencyColumn, p.TypeColumn,
+		p.DescriptionColumns, p.EndingBalanceColumn, p.AccountColumn, p.UpdatedTs}
+
+	if p.ProfileId == 0 {
+		res, execErr := c.dbConn.Exec(csvProfileInsertQuery(), args...)
+		if execErr != nil {
+			log.Error().Err(execErr).Str("name", p.Name).Msgf("[%s] cannot insert CSV profile", dbCsvProfilesPrefix)
+			return 0, execErr
+		}
+		return res.LastInsertId()
+	}
+
+	res, execErr := c.dbConn.Exec(csvProfileUpdateQuery(), append(args, p.ProfileId)...)
+	if execErr != nil {
+		log.Error().Err(execErr).Int64("profileId", p.ProfileId).
+			Msgf("[%s] cannot update CSV profile", dbCsvProfilesPrefix)
+		return 0, execErr
+	}
+	if affected, _ := res.RowsAffected(); affected == 0 {
+		return 0, sql.ErrNoRows
+	}
+	return p.ProfileId, nil
+}
+
+// CsvProfileDelete deletes CSV profile.
+func (c *Client) CsvProfileDelete(profileId int64) error {
+	_, execErr := c.dbConn.Exec(csvProfileDeleteQuery(), profileId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int64("profileId", profileId).
+			Msgf("[%s] cannot delete CSV profile", dbCsvProfilesPrefix)
+	}
+	return execErr
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanCsvProfile(row rowScanner) (CsvProfile, error) {
+	var p CsvProfile
+	sErr := row.Scan(&p.ProfileId, &p.Name, &p.Delimiter, &p.Encoding, &p.SkipRows, &p.HasHeader,
+		&p.DateLayout, &p.DecimalComma, &p.AmountSign, &p.DefaultCurrency, &p.AccountNumber,
+		&p.ExecutionDateColumn, &p.OrderDateColumn, &p.AmountColumn, &p.DebitColumn, &p.CreditColumn,
+		&p.CurrencyColumn, &p.TypeColumn, &p.DescriptionColumns, &p.EndingBalanceColumn, &p.AccountColumn,
+		&p.UpdatedTs)
+	return p, sErr
+}
+
+func csvProfileColumns() string {
+	return `
+			ProfileId,
+			Name,
+			Delimiter,
+			Encoding,
+			SkipRows,
+			HasHeader,
+			DateLayout,
+			DecimalComma,
+			AmountSign,
+			DefaultCurrency,
+			AccountNumber,
+			ExecutionDateColumn,
+			OrderDateColumn,
+			AmountColumn,
+			DebitColumn,
+			CreditColumn,
+			CurrencyColumn,
+			TypeColumn,
+			DescriptionColumns,
+			EndingBalanceColumn,
+			AccountColumn,
+			UpdatedTs`
+}
+
+func csvProfilesQuery() string {
+	return `
+		SELECT` + csvProfileColumns() + `
+		FROM
+			csvProfiles
+		ORDER BY
+			Name
+	`
+}
+
+func csvProfileByIdQuery() string {
+	return `
+		SELECT` + csvProfileColumns() + `
+		FROM
+			csvProfiles
+		WHERE
+			ProfileId = ?
+	`
+}
+
+func csvProfileInsertQuery() string {
+	return `
+		INSERT INTO csvProfiles (
+			Name, Delimiter, Encoding, SkipRows, HasHeader, DateLayout, DecimalComma, AmountSign,
+			DefaultCurrency, AccountNumber, ExecutionDateColumn, OrderDateColumn, AmountColumn, DebitColumn,
+			CreditColumn, CurrencyColumn, TypeColumn, DescriptionColumns, EndingBalanceColumn, AccountColumn,
+			UpdatedTs
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+}
+
+func csvProfileUpdateQuery() string {
+	return `
+		UPDATE
+			csvProfiles
+		SET
+			Name = ?,
+			Delimiter = ?,
+			Encoding = ?,
+			SkipRows = ?,
+			HasHeader = ?,
+			DateLayout = ?,
+			DecimalComma = ?,
+			AmountSign = ?,
+			DefaultCurrency = ?,
+			AccountNumber = ?,
+			ExecutionDateColumn = ?,
+			OrderDateColumn = ?,
+			AmountColumn = ?,
+			DebitColumn = ?,
+			CreditColumn = ?,
+			CurrencyColumn = ?,
+			TypeColumn = ?,
+			DescriptionColumns = ?,
+			EndingBalanceColumn = ?,
+			AccountColumn = ?,
+			UpdatedTs = ?
+		WHERE
+			ProfileId = ?
+	`
+}
+
+func csvProfileDeleteQuery() string {
+	return `
+		DELETE FROM
+			csvProfiles
+		WHERE
+			ProfileId = ?
+	`
+}
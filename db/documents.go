@@ -21,6 +21,7 @@ type DocumentInfo struct {
 	PersonInvolved *string
 	FileExtension  string
 	FileSizeBytes  int64
+	ExpiryDate     *string
 }
 
 // NewDocument represents new documentat candidate that shall be inserted into
@@ -31,6 +32,7 @@ type NewDocument struct {
 	Category       string
 	PersonInvolved string
 	FileExtension  string
+	ExpiryDate     string // Optional, e.g. for ID cards or insurance policies
 	DocumentFile   []byte
 }
 
@@ -46,26 +48,13 @@ func (c *Client) Documents() ([]DocumentInfo, error) {
 		return documents, qErr
 	}
 
-	var id int
-	var fileSize int64
-	var name, uploadDate, category, fileExt string
-	var documentDate, person *string
 	for rows.Next() {
-		sErr := rows.Scan(&id, &name, &uploadDate, &documentDate, &category, &person, &fileExt, &fileSize)
+		doc, sErr := scanDocumentInfo(rows)
 		if sErr != nil {
 			log.Warn().Err(sErr).Msgf("[%s] while scanning results of documentsQuery", dbDocsPrefix)
 			continue
 		}
-		documents = append(documents, DocumentInfo{
-			Id:             id,
-			Name:           name,
-			UploadDate:     uploadDate,
-			DocumentDate:   documentDate,
-			Category:       category,
-			PersonInvolved: person,
-			FileExtension:  fileExt,
-			FileSizeBytes:  fileSize,
-		})
+		documents = append(documents, doc)
 	}
 	log.Info().Int("rowsLoaded", len(documents)).Dur("duration", time.Since(startTs)).
 		Msgf("[%s] finished reading documents info", dbDocsPrefix)
@@ -87,26 +76,13 @@ func (c *Client) DocumentsFiltered(phrase string) ([]DocumentInfo, error) {
 		return documents, qErr
 	}
 
-	var id int
-	var fileSize int64
-	var name, uploadDate, category, fileExt string
-	var documentDate, person *string
 	for rows.Next() {
-		sErr := rows.Scan(&id, &name, &uploadDate, &documentDate, &category, &person, &fileExt, &fileSize)
+		doc, sErr := scanDocumentInfo(rows)
 		if sErr != nil {
 			log.Warn().Err(sErr).Msgf("[%s] while scanning results of documentsFilteredQuery", dbDocsPrefix)
 			continue
 		}
-		documents = append(documents, DocumentInfo{
-			Id:             id,
-			Name:           name,
-			UploadDate:     uploadDate,
-			DocumentDate:   documentDate,
-			Category:       category,
-			PersonInvolved: person,
-			FileExtension:  fileExt,
-			FileSizeBytes:  fileSize,
-		})
+		documents = append(documents, doc)
 	}
 	log.Info().Str("filter", phrase).Int("rowsLoaded", len(documents)).Dur("duration", time.Since(startTs)).
 		Msgf("[%s] finished reading filtered documents info", dbDocsPrefix)
@@ -114,9 +90,50 @@ func (c *Client) DocumentsFiltered(phrase string) ([]DocumentInfo, error) {
 	return documents, nil
 }
 
+// DocumentsUploadedBetween reads metadata of documents uploaded between
+// given dates (YYYY-MM-DD), including fromDate and excluding toDate.
+func (c *Client) DocumentsUploadedBetween(fromDate, toDate string) ([]DocumentInfo, error) {
+	return c.documentsByDates(documentsUploadedBetweenQuery(), fromDate, toDate)
+}
+
+// DocumentsExpiringBetween reads metadata of documents which expiry date is
+// between given dates (YYYY-MM-DD), both inclusive. The soonest expiring
+// documents are first.
+func (c *Client) DocumentsExpiringBetween(fromDate, toDate string) ([]DocumentInfo, error) {
+	return c.documentsByDates(documentsExpiringBetweenQuery(), fromDate, toDate)
+}
+
+func (c *Client) documentsByDates(query, fromDate, toDate string) ([]DocumentInfo, error) {
+	startTs := time.Now()
+	documents := make([]DocumentInfo, 0)
+
+	rows, qErr := c.dbConn.Query(query, fromDate, toDate)
+	if qErr != nil {
+		log.Error().Err(qErr).Str("from", fromDate).Str("to", toDate).
+			Msgf("[%s] documents by dates query failed", dbDocsPrefix)
+		return documents, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		doc, sErr := scanDocumentInfo(rows)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning documents by dates", dbDocsPrefix)
+			continue
+		}
+		documents = append(documents, doc)
+	}
+	log.Info().Int("rowsLoaded", len(documents)).Str("from", fromDate).Str("to", toDate).
+		Dur("duration", time.Since(startTs)).Msgf("[%s] finished reading documents by dates", dbDocsPrefix)
+
+	return documents, nil
+}
+
 // DocumentInsertNew uploads into database information about new document. This
 // is composed of two things - metadata about document and the content.
-func (c *Client) DocumentInsertNew(newDoc NewDocument) error {
+// Notification (if not nil) is stored in the outbox within the same
+// transaction.
+func (c *Client) DocumentInsertNew(newDoc NewDocument, notification *NewOutboxMessage) error {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start inserting new document", dbDocsPrefix)
 
@@ -151,6 +168,13 @@ func (c *Client) DocumentInsertNew(newDoc NewDocument) error {
 		return contentErr
 	}
 
+	outboxErr := insertOutboxMessage(notification, tx)
+	if outboxErr != nil {
+		log.Error().Err(outboxErr).Msgf("[%s] cannot insert notification into outbox, rollback.", dbDocsPrefix)
+		tx.Rollback()
+		return outboxErr
+	}
+
 	commErr := tx.Commit()
 	if commErr != nil {
 		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbDocsPrefix)
@@ -164,26 +188,39 @@ func (c *Client) DocumentInsertNew(newDoc NewDocument) error {
 	return nil
 }
 
-// DocumentFile load from database content of given document and it's extension
-// name.
-func (c *Client) DocumentFile(documentId int) ([]byte, string, error) {
+// DocumentFile load from database content of given document, it's name and
+// it's extension name.
+func (c *Client) DocumentFile(documentId int) ([]byte, string, string, error) {
 	startTs := time.Now()
 	log.Info().Int("documentId", documentId).Msgf("[%s] start reading document file", dbDocsPrefix)
 
 	row := c.dbConn.QueryRow(documentFileQuery(), documentId)
 	var fileBytes []byte
-	var fileExt string
-	scanErr := row.Scan(&fileExt, &fileBytes)
+	var fileExt, name string
+	scanErr := row.Scan(&fileExt, &name, &fileBytes)
 	if scanErr != nil {
 		log.Error().Err(scanErr).Int("documentId", documentId).
 			Msgf("[%s] while loading document content", dbDocsPrefix)
-		return fileBytes, fileExt, scanErr
+		return fileBytes, name, fileExt, scanErr
 	}
 
 	log.Info().Int("loadedBytes", len(fileBytes)).Int("documentId", documentId).
 		Dur("duration", time.Since(startTs)).Msgf("[%s] finished loading document content", dbDocsPrefix)
 
-	return fileBytes, fileExt, nil
+	return fileBytes, name, fileExt, nil
+}
+
+// DocumentFileSize reads size in bytes of stored document file, without
+// loading its content. If document does not exist sql.ErrNoRows is returned.
+func (c *Client) DocumentFileSize(documentId int) (int64, error) {
+	var fileSize int64
+	scanErr := c.dbConn.QueryRow(documentFileSizeQuery(), documentId).Scan(&fileSize)
+	if scanErr != nil {
+		log.Error().Err(scanErr).Int("documentId", documentId).
+			Msgf("[%s] while reading document file size", dbDocsPrefix)
+		return 0, scanErr
+	}
+	return fileSize, nil
 }
 
 func getMaxDocumentId(tx *sql.Tx) (int, error) {
@@ -207,7 +244,7 @@ func insertDocumentMeta(documentId int, newDoc NewDocument, tx *sql.Tx) error {
 	_, qErr := tx.Exec(
 		documentInsertNewMetaQuery(), documentId, newDoc.Name, uploadDate,
 		toNullString(&newDoc.DocumentDate), newDoc.Category, toNullString(&newDoc.PersonInvolved),
-		newDoc.FileExtension, len(newDoc.DocumentFile))
+		newDoc.FileExtension, len(newDoc.DocumentFile), toNullString(&newDoc.ExpiryDate))
 	if qErr != nil {
 		return qErr
 	}
@@ -234,6 +271,14 @@ func insertDocumentContent(documentId int, content []byte, tx *sql.Tx) error {
 	return nil
 }
 
+// Scans single row of documents metadata query.
+func scanDocumentInfo(rows *sql.Rows) (DocumentInfo, error) {
+	var doc DocumentInfo
+	sErr := rows.Scan(&doc.Id, &doc.Name, &doc.UploadDate, &doc.DocumentDate, &doc.Category,
+		&doc.PersonInvolved, &doc.FileExtension, &doc.FileSizeBytes, &doc.ExpiryDate)
+	return doc, sErr
+}
+
 func (nd *NewDocument) Validate() error {
 	if len(nd.Name) == 0 {
 		return errors.New("document name should be provided")
@@ -244,6 +289,11 @@ func (nd *NewDocument) Validate() error {
 	if len(nd.FileExtension) == 0 {
 		return errors.New("document extension should be provided")
 	}
+	if nd.ExpiryDate != "" {
+		if _, pErr := time.Parse("2006-01-02", nd.ExpiryDate); pErr != nil {
+			return errors.New("document expiry date should be in YYYY-MM-DD format")
+		}
+	}
 	return nil
 }
 
@@ -257,7 +307,8 @@ func documentsQuery() string {
 			Category,
 			PersonInvolved,
 			FileExtension,
-			FileSize
+			FileSize,
+			ExpiryDate
 		FROM
 			documents
 		ORDER BY
@@ -275,7 +326,8 @@ func documentsFilteredQuery() string {
 		d.Category,
 		d.PersonInvolved,
 		d.FileExtension,
-		d.FileSize
+		d.FileSize,
+		d.ExpiryDate
 	FROM
 		documentsFts5 f
 	INNER JOIN
@@ -287,13 +339,58 @@ func documentsFilteredQuery() string {
 	`
 }
 
+func documentsUploadedBetweenQuery() string {
+	return `
+		SELECT
+			DocumentId,
+			DocumentName,
+			UploadDate,
+			DocumentDate,
+			Category,
+			PersonInvolved,
+			FileExtension,
+			FileSize,
+			ExpiryDate
+		FROM
+			documents
+		WHERE
+			UploadDate >= ?
+			AND UploadDate < ?
+		ORDER BY
+			DocumentId
+	`
+}
+
+func documentsExpiringBetweenQuery() string {
+	return `
+		SELECT
+			DocumentId,
+			DocumentName,
+			UploadDate,
+			DocumentDate,
+			Category,
+			PersonInvolved,
+			FileExtension,
+			FileSize,
+			ExpiryDate
+		FROM
+			documents
+		WHERE
+			ExpiryDate >= ?
+			AND ExpiryDate <= ?
+		ORDER BY
+			ExpiryDate,
+			DocumentId
+	`
+}
+
 func documentInsertNewMetaQuery() string {
 	return `
 	INSERT INTO documents (
 		DocumentId, DocumentName, UploadDate, DocumentDate, Category, PersonInvolved,
-		FileExtension, FileSize
+		FileExtension, FileSize, ExpiryDate
 	)
-	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 }
 
@@ -318,6 +415,7 @@ func documentFileQuery() string {
 	return `
 		SELECT
 			d.FileExtension,
+			d.DocumentName,
 			df.FileBytes
 		FROM
 			documents d
@@ -327,3 +425,14 @@ func documentFileQuery() string {
 			d.DocumentId = ?
 `
 }
+
+func documentFileSizeQuery() string {
+	return `
+		SELECT
+			FileSize
+		FROM
+			documents
+		WHERE
+			DocumentId = ?
+	`
+}
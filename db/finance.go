@@ -39,8 +39,9 @@ func (c *Client) FinTransMonthly(year int, month int) ([]BankTransaction, error)
 
 // FinInsertTransactions inserts bank transactions into database. Transactions
 // is bundled into SQL transaction which is unrolled in case when there is a
-// failure.
-func (c *Client) FinInsertTransactions(transactions []BankTransaction) error {
+// failure. Notification (if not nil) is stored in the outbox within the same
+// transaction.
+func (c *Client) FinInsertTransactions(transactions []BankTransaction, notification *NewOutboxMessage) error {
 	startTs := time.Now()
 	log.Info().Msgf("[%s] start inserting financial transactions", dbFinPrefix)
 
@@ -63,6 +64,17 @@ func (c *Client) FinInsertTransactions(transactions []BankTransaction) error {
 		}
 	}
 
+	outboxErr := insertOutboxMessage(notification, tx)
+	if outboxErr != nil {
+		log.Error().Err(outboxErr).Msgf("[%s] cannot insert notification into outbox", dbFinPrefix)
+		rollErr := tx.Rollback()
+		if rollErr != nil {
+			log.Error().Err(rollErr).Msgf("[%s] SQL TX rollback failed", dbFinPrefix)
+			return rollErr
+		}
+		return outboxErr
+	}
+
 	commErr := tx.Commit()
 	if commErr != nil {
 		log.Error().Err(commErr).Msgf("[%s] couldn't commit SQL transaction", dbFinPrefix)
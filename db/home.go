@@ -102,8 +102,8 @@ func homeSummaryQuery() string {
 	, documentsSummary AS (
 		SELECT
 			COUNT(d.DocumentId) AS DocNumber,
-			SUM(LENGTH(df.FileBytes)) / 1000000.0 AS DocSizeMb,
-			MAX(d.UploadDate) AS DocLatestDate
+			COALESCE(SUM(LENGTH(df.FileBytes)) / 1000000.0, 0.0) AS DocSizeMb,
+			COALESCE(MAX(d.UploadDate), '') AS DocLatestDate
 		FROM
 			documents d
 		INNER JOIN
@@ -111,40 +111,40 @@ func homeSummaryQuery() string {
 	)
 	, booksSummary AS (
 		SELECT
-			SUM(
+			COALESCE(SUM(
 				CASE
 					WHEN FileSize IS NOT NULL THEN 1
 					ELSE 0
 				END
-			) AS EbooksNumber,
+			), 0) AS EbooksNumber,
 			CASE
 				WHEN SUM(FileSize) IS NULL THEN 0.0
 				ELSE SUM(FileSize) / 1000000.0
 			END AS EbooksSizeMb,
-			MAX(
+			COALESCE(MAX(
 				CASE
 					WHEN FileSize IS NOT NULL THEN UploadDate
 					ELSE ''
 				END
-			) AS EbookLatestUploadDate,
-			SUM(
+			), '') AS EbookLatestUploadDate,
+			COALESCE(SUM(
 				CASE
 					WHEN FileSize IS NULL THEN 1
 					ELSE 0
 				END
-			) AS BooksNumber,
-			MAX(
+			), 0) AS BooksNumber,
+			COALESCE(MAX(
 				CASE
 					WHEN FileSize IS NULL THEN UploadDate
 					ELSE ''
 				END
-			) AS BookLatestUploadDate
+			), '') AS BookLatestUploadDate
 		FROM
 			books b
 	)
 	, finSummary AS (
 		SELECT
-			MAX(OrderDate) AS FinLatestOrderDate
+			COALESCE(MAX(OrderDate), '') AS FinLatestOrderDate
 		FROM
 			bankTransactions
 		WHERE
@@ -0,0 +1,134 @@
+package db
+
+import (
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbJwtKeysPrefix = "db/jwtKeys"
+
+// JwtSigningKey represents single key used for signing JWTs. Key without
+// RetireTs is the current one.
+type JwtSigningKey struct {
+	KeyId      string
+	SigningKey []byte
+	CreateTs   string
+	RetireTs   *string
+}
+
+// JwtSigningKeys reads all stored JWT signing keys ordered from the newest.
+func (c *Client) JwtSigningKeys() ([]JwtSigningKey, error) {
+	startTs := time.Now()
+	keys := make([]JwtSigningKey, 0, 5)
+	log.Info().Msgf("[%s] start reading JWT signing keys", dbJwtKeysPrefix)
+
+	rows, qErr := c.dbConn.Query(jwtSigningKeysQuery())
+	if qErr != nil {
+		log.Error().Err(qErr).Msgf("[%s] jwtSigningKeysQuery failed", dbJwtKeysPrefix)
+		return keys, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key JwtSigningKey
+		sErr := rows.Scan(&key.KeyId, &key.SigningKey, &key.CreateTs, &key.RetireTs)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of jwtSigningKeysQuery", dbJwtKeysPrefix)
+			continue
+		}
+		keys = append(keys, key)
+	}
+	log.Info().Int("rowsLoaded", len(keys)).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished reading JWT signing keys", dbJwtKeysPrefix)
+
+	return keys, nil
+}
+
+// JwtSigningKeyRotate retires current signing key (if exists) and inserts new
+// one. Both steps are bundled into SQL transaction. Keys retired before
+// deleteRetiredBefore are deleted.
+func (c *Client) JwtSigningKeyRotate(newKey JwtSigningKey, deleteRetiredBefore string) error {
+	startTs := time.Now()
+	log.Info().Str("keyId", newKey.KeyId).Msgf("[%s] start rotating JWT signing key", dbJwtKeysPrefix)
+
+	tx, tErr := c.dbConn.Begin()
+	if tErr != nil {
+		log.Error().Err(tErr).Msgf("[%s] cannot start new transaction", dbJwtKeysPrefix)
+		return tErr
+	}
+
+	_, rErr := tx.Exec(jwtSigningKeyRetireQuery(), newKey.CreateTs)
+	if rErr != nil {
+		log.Error().Err(rErr).Msgf("[%s] cannot retire current JWT signing key", dbJwtKeysPrefix)
+		tx.Rollback()
+		return rErr
+	}
+
+	_, dErr := tx.Exec(jwtSigningKeyDeleteRetiredQuery(), deleteRetiredBefore)
+	if dErr != nil {
+		log.Error().Err(dErr).Msgf("[%s] cannot delete retired JWT signing keys", dbJwtKeysPrefix)
+		tx.Rollback()
+		return dErr
+	}
+
+	_, iErr := tx.Exec(jwtSigningKeyInsertQuery(), newKey.KeyId, newKey.SigningKey, newKey.CreateTs)
+	if iErr != nil {
+		log.Error().Err(iErr).Msgf("[%s] cannot insert new JWT signing key", dbJwtKeysPrefix)
+		tx.Rollback()
+		return iErr
+	}
+
+	commErr := tx.Commit()
+	if commErr != nil {
+		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbJwtKeysPrefix)
+		tx.Rollback()
+		return commErr
+	}
+
+	log.Info().Str("keyId", newKey.KeyId).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished rotating JWT signing key", dbJwtKeysPrefix)
+	return nil
+}
+
+func jwtSigningKeysQuery() string {
+	return `
+		SELECT
+			KeyId,
+			SigningKey,
+			CreateTs,
+			RetireTs
+		FROM
+			jwtSigningKeys
+		ORDER BY
+			CreateTs DESC
+	`
+}
+
+func jwtSigningKeyRetireQuery() string {
+	return `
+		UPDATE
+			jwtSigningKeys
+		SET
+			RetireTs = ?
+		WHERE
+			RetireTs IS NULL
+	`
+}
+
+func jwtSigningKeyDeleteRetiredQuery() string {
+	return `
+		DELETE FROM
+			jwtSigningKeys
+		WHERE
+			RetireTs IS NOT NULL
+			AND RetireTs < ?
+	`
+}
+
+func jwtSigningKeyInsertQuery() string {
+	return `
+		INSERT INTO jwtSigningKeys (KeyId, SigningKey, CreateTs)
+		VALUES (?, ?, ?)
+	`
+}
@@ -0,0 +1,119 @@
+package db
+
+import (
+	"database/sql"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbThrottlePrefix = "db/loginThrottle"
+
+// LoginThrottle represents failed login attempts for single throttle key
+// (username or IP address).
+type LoginThrottle struct {
+	ThrottleKey    string
+	FailedCount    int
+	LastFailureTs  string
+	BlockedUntilTs *string
+}
+
+// LoginThrottleByKeys reads throttle state of given keys. Keys without failed
+// attempts are skipped.
+func (c *Client) LoginThrottleByKeys(keys ...string) ([]LoginThrottle, error) {
+	throttles := make([]LoginThrottle, 0, len(keys))
+	for _, key := range keys {
+		var t LoginThrottle
+		row := c.dbConn.QueryRow(loginThrottleByKeyQuery(), key)
+		scanErr := row.Scan(&t.ThrottleKey, &t.FailedCount, &t.LastFailureTs, &t.BlockedUntilTs)
+		if scanErr == sql.ErrNoRows {
+			continue
+		}
+		if scanErr != nil {
+			log.Error().Err(scanErr).Str("key", key).Msgf("[%s] cannot read login throttle", dbThrottlePrefix)
+			return nil, scanErr
+		}
+		throttles = append(throttles, t)
+	}
+	return throttles, nil
+}
+
+// LoginThrottleRegisterFailure increments number of failed attempts for given
+// key and returns the new value. Counter starts over if the last failure
+// happened before resetBeforeTs.
+func (c *Client) LoginThrottleRegisterFailure(key, nowTs, resetBeforeTs string) (int, error) {
+	var failedCount int
+	row := c.dbConn.QueryRow(loginThrottleRegisterFailureQuery(), key, nowTs, resetBeforeTs)
+	scanErr := row.Scan(&failedCount)
+	if scanErr != nil {
+		log.Error().Err(scanErr).Str("key", key).Msgf("[%s] cannot register failed login", dbThrottlePrefix)
+		return 0, scanErr
+	}
+	return failedCount, nil
+}
+
+// LoginThrottleBlock blocks login attempts for given key until given
+// timestamp.
+func (c *Client) LoginThrottleBlock(key, blockedUntilTs string) error {
+	_, execErr := c.dbConn.Exec(loginThrottleBlockQuery(), blockedUntilTs, key)
+	if execErr != nil {
+		log.Error().Err(execErr).Str("key", key).Msgf("[%s] cannot block login attempts", dbThrottlePrefix)
+	}
+	return execErr
+}
+
+// LoginThrottleReset deletes failed attempts of given keys.
+func (c *Client) LoginThrottleReset(keys ...string) error {
+	for _, key := range keys {
+		_, execErr := c.dbConn.Exec(loginThrottleDeleteQuery(), key)
+		if execErr != nil {
+			log.Error().Err(execErr).Str("key", key).Msgf("[%s] cannot reset login throttle", dbThrottlePrefix)
+			return execErr
+		}
+	}
+	return nil
+}
+
+func loginThrottleByKeyQuery() string {
+	return `
+		SELECT
+			ThrottleKey,
+			FailedCount,
+			LastFailureTs,
+			BlockedUntilTs
+		FROM
+			loginThrottle
+		WHERE
+			ThrottleKey = ?
+	`
+}
+
+func loginThrottleRegisterFailureQuery() string {
+	return `
+		INSERT INTO loginThrottle (ThrottleKey, FailedCount, LastFailureTs)
+		VALUES (?, 1, ?)
+		ON CONFLICT (ThrottleKey) DO UPDATE SET
+			FailedCount = CASE WHEN LastFailureTs < ? THEN 1 ELSE FailedCount + 1 END,
+			LastFailureTs = excluded.LastFailureTs
+		RETURNING FailedCount
+	`
+}
+
+func loginThrottleBlockQuery() string {
+	return `
+		UPDATE
+			loginThrottle
+		SET
+			BlockedUntilTs = ?
+		WHERE
+			ThrottleKey = ?
+	`
+}
+
+func loginThrottleDeleteQuery() string {
+	return `
+		DELETE FROM
+			loginThrottle
+		WHERE
+			ThrottleKey = ?
+	`
+}
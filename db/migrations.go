@@ -0,0 +1,65 @@
+package db
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbMigrationsPrefix = "db/migrations"
+
+// Column added to already existing table. CREATE TABLE statements in
+// sql/schema.sql contain those columns, but tables created before need ALTER
+// TABLE.
+type columnMigration struct {
+	Table      string
+	Column     string
+	Definition string
+}
+
+var columnMigrations = []columnMigration{
+	{Table: "documents", Column: "ExpiryDate", Definition: "TEXT NULL"},
+}
+
+// Migrate brings existing database up to date. It executes given schema
+// (sql/schema.sql, only CREATE ... IF NOT EXISTS statements and full text
+// search rebuild), so missing tables and indexes are created, and then adds
+// missing columns to tables created by older versions. It's idempotent, so
+// it's run on each start.
+func (c *Client) Migrate(schema string) error {
+	startTs := time.Now()
+	if _, execErr := c.dbConn.Exec(schema); execErr != nil {
+		return fmt.Errorf("cannot apply schema: %w", execErr)
+	}
+	for _, m := range columnMigrations {
+		var count int
+		row := c.dbConn.QueryRow(columnExistsQuery(), m.Table, m.Column)
+		if scanErr := row.Scan(&count); scanErr != nil {
+			return fmt.Errorf("cannot check column %s.%s: %w", m.Table, m.Column, scanErr)
+		}
+		if count > 0 {
+			continue
+		}
+
+		alter := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", m.Table, m.Column, m.Definition)
+		if _, execErr := c.dbConn.Exec(alter); execErr != nil {
+			return fmt.Errorf("cannot add column %s.%s: %w", m.Table, m.Column, execErr)
+		}
+		log.Info().Str("table", m.Table).Str("column", m.Column).
+			Msgf("[%s] added missing column", dbMigrationsPrefix)
+	}
+	log.Info().Dur("duration", time.Since(startTs)).Msgf("[%s] finished schema migrations", dbMigrationsPrefix)
+	return nil
+}
+
+func columnExistsQuery() string {
+	return `
+		SELECT
+			COUNT(*)
+		FROM
+			pragma_table_info(?)
+		WHERE
+			name = ?
+	`
+}
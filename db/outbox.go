@@ -0,0 +1,231 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbOutboxPrefix = "db/outbox"
+
+// Statuses of outbox messages.
+const (
+	OutboxPending   = "pending"
+	OutboxDelivered = "delivered"
+	OutboxDead      = "dead"
+)
+
+// OutboxStatuses lists all statuses of outbox messages.
+var OutboxStatuses = []string{OutboxPending, OutboxDelivered, OutboxDead}
+
+// OutboxMessage is a notification stored in the outbox until it's delivered.
+type OutboxMessage struct {
+	MessageId         int64
+	Event             string
+	Title             string
+	Text              string
+	Status            string
+	Attempts          int
+	DeliveredChannels string // Comma separated
+	LastError         *string
+	CreatedTs         string
+	NextAttemptTs     string
+	DeliveredTs       *string
+}
+
+// NewOutboxMessage is a notification which should be stored in the outbox.
+type NewOutboxMessage struct {
+	Event string
+	Title string
+	Text  string
+}
+
+// OutboxInsert stores new notification in the outbox. Notifications
+// describing inserted data should be rather passed to the insert method, so
+// they're stored in the same transaction.
+func (c *Client) OutboxInsert(msg NewOutboxMessage) error {
+	nowTs := time.Now().UTC().Format(TimestampFormat)
+	_, execErr := c.dbConn.Exec(outboxInsertQuery(), msg.Event, msg.Title, msg.Text, OutboxPending, nowTs, nowTs)
+	if execErr != nil {
+		log.Error().Err(execErr).Str("event", msg.Event).Msgf("[%s] cannot insert outbox message", dbOutboxPrefix)
+	}
+	return execErr
+}
+
+// OutboxDue reads pending messages which should be delivered at given time,
+// the oldest first.
+func (c *Client) OutboxDue(nowTs string, limit int) ([]OutboxMessage, error) {
+	return c.outboxQueryMessages(outboxDueQuery(), OutboxPending, nowTs, limit)
+}
+
+// OutboxMessages reads the latest messages of given status. All messages are
+// read when status is empty.
+func (c *Client) OutboxMessages(status string, limit int) ([]OutboxMessage, error) {
+	return c.outboxQueryMessages(outboxMessagesQuery(), status, status, limit)
+}
+
+// OutboxUpdate saves delivery state (status, attempts, delivered channels,
+// error and timestamps) of the message.
+func (c *Client) OutboxUpdate(msg OutboxMessage) error {
+	_, execErr := c.dbConn.Exec(outboxUpdateQuery(), msg.Status, msg.Attempts, msg.DeliveredChannels,
+		msg.LastError, msg.NextAttemptTs, msg.DeliveredTs, msg.MessageId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int64("messageId", msg.MessageId).
+			Msgf("[%s] cannot update outbox message", dbOutboxPrefix)
+	}
+	return execErr
+}
+
+// OutboxRequeue moves dead message back to pending messages, so it's
+// delivered again with reset attempts counter. Returns false if there's no
+// such dead message.
+func (c *Client) OutboxRequeue(messageId int64, nowTs string) (bool, error) {
+	res, execErr := c.dbConn.Exec(outboxRequeueQuery(), OutboxPending, nowTs, messageId, OutboxDead)
+	if execErr != nil {
+		log.Error().Err(execErr).Int64("messageId", messageId).
+			Msgf("[%s] cannot requeue outbox message", dbOutboxPrefix)
+		return false, execErr
+	}
+	affected, _ := res.RowsAffected()
+	return affected == 1, nil
+}
+
+// OutboxDeleteDelivered deletes messages delivered before given timestamp.
+func (c *Client) OutboxDeleteDelivered(beforeTs string) (int64, error) {
+	res, execErr := c.dbConn.Exec(outboxDeleteDeliveredQuery(), OutboxDelivered, beforeTs)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot delete delivered outbox messages", dbOutboxPrefix)
+		return 0, execErr
+	}
+	return res.RowsAffected()
+}
+
+// Inserts notification within given transaction. Nothing is inserted for nil
+// message.
+func insertOutboxMessage(msg *NewOutboxMessage, tx *sql.Tx) error {
+	if msg == nil {
+		return nil
+	}
+	nowTs := time.Now().UTC().Format(TimestampFormat)
+	_, execErr := tx.Exec(outboxInsertQuery(), msg.Event, msg.Title, msg.Text, OutboxPending, nowTs, nowTs)
+	return execErr
+}
+
+func (c *Client) outboxQueryMessages(query string, args ...interface{}) ([]OutboxMessage, error) {
+	messages := make([]OutboxMessage, 0)
+	rows, qErr := c.dbConn.Query(query, args...)
+	if qErr != nil {
+		log.Error().Err(qErr).Msgf("[%s] outbox query failed", dbOutboxPrefix)
+		return messages, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var m OutboxMessage
+		sErr := rows.Scan(&m.MessageId, &m.Event, &m.Title, &m.Text, &m.Status, &m.Attempts,
+			&m.DeliveredChannels, &m.LastError, &m.CreatedTs, &m.NextAttemptTs, &m.DeliveredTs)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning outbox messages", dbOutboxPrefix)
+			continue
+		}
+		messages = append(messages, m)
+	}
+	return messages, nil
+}
+
+func outboxInsertQuery() string {
+	return `
+		INSERT INTO outbox (Event, Title, Text, Status, Attempts, DeliveredChannels, CreatedTs, NextAttemptTs)
+		VALUES (?, ?, ?, ?, 0, '', ?, ?)
+	`
+}
+
+func outboxDueQuery() string {
+	return `
+		SELECT
+			MessageId,
+			Event,
+			Title,
+			Text,
+			Status,
+			Attempts,
+			DeliveredChannels,
+			LastError,
+			CreatedTs,
+			NextAttemptTs,
+			DeliveredTs
+		FROM
+			outbox
+		WHERE
+			Status = ?
+			AND NextAttemptTs <= ?
+		ORDER BY
+			MessageId
+		LIMIT ?
+	`
+}
+
+func outboxMessagesQuery() string {
+	return `
+		SELECT
+			MessageId,
+			Event,
+			Title,
+			Text,
+			Status,
+			Attempts,
+			DeliveredChannels,
+			LastError,
+			CreatedTs,
+			NextAttemptTs,
+			DeliveredTs
+		FROM
+			outbox
+		WHERE
+			(? = '' OR Status = ?)
+		ORDER BY
+			MessageId DESC
+		LIMIT ?
+	`
+}
+
+func outboxUpdateQuery() string {
+	return `
+		UPDATE
+			outbox
+		SET
+			Status = ?,
+			Attempts = ?,
+			DeliveredChannels = ?,
+			LastError = ?,
+			NextAttemptTs = ?,
+			DeliveredTs = ?
+		WHERE
+			MessageId = ?
+	`
+}
+
+func outboxRequeueQuery() string {
+	return `
+		UPDATE
+			outbox
+		SET
+			Status = ?,
+			Attempts = 0,
+			NextAttemptTs = ?
+		WHERE
+			MessageId = ?
+			AND Status = ?
+	`
+}
+
+func outboxDeleteDeliveredQuery() string {
+	return `
+		DELETE FROM
+			outbox
+		WHERE
+			Status = ?
+			AND DeliveredTs < ?
+	`
+}
@@ -0,0 +1,121 @@
+package db
+
+import (
+	"github.com/rs/zerolog/log"
+)
+
+const dbPermissionsPrefix = "db/permissions"
+
+// UserPermission represents access level of a user to single module.
+type UserPermission struct {
+	UserId int
+	Module string
+	Access string
+}
+
+// UserPermissions reads all module permissions of given user.
+func (c *Client) UserPermissions(userId int) ([]UserPermission, error) {
+	permissions := make([]UserPermission, 0, 4)
+	rows, qErr := c.dbConn.Query(userPermissionsQuery(), userId)
+	if qErr != nil {
+		log.Error().Err(qErr).Int("userId", userId).Msgf("[%s] userPermissionsQuery failed", dbPermissionsPrefix)
+		return permissions, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p UserPermission
+		if sErr := rows.Scan(&p.UserId, &p.Module, &p.Access); sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of userPermissionsQuery", dbPermissionsPrefix)
+			continue
+		}
+		permissions = append(permissions, p)
+	}
+	return permissions, nil
+}
+
+// UserPermissionSet sets access level of given user to the module.
+func (c *Client) UserPermissionSet(userId int, module, access string) error {
+	_, execErr := c.dbConn.Exec(userPermissionUpsertQuery(), userId, module, access)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Str("module", module).
+			Msgf("[%s] cannot set user permission", dbPermissionsPrefix)
+	}
+	return execErr
+}
+
+// UserPermissionDelete removes access of given user to the module.
+func (c *Client) UserPermissionDelete(userId int, module string) error {
+	_, execErr := c.dbConn.Exec(userPermissionDeleteQuery(), userId, module)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Str("module", module).
+			Msgf("[%s] cannot delete user permission", dbPermissionsPrefix)
+	}
+	return execErr
+}
+
+// UserRoleSet grants or revokes role of given user.
+func (c *Client) UserRoleSet(userId int, role string, isGranted bool) error {
+	query := userRoleDeleteQuery()
+	if isGranted {
+		query = userRoleGrantQuery()
+	}
+	_, execErr := c.dbConn.Exec(query, userId, role)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Str("role", role).Bool("isGranted", isGranted).
+			Msgf("[%s] cannot set user role", dbPermissionsPrefix)
+	}
+	return execErr
+}
+
+func userPermissionsQuery() string {
+	return `
+		SELECT
+			UserId,
+			Module,
+			Access
+		FROM
+			userPermissions
+		WHERE
+			UserId = ?
+		ORDER BY
+			Module
+	`
+}
+
+func userPermissionUpsertQuery() string {
+	return `
+		INSERT INTO userPermissions (UserId, Module, Access)
+		VALUES (?, ?, ?)
+		ON CONFLICT (UserId, Module) DO UPDATE SET
+			Access = excluded.Access
+	`
+}
+
+func userPermissionDeleteQuery() string {
+	return `
+		DELETE FROM
+			userPermissions
+		WHERE
+			UserId = ?
+			AND Module = ?
+	`
+}
+
+func userRoleGrantQuery() string {
+	return `
+		INSERT INTO userRoles (UserId, Role)
+		VALUES (?, ?)
+		ON CONFLICT (UserId, Role) DO NOTHING
+	`
+}
+
+func userRoleDeleteQuery() string {
+	return `
+		DELETE FROM
+			userRoles
+		WHERE
+			UserId = ?
+			AND Role = ?
+	`
+}
@@ -0,0 +1,239 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbSessPrefix = "db/sessions"
+
+// Session represents server-side state of user session. SessionId is the
+// same as "jti" claim of session JWT.
+type Session struct {
+	SessionId  string
+	UserId     int
+	UserAgent  string
+	IpAddress  string
+	CsrfToken  string
+	CreateTs   string
+	LastSeenTs string
+	ExpireTs   string
+	RevokeTs   *string
+}
+
+// SessionInsertNew inserts new user session.
+func (c *Client) SessionInsertNew(s Session) error {
+	startTs := time.Now()
+	log.Info().Int("userId", s.UserId).Msgf("[%s] start inserting new session", dbSessPrefix)
+
+	_, execErr := c.dbConn.Exec(sessionInsertQuery(), s.SessionId, s.UserId, s.UserAgent, s.IpAddress,
+		s.CsrfToken, s.CreateTs, s.LastSeenTs, s.ExpireTs)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", s.UserId).Msgf("[%s] cannot insert new session", dbSessPrefix)
+		return execErr
+	}
+
+	log.Info().Int("userId", s.UserId).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished inserting new session", dbSessPrefix)
+	return nil
+}
+
+// SessionById reads session of given ID. If session does not exist
+// sql.ErrNoRows is returned.
+func (c *Client) SessionById(sessionId string) (Session, error) {
+	var s Session
+	row := c.dbConn.QueryRow(sessionByIdQuery(), sessionId)
+	scanErr := row.Scan(&s.SessionId, &s.UserId, &s.UserAgent, &s.IpAddress, &s.CsrfToken, &s.CreateTs,
+		&s.LastSeenTs, &s.ExpireTs, &s.RevokeTs)
+
+	switch scanErr {
+	case nil:
+		return s, nil
+	case sql.ErrNoRows:
+		log.Warn().Msgf("[%s] session does not exist", dbSessPrefix)
+		return Session{}, scanErr
+	default:
+		log.Error().Err(scanErr).Msgf("[%s] cannot read session", dbSessPrefix)
+		return Session{}, scanErr
+	}
+}
+
+// SessionsActive reads all not revoked and not expired sessions of given
+// user, ordered from the most recently seen.
+func (c *Client) SessionsActive(userId int, nowTs string) ([]Session, error) {
+	startTs := time.Now()
+	sessions := make([]Session, 0, 10)
+	log.Info().Int("userId", userId).Msgf("[%s] start reading active sessions", dbSessPrefix)
+
+	rows, qErr := c.dbConn.Query(sessionsActiveQuery(), userId, nowTs)
+	if qErr != nil {
+		log.Error().Err(qErr).Int("userId", userId).Msgf("[%s] sessionsActiveQuery failed", dbSessPrefix)
+		return sessions, qErr
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var s Session
+		sErr := rows.Scan(&s.SessionId, &s.UserId, &s.UserAgent, &s.IpAddress, &s.CsrfToken, &s.CreateTs,
+			&s.LastSeenTs, &s.ExpireTs, &s.RevokeTs)
+		if sErr != nil {
+			log.Warn().Err(sErr).Msgf("[%s] while scanning results of sessionsActiveQuery", dbSessPrefix)
+			continue
+		}
+		sessions = append(sessions, s)
+	}
+
+	log.Info().Int("userId", userId).Int("rowsLoaded", len(sessions)).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished reading active sessions", dbSessPrefix)
+	return sessions, nil
+}
+
+// SessionUpdateLastSeen sets session last seen timestamp.
+func (c *Client) SessionUpdateLastSeen(sessionId, lastSeenTs string) error {
+	_, execErr := c.dbConn.Exec(sessionUpdateLastSeenQuery(), lastSeenTs, sessionId)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot update session last seen timestamp", dbSessPrefix)
+	}
+	return execErr
+}
+
+// SessionProlong sets new expiration timestamp for given session.
+func (c *Client) SessionProlong(sessionId, expireTs string) error {
+	_, execErr := c.dbConn.Exec(sessionProlongQuery(), expireTs, sessionId)
+	if execErr != nil {
+		log.Error().Err(execErr).Msgf("[%s] cannot prolong session", dbSessPrefix)
+	}
+	return execErr
+}
+
+// SessionRevoke revokes single session of given user.
+func (c *Client) SessionRevoke(userId int, sessionId, revokeTs string) error {
+	log.Info().Int("userId", userId).Msgf("[%s] start revoking session", dbSessPrefix)
+
+	_, execErr := c.dbConn.Exec(sessionRevokeQuery(), revokeTs, userId, sessionId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot revoke session", dbSessPrefix)
+		return execErr
+	}
+
+	log.Info().Int("userId", userId).Msgf("[%s] session revoked", dbSessPrefix)
+	return nil
+}
+
+// SessionsRevokeAll revokes all sessions of given user except the one with
+// exceptSessionId. Empty exceptSessionId means that all sessions would be
+// revoked.
+func (c *Client) SessionsRevokeAll(userId int, revokeTs, exceptSessionId string) error {
+	log.Info().Int("userId", userId).Msgf("[%s] start revoking all user sessions", dbSessPrefix)
+
+	res, execErr := c.dbConn.Exec(sessionsRevokeAllQuery(), revokeTs, userId, exceptSessionId)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot revoke user sessions", dbSessPrefix)
+		return execErr
+	}
+
+	revoked, _ := res.RowsAffected()
+	log.Info().Int("userId", userId).Int64("revoked", revoked).Msgf("[%s] user sessions revoked", dbSessPrefix)
+	return nil
+}
+
+func sessionInsertQuery() string {
+	return `
+		INSERT INTO sessions (
+			SessionId, UserId, UserAgent, IpAddress, CsrfToken, CreateTs, LastSeenTs, ExpireTs
+		)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+}
+
+func sessionByIdQuery() string {
+	return `
+		SELECT
+			SessionId,
+			UserId,
+			UserAgent,
+			IpAddress,
+			CsrfToken,
+			CreateTs,
+			LastSeenTs,
+			ExpireTs,
+			RevokeTs
+		FROM
+			sessions
+		WHERE
+			SessionId = ?
+	`
+}
+
+func sessionsActiveQuery() string {
+	return `
+		SELECT
+			SessionId,
+			UserId,
+			UserAgent,
+			IpAddress,
+			CsrfToken,
+			CreateTs,
+			LastSeenTs,
+			ExpireTs,
+			RevokeTs
+		FROM
+			sessions
+		WHERE
+			UserId = ?
+			AND RevokeTs IS NULL
+			AND ExpireTs > ?
+		ORDER BY
+			LastSeenTs DESC
+	`
+}
+
+func sessionUpdateLastSeenQuery() string {
+	return `
+		UPDATE
+			sessions
+		SET
+			LastSeenTs = ?
+		WHERE
+			SessionId = ?
+	`
+}
+
+func sessionProlongQuery() string {
+	return `
+		UPDATE
+			sessions
+		SET
+			ExpireTs = ?
+		WHERE
+			SessionId = ?
+	`
+}
+
+func sessionRevokeQuery() string {
+	return `
+		UPDATE
+			sessions
+		SET
+			RevokeTs = ?
+		WHERE
+			UserId = ?
+			AND SessionId = ?
+			AND RevokeTs IS NULL
+	`
+}
+
+func sessionsRevokeAllQuery() string {
+	return `
+		UPDATE
+			sessions
+		SET
+			RevokeTs = ?
+		WHERE
+			UserId = ?
+			AND SessionId != ?
+			AND RevokeTs IS NULL
+	`
+}
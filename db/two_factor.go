@@ -0,0 +1,223 @@
+package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const dbTwoFactorPrefix = "db/twoFactor"
+
+// UserTwoFactor represents second factor settings of a user. TOTP secrets are
+// stored encrypted. TotpPendingSecretEncrypted is set during TOTP enrollment,
+// before user confirms it with the first code.
+type UserTwoFactor struct {
+	UserId                     int
+	Method                     string
+	TotpSecretEncrypted        []byte
+	TotpPendingSecretEncrypted []byte
+	TotpLastCounter            uint64
+	UpdateTs                   string
+}
+
+// UserTwoFactorById reads second factor settings of given user. If user has
+// no settings, then sql.ErrNoRows is returned.
+func (c *Client) UserTwoFactorById(userId int) (UserTwoFactor, error) {
+	var tf UserTwoFactor
+	row := c.dbConn.QueryRow(userTwoFactorByIdQuery(), userId)
+	scanErr := row.Scan(&tf.UserId, &tf.Method, &tf.TotpSecretEncrypted, &tf.TotpPendingSecretEncrypted,
+		&tf.TotpLastCounter, &tf.UpdateTs)
+
+	switch scanErr {
+	case nil:
+		return tf, nil
+	case sql.ErrNoRows:
+		return UserTwoFactor{}, scanErr
+	default:
+		log.Error().Err(scanErr).Int("userId", userId).
+			Msgf("[%s] cannot read user second factor settings", dbTwoFactorPrefix)
+		return UserTwoFactor{}, scanErr
+	}
+}
+
+// UserTwoFactorUpsert inserts or replaces second factor settings of a user.
+func (c *Client) UserTwoFactorUpsert(tf UserTwoFactor) error {
+	startTs := time.Now()
+	log.Info().Int("userId", tf.UserId).Str("method", tf.Method).
+		Msgf("[%s] start updating user second factor settings", dbTwoFactorPrefix)
+
+	_, execErr := c.dbConn.Exec(userTwoFactorUpsertQuery(), tf.UserId, tf.Method, tf.TotpSecretEncrypted,
+		tf.TotpPendingSecretEncrypted, tf.TotpLastCounter, tf.UpdateTs)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", tf.UserId).
+			Msgf("[%s] cannot update user second factor settings", dbTwoFactorPrefix)
+		return execErr
+	}
+
+	log.Info().Int("userId", tf.UserId).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished updating user second factor settings", dbTwoFactorPrefix)
+	return nil
+}
+
+// UserTotpCounterUpdate sets counter of the last used TOTP code. Counter is
+// updated only if it's greater than the stored one, so the same code cannot
+// be used twice by concurrent logins. Returns false if counter was not
+// updated.
+func (c *Client) UserTotpCounterUpdate(userId int, counter uint64) (bool, error) {
+	res, execErr := c.dbConn.Exec(userTotpCounterUpdateQuery(), counter, userId, counter)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot update TOTP counter", dbTwoFactorPrefix)
+		return false, execErr
+	}
+	updated, _ := res.RowsAffected()
+	return updated == 1, nil
+}
+
+// RecoveryCodesReplace deletes all recovery codes of given user and inserts
+// new ones (already hashed).
+func (c *Client) RecoveryCodesReplace(userId int, codesHashed []string, createTs string) error {
+	startTs := time.Now()
+	log.Info().Int("userId", userId).Msgf("[%s] start replacing recovery codes", dbTwoFactorPrefix)
+
+	tx, tErr := c.dbConn.Begin()
+	if tErr != nil {
+		log.Error().Err(tErr).Msgf("[%s] cannot start new transaction", dbTwoFactorPrefix)
+		return tErr
+	}
+
+	_, dErr := tx.Exec(recoveryCodesDeleteQuery(), userId)
+	if dErr != nil {
+		log.Error().Err(dErr).Int("userId", userId).Msgf("[%s] cannot delete recovery codes", dbTwoFactorPrefix)
+		tx.Rollback()
+		return dErr
+	}
+
+	for _, codeHashed := range codesHashed {
+		_, iErr := tx.Exec(recoveryCodeInsertQuery(), userId, codeHashed, createTs)
+		if iErr != nil {
+			log.Error().Err(iErr).Int("userId", userId).Msgf("[%s] cannot insert recovery code", dbTwoFactorPrefix)
+			tx.Rollback()
+			return iErr
+		}
+	}
+
+	commErr := tx.Commit()
+	if commErr != nil {
+		log.Error().Err(commErr).Msgf("[%s] could not commit transaction, rollback.", dbTwoFactorPrefix)
+		tx.Rollback()
+		return commErr
+	}
+
+	log.Info().Int("userId", userId).Int("codes", len(codesHashed)).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] finished replacing recovery codes", dbTwoFactorPrefix)
+	return nil
+}
+
+// RecoveryCodeUse marks not yet used recovery code as used. Returns false if
+// there is no such unused code for given user.
+func (c *Client) RecoveryCodeUse(userId int, codeHashed, usedTs string) (bool, error) {
+	res, execErr := c.dbConn.Exec(recoveryCodeUseQuery(), usedTs, userId, codeHashed)
+	if execErr != nil {
+		log.Error().Err(execErr).Int("userId", userId).Msgf("[%s] cannot use recovery code", dbTwoFactorPrefix)
+		return false, execErr
+	}
+	updated, _ := res.RowsAffected()
+	return updated == 1, nil
+}
+
+// RecoveryCodesUnused counts not yet used recovery codes of given user.
+func (c *Client) RecoveryCodesUnused(userId int) (int, error) {
+	var count int
+	row := c.dbConn.QueryRow(recoveryCodesUnusedQuery(), userId)
+	scanErr := row.Scan(&count)
+	if scanErr != nil {
+		log.Error().Err(scanErr).Int("userId", userId).Msgf("[%s] cannot count recovery codes", dbTwoFactorPrefix)
+		return 0, scanErr
+	}
+	return count, nil
+}
+
+func userTwoFactorByIdQuery() string {
+	return `
+		SELECT
+			UserId,
+			Method,
+			TotpSecretEncrypted,
+			TotpPendingSecretEncrypted,
+			TotpLastCounter,
+			UpdateTs
+		FROM
+			userTwoFactor
+		WHERE
+			UserId = ?
+	`
+}
+
+func userTwoFactorUpsertQuery() string {
+	return `
+		INSERT INTO userTwoFactor (
+			UserId, Method, TotpSecretEncrypted, TotpPendingSecretEncrypted, TotpLastCounter, UpdateTs
+		)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (UserId) DO UPDATE SET
+			Method = excluded.Method,
+			TotpSecretEncrypted = excluded.TotpSecretEncrypted,
+			TotpPendingSecretEncrypted = excluded.TotpPendingSecretEncrypted,
+			TotpLastCounter = excluded.TotpLastCounter,
+			UpdateTs = excluded.UpdateTs
+	`
+}
+
+func userTotpCounterUpdateQuery() string {
+	return `
+		UPDATE
+			userTwoFactor
+		SET
+			TotpLastCounter = ?
+		WHERE
+			UserId = ?
+			AND TotpLastCounter < ?
+	`
+}
+
+func recoveryCodesDeleteQuery() string {
+	return `
+		DELETE FROM
+			userRecoveryCodes
+		WHERE
+			UserId = ?
+	`
+}
+
+func recoveryCodeInsertQuery() string {
+	return `
+		INSERT INTO userRecoveryCodes (UserId, CodeHashed, CreateTs)
+		VALUES (?, ?, ?)
+	`
+}
+
+func recoveryCodeUseQuery() string {
+	return `
+		UPDATE
+			userRecoveryCodes
+		SET
+			UsedTs = ?
+		WHERE
+			UserId = ?
+			AND CodeHashed = ?
+			AND UsedTs IS NULL
+	`
+}
+
+func recoveryCodesUnusedQuery() string {
+	return `
+		SELECT
+			COUNT(*)
+		FROM
+			userRecoveryCodes
+		WHERE
+			UserId = ?
+			AND UsedTs IS NULL
+	`
+}
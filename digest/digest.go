@@ -0,0 +1,134 @@
+// Package digest composes periodic digests (finance, counters, new documents
+// and books, expiring documents) from Home DB and sends them as notifications
+// on cron-like schedules.
+package digest
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"homeApp/db"
+	"homeApp/finance"
+
+	"github.com/rs/zerolog/log"
+)
+
+const digestPrefix = "digest"
+
+// Kinds of digests. They differ in covered period and template.
+const (
+	Weekly  = "weekly"  // Previous 7 days
+	Monthly = "monthly" // Previous calendar month
+)
+
+// Kinds lists known kinds of digests.
+var Kinds = []string{Weekly, Monthly}
+
+const (
+	dateLayout             = "2006-01-02"
+	monthLayout            = "2006-01"
+	financeDefaultCurrency = "PLN"
+)
+
+// Data is passed to the digest template.
+type Data struct {
+	Kind string
+	From string // First day of the period
+	To   string // Last day of the period
+
+	FinanceMonth string
+	Finance      *finance.MonthlyAgg // Nil when there are no transactions in FinanceMonth
+	Counters     *db.HomeSummary     // Nil when there is not enough counters data
+
+	NewDocuments      []db.DocumentInfo
+	NewBooks          []db.Book
+	ExpiringDocuments []db.DocumentInfo
+	ExpiryDays        int
+}
+
+// Composer reads digest data from the database.
+type Composer struct {
+	DbClient   *db.Client
+	ExpiryDays int // Documents expiring within this many days are listed
+}
+
+// Compose reads data of given kind of digest for period ending before now.
+// Finance section always covers the previous calendar month.
+func (c *Composer) Compose(kind string, now time.Time) (Data, error) {
+	startTs := time.Now()
+	from, to, pErr := period(kind, now)
+	if pErr != nil {
+		return Data{}, pErr
+	}
+	financeMonth := firstDayOfMonth(now).AddDate(0, -1, 0)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	data := Data{
+		Kind:         kind,
+		From:         from.Format(dateLayout),
+		To:           to.AddDate(0, 0, -1).Format(dateLayout),
+		FinanceMonth: financeMonth.Format(monthLayout),
+		ExpiryDays:   c.ExpiryDays,
+	}
+
+	transactions, tErr := c.DbClient.FinTransMonthly(financeMonth.Year(), int(financeMonth.Month()))
+	if tErr != nil {
+		return Data{}, fmt.Errorf("cannot read financial transactions: %w", tErr)
+	}
+	for _, agg := range finance.AggregateMonthly(transactions, financeDefaultCurrency) {
+		monthAgg := agg
+		data.Finance = &monthAgg
+	}
+
+	// Counters section is skipped when there is not enough counters data
+	summary, sErr := c.DbClient.HomeSummary()
+	if sErr != nil && !errors.Is(sErr, sql.ErrNoRows) {
+		log.Warn().Err(sErr).Msgf("[%s] cannot read counters summary, skipping the section", digestPrefix)
+	}
+	if sErr == nil {
+		data.Counters = &summary
+	}
+
+	docs, dErr := c.DbClient.DocumentsUploadedBetween(from.Format(dateLayout), to.Format(dateLayout))
+	if dErr != nil {
+		return Data{}, fmt.Errorf("cannot read new documents: %w", dErr)
+	}
+	data.NewDocuments = docs
+
+	books, bErr := c.DbClient.BooksUploadedBetween(from.Format(dateLayout), to.Format(dateLayout))
+	if bErr != nil {
+		return Data{}, fmt.Errorf("cannot read new books: %w", bErr)
+	}
+	data.NewBooks = books
+
+	expiring, eErr := c.DbClient.DocumentsExpiringBetween(today.Format(dateLayout),
+		today.AddDate(0, 0, c.ExpiryDays).Format(dateLayout))
+	if eErr != nil {
+		return Data{}, fmt.Errorf("cannot read expiring documents: %w", eErr)
+	}
+	data.ExpiringDocuments = expiring
+
+	log.Info().Str("kind", kind).Dur("duration", time.Since(startTs)).
+		Msgf("[%s] composed digest for %s - %s", digestPrefix, data.From, data.To)
+	return data, nil
+}
+
+// Period of given kind of digest ending before now. Returned to date is
+// exclusive.
+func period(kind string, now time.Time) (time.Time, time.Time, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	switch kind {
+	case Weekly:
+		return today.AddDate(0, 0, -7), today, nil
+	case Monthly:
+		thisMonth := firstDayOfMonth(now)
+		return thisMonth.AddDate(0, -1, 0), thisMonth, nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("unknown digest kind [%s]", kind)
+}
+
+func firstDayOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
@@ -0,0 +1,95 @@
+package digest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"homeApp/db"
+	"homeApp/finance"
+)
+
+func TestPeriod(t *testing.T) {
+	now := time.Date(2024, 3, 4, 8, 0, 0, 0, time.UTC)
+	from, to, _ := period(Weekly, now)
+	if from.Format(dateLayout) != "2024-02-26" || to.Format(dateLayout) != "2024-03-04" {
+		t.Errorf("unexpected weekly period %v - %v", from, to)
+	}
+	from, to, _ = period(Monthly, now)
+	if from.Format(dateLayout) != "2024-02-01" || to.Format(dateLayout) != "2024-03-01" {
+		t.Errorf("unexpected monthly period %v - %v", from, to)
+	}
+	if _, _, err := period("daily", now); err == nil {
+		t.Error("expected error for unknown kind")
+	}
+}
+
+func TestRenderDefaultTemplates(t *testing.T) {
+	templates, lErr := LoadTemplates("")
+	if lErr != nil {
+		t.Fatalf("unexpected error: %v", lErr)
+	}
+	expiry := "2024-03-20"
+	data := Data{
+		Kind:         Monthly,
+		From:         "2024-02-01",
+		To:           "2024-02-29",
+		FinanceMonth: "2024-02",
+		Finance: &finance.MonthlyAgg{NumOfTransactions: 3, NumOfOutflows: 2, OutflowsAmountSum: -150.5,
+			TopOutflow: db.BankTransaction{AmountValue: -100, Description: "Rent"}},
+		Counters:          &db.HomeSummary{ColdWaterWeeklyAvg: 700, HotWaterWeeklyAvg: 300, EnergyWeeklyAvg: 35.25},
+		NewDocuments:      []db.DocumentInfo{{Name: "Umowa najmu", Category: "mieszkanie"}},
+		ExpiringDocuments: []db.DocumentInfo{{Name: "Passport", Category: "personalne", ExpiryDate: &expiry}},
+		ExpiryDays:        30,
+	}
+
+	text, rErr := templates.Render(data)
+	if rErr != nil {
+		t.Fatalf("unexpected error: %v", rErr)
+	}
+	for _, expected := range []string{
+		"Period: 2024-02-01 - 2024-02-29\n\nFinance 2024-02\nTransactions: 3\n",
+		"Top outflow: -100.00 zł Rent\n\nCounters (weekly avg): cold water 700.00 l",
+		"energy 35.25 kWh\n\nNew documents: 1\n- Umowa najmu [mieszkanie]\nNew books: 0\n\n",
+		"Documents expiring within 30 days:\n- Passport [personalne] on 2024-03-20",
+	} {
+		if !strings.Contains(text, expected) {
+			t.Errorf("expected [%s] in digest:\n%s", expected, text)
+		}
+	}
+
+	data = Data{Kind: Weekly, From: "2024-02-26", To: "2024-03-03"}
+	text, rErr = templates.Render(data)
+	if rErr != nil || text != "Period: 2024-02-26 - 2024-03-03\n\nNo new documents and books." {
+		t.Errorf("unexpected weekly digest (error %v):\n%s", rErr, text)
+	}
+}
+
+func TestOverrideTemplate(t *testing.T) {
+	dir := t.TempDir()
+	content := `Week {{ .From }}{{ template "expiring" . }}`
+	if wErr := os.WriteFile(filepath.Join(dir, "weekly.tmpl"), []byte(content), 0600); wErr != nil {
+		t.Fatal(wErr)
+	}
+	templates, lErr := LoadTemplates(dir)
+	if lErr != nil {
+		t.Fatalf("unexpected error: %v", lErr)
+	}
+	text, _ := templates.Render(Data{Kind: Weekly, From: "2024-02-26"})
+	if text != "Week 2024-02-26" {
+		t.Errorf("expected overridden template, got [%s]", text)
+	}
+	text, _ = templates.Render(Data{Kind: Monthly, From: "2024-02-01"})
+	if !strings.HasPrefix(text, "Period: 2024-02-01") {
+		t.Errorf("expected default monthly template, got [%s]", text)
+	}
+
+	if wErr := os.WriteFile(filepath.Join(dir, "monthly.tmpl"), []byte("{{ .Missing"), 0600); wErr != nil {
+		t.Fatal(wErr)
+	}
+	if _, err := LoadTemplates(dir); err == nil {
+		t.Error("expected error for incorrect template")
+	}
+}
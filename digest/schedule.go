@@ -0,0 +1,156 @@
+package digest
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Schedule is a cron-like schedule with five fields: minute, hour, day of
+// month, month and day of week (0 or 7 is Sunday). Fields support "*", single
+// values, ranges ("1-5"), lists ("1,15") and steps ("*/15", "0-30/10"). As in
+// cron, when both day of month and day of week are restricted, time matches
+// when either of them matches. Day field starting with "*" (also "*/2") isn't
+// restricted. Macros @daily, @weekly and @monthly are supported as well.
+type Schedule struct {
+	spec       string
+	minutes    uint64
+	hours      uint64
+	days       uint64
+	months     uint64
+	weekdays   uint64
+	anyDay     bool
+	anyWeekday bool
+}
+
+var scheduleMacros = map[string]string{
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+}
+
+// Next matching time is searched at most this far.
+const maxScheduleLookahead = 5 * 366 * 24 * time.Hour
+
+// ParseSchedule parses cron-like schedule, e.g. "0 8 * * 1" (Mondays at
+// 8:00).
+func ParseSchedule(spec string) (Schedule, error) {
+	fieldsSpec := strings.TrimSpace(spec)
+	if macro, exists := scheduleMacros[fieldsSpec]; exists {
+		fieldsSpec = macro
+	}
+	fields := strings.Fields(fieldsSpec)
+	if len(fields) != 5 {
+		return Schedule{}, fmt.Errorf("schedule [%s] should have 5 fields (minute hour day month weekday)", spec)
+	}
+
+	s := Schedule{
+		spec:       spec,
+		anyDay:     strings.HasPrefix(fields[2], "*"),
+		anyWeekday: strings.HasPrefix(fields[4], "*"),
+	}
+	bounds := []struct {
+		name     string
+		min, max int
+		bits     *uint64
+	}{
+		{"minute", 0, 59, &s.minutes},
+		{"hour", 0, 23, &s.hours},
+		{"day of month", 1, 31, &s.days},
+		{"month", 1, 12, &s.months},
+		{"day of week", 0, 7, &s.weekdays},
+	}
+	for idx, b := range bounds {
+		bits, pErr := parseScheduleField(fields[idx], b.min, b.max)
+		if pErr != nil {
+			return Schedule{}, fmt.Errorf("incorrect %s in schedule [%s]: %v", b.name, spec, pErr)
+		}
+		*b.bits = bits
+	}
+	if s.weekdays&(1<<7) != 0 {
+		s.weekdays = s.weekdays | 1
+	}
+	return s, nil
+}
+
+// String returns schedule as it was given.
+func (s Schedule) String() string {
+	return s.spec
+}
+
+// Next returns the first matching time (with minute precision) after given
+// time. Zero time is returned when schedule never matches, e.g. for 31st of
+// February.
+func (s Schedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+	limit := after.Add(maxScheduleLookahead)
+	for t.Before(limit) {
+		if s.months&(1<<uint(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if !s.dayMatches(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.hours&(1<<uint(t.Hour())) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+			continue
+		}
+		if s.minutes&(1<<uint(t.Minute())) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+		return t
+	}
+	return time.Time{}
+}
+
+func (s Schedule) dayMatches(t time.Time) bool {
+	dayMatch := s.days&(1<<uint(t.Day())) != 0
+	weekdayMatch := s.weekdays&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return dayMatch && weekdayMatch
+	}
+	return dayMatch || weekdayMatch
+}
+
+// Parses single schedule field into bit set of matching values.
+func parseScheduleField(field string, min, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangeSpec, stepSpec, hasStep := strings.Cut(item, "/")
+		step := 1
+		if hasStep {
+			parsedStep, sErr := strconv.Atoi(stepSpec)
+			if sErr != nil || parsedStep < 1 {
+				return 0, fmt.Errorf("incorrect step [%s]", stepSpec)
+			}
+			step = parsedStep
+		}
+
+		from, to := min, max
+		if rangeSpec != "*" {
+			fromSpec, toSpec, isRange := strings.Cut(rangeSpec, "-")
+			var fErr, tErr error
+			from, fErr = strconv.Atoi(fromSpec)
+			to = from
+			if isRange {
+				to, tErr = strconv.Atoi(toSpec)
+			} else if hasStep {
+				to = max
+			}
+			if fErr != nil || tErr != nil {
+				return 0, fmt.Errorf("incorrect value [%s]", rangeSpec)
+			}
+			if from < min || to > max || from > to {
+				return 0, fmt.Errorf("value [%s] is out of range %d-%d", rangeSpec, min, max)
+			}
+		}
+		for v := from; v <= to; v += step {
+			bits = bits | 1<<uint(v)
+		}
+	}
+	return bits, nil
+}
@@ -0,0 +1,45 @@
+package digest
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	after := time.Date(2024, 5, 1, 10, 30, 15, 0, time.UTC) // Wednesday
+	cases := []struct {
+		spec     string
+		expected time.Time
+	}{
+		{"0 8 * * 1", time.Date(2024, 5, 6, 8, 0, 0, 0, time.UTC)},
+		{"0 8 1 * *", time.Date(2024, 6, 1, 8, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 5, 1, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, 5, 2, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2024, 5, 1, 13, 0, 0, 0, time.UTC)},
+		{"0 0 15 * 7", time.Date(2024, 5, 5, 0, 0, 0, 0, time.UTC)}, // Sunday before 15th
+		{"0 0 15 * */1", time.Date(2024, 5, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 0 */2 * 1", time.Date(2024, 5, 13, 0, 0, 0, 0, time.UTC)}, // odd day and Monday
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 31 2 *", time.Time{}},
+	}
+	for _, c := range cases {
+		s, pErr := ParseSchedule(c.spec)
+		if pErr != nil {
+			t.Errorf("[%s] unexpected error: %v", c.spec, pErr)
+			continue
+		}
+		if got := s.Next(after); !got.Equal(c.expected) {
+			t.Errorf("[%s] expected %v, got %v", c.spec, c.expected, got)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, spec := range []string{"", "0 8 * *", "60 * * * *", "0 8 0 * *", "0 8 * * 8", "*/0 * * * *",
+		"5-1 * * * *", "a * * * *", "@yearly"} {
+		if _, err := ParseSchedule(spec); err == nil {
+			t.Errorf("expected error for schedule [%s]", spec)
+		}
+	}
+}
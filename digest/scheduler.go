@@ -0,0 +1,70 @@
+package digest
+
+import (
+	"fmt"
+	"time"
+
+	"homeApp/notify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Digest is a kind of digest sent on given schedule.
+type Digest struct {
+	Kind     string
+	Schedule Schedule
+}
+
+// Scheduler sends digests as notifications of digest event type, so channels
+// are chosen by notification routing rules.
+type Scheduler struct {
+	Composer  *Composer
+	Templates *Templates
+	Notifier  notify.Notifier
+	Digests   []Digest
+}
+
+// Start starts goroutine for each scheduled digest.
+func (s *Scheduler) Start() {
+	for _, d := range s.Digests {
+		go s.run(d)
+	}
+}
+
+// Send composes, renders and sends digest of given kind for period ending
+// before now.
+func (s *Scheduler) Send(kind string, now time.Time) error {
+	data, cErr := s.Composer.Compose(kind, now)
+	if cErr != nil {
+		return cErr
+	}
+	text, rErr := s.Templates.Render(data)
+	if rErr != nil {
+		return fmt.Errorf("cannot render %s digest: %w", kind, rErr)
+	}
+	return s.Notifier.Notify(notify.Message{
+		Event: notify.EventDigest,
+		Title: fmt.Sprintf("HomeApp %s digest", kind),
+		Text:  text,
+	})
+}
+
+func (s *Scheduler) run(d Digest) {
+	log.Info().Str("kind", d.Kind).Str("schedule", d.Schedule.String()).
+		Msgf("[%s] digest scheduled", digestPrefix)
+	for {
+		next := d.Schedule.Next(time.Now())
+		if next.IsZero() {
+			log.Error().Str("kind", d.Kind).Str("schedule", d.Schedule.String()).
+				Msgf("[%s] schedule never matches, digest won't be sent", digestPrefix)
+			return
+		}
+		time.Sleep(time.Until(next))
+
+		if sErr := s.Send(d.Kind, next); sErr != nil {
+			log.Error().Err(sErr).Str("kind", d.Kind).Msgf("[%s] sending digest failed", digestPrefix)
+			continue
+		}
+		log.Info().Str("kind", d.Kind).Msgf("[%s] digest sent", digestPrefix)
+	}
+}
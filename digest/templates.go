@@ -0,0 +1,83 @@
+package digest
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/template"
+)
+
+const commonTemplateFile = "common.tmpl"
+
+//go:embed templates/*.tmpl
+var defaultTemplates embed.FS
+
+// Templates renders digests using text templates, one per digest kind
+// (weekly.tmpl, monthly.tmpl) plus common.tmpl with shared "uploads" and
+// "expiring" blocks.
+type Templates struct {
+	byKind map[string]*template.Template
+}
+
+// LoadTemplates parses default templates. Files with the same name in
+// overrideDir (if not empty) are used instead of defaults, so single template
+// or only common blocks can be customized.
+func LoadTemplates(overrideDir string) (*Templates, error) {
+	templates := &Templates{byKind: make(map[string]*template.Template)}
+	common, cErr := readTemplate(overrideDir, commonTemplateFile)
+	if cErr != nil {
+		return nil, cErr
+	}
+	for _, kind := range Kinds {
+		content, rErr := readTemplate(overrideDir, kind+".tmpl")
+		if rErr != nil {
+			return nil, rErr
+		}
+		tmpl, pErr := template.New(kind).Parse(content)
+		if pErr != nil {
+			return nil, fmt.Errorf("cannot parse %s digest template: %w", kind, pErr)
+		}
+		if _, pErr := tmpl.New(commonTemplateFile).Parse(common); pErr != nil {
+			return nil, fmt.Errorf("cannot parse common digest template: %w", pErr)
+		}
+		templates.byKind[kind] = tmpl
+	}
+	return templates, nil
+}
+
+// Render renders digest of data.Kind.
+func (t *Templates) Render(data Data) (string, error) {
+	tmpl, exists := t.byKind[data.Kind]
+	if !exists {
+		return "", fmt.Errorf("there is no template for digest kind [%s]", data.Kind)
+	}
+	var buf bytes.Buffer
+	if eErr := tmpl.Execute(&buf, data); eErr != nil {
+		return "", eErr
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
+
+// Reads template from override directory if it exists there, otherwise
+// default one is used.
+func readTemplate(overrideDir, name string) (string, error) {
+	if overrideDir != "" {
+		content, rErr := os.ReadFile(filepath.Join(overrideDir, name))
+		if rErr == nil {
+			return string(content), nil
+		}
+		if !errors.Is(rErr, fs.ErrNotExist) {
+			return "", fmt.Errorf("cannot read digest template: %w", rErr)
+		}
+	}
+	content, rErr := defaultTemplates.ReadFile("templates/" + name)
+	if rErr != nil {
+		return "", rErr
+	}
+	return string(content), nil
+}
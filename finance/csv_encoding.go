@@ -0,0 +1,90 @@
+package finance
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Encodings of CSV files. Polish banks often export files in Windows-1250 or
+// ISO-8859-2 instead of UTF-8.
+const (
+	EncodingUtf8     = "utf-8"
+	EncodingCp1250   = "cp1250"
+	EncodingIso88592 = "iso-8859-2"
+)
+
+// Encodings lists supported encodings of CSV files.
+var Encodings = []string{EncodingUtf8, EncodingCp1250, EncodingIso88592}
+
+var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Decodes text in given encoding into UTF-8 string.
+func decodeText(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case EncodingUtf8:
+		data = bytes.TrimPrefix(data, utf8Bom)
+		if !utf8.Valid(data) {
+			return "", errors.New("file is not valid UTF-8, please choose another encoding")
+		}
+		return string(data), nil
+	case EncodingCp1250:
+		return decodeSingleByte(data, 0x80, cp1250Table[:]), nil
+	case EncodingIso88592:
+		return decodeSingleByte(data, 0xA0, iso88592Table[:]), nil
+	}
+	return "", fmt.Errorf("unsupported encoding [%s]", encoding)
+}
+
+// Decodes single byte encoding which is ASCII compatible. Bytes from given
+// offset are mapped using the table, lower ones are the same in Unicode.
+func decodeSingleByte(data []byte, offset byte, table []rune) string {
+	var text strings.Builder
+	text.Grow(len(data))
+	for _, b := range data {
+		if b < offset {
+			text.WriteByte(b)
+			continue
+		}
+		text.WriteRune(table[b-offset])
+	}
+	return text.String()
+}
+
+// Windows-1250 characters from 0x80. Undefined bytes are mapped to U+FFFD.
+var cp1250Table = [128]rune{
+	'\u20ac', '\ufffd', '\u201a', '\ufffd', '\u201e', '\u2026', '\u2020', '\u2021',
+	'\ufffd', '\u2030', '\u0160', '\u2039', '\u015a', '\u0164', '\u017d', '\u0179',
+	'\ufffd', '\u2018', '\u2019', '\u201c', '\u201d', '\u2022', '\u2013', '\u2014',
+	'\ufffd', '\u2122', '\u0161', '\u203a', '\u015b', '\u0165', '\u017e', '\u017a',
+	'\u00a0', '\u02c7', '\u02d8', '\u0141', '\u00a4', '\u0104', '\u00a6', '\u00a7',
+	'\u00a8', '\u00a9', '\u015e', '\u00ab', '\u00ac', '\u00ad', '\u00ae', '\u017b',
+	'\u00b0', '\u00b1', '\u02db', '\u0142', '\u00b4', '\u00b5', '\u00b6', '\u00b7',
+	'\u00b8', '\u0105', '\u015f', '\u00bb', '\u013d', '\u02dd', '\u013e', '\u017c',
+	'\u0154', '\u00c1', '\u00c2', '\u0102', '\u00c4', '\u0139', '\u0106', '\u00c7',
+	'\u010c', '\u00c9', '\u0118', '\u00cb', '\u011a', '\u00cd', '\u00ce', '\u010e',
+	'\u0110', '\u0143', '\u0147', '\u00d3', '\u00d4', '\u0150', '\u00d6', '\u00d7',
+	'\u0158', '\u016e', '\u00da', '\u0170', '\u00dc', '\u00dd', '\u0162', '\u00df',
+	'\u0155', '\u00e1', '\u00e2', '\u0103', '\u00e4', '\u013a', '\u0107', '\u00e7',
+	'\u010d', '\u00e9', '\u0119', '\u00eb', '\u011b', '\u00ed', '\u00ee', '\u010f',
+	'\u0111', '\u0144', '\u0148', '\u00f3', '\u00f4', '\u0151', '\u00f6', '\u00f7',
+	'\u0159', '\u016f', '\u00fa', '\u0171', '\u00fc', '\u00fd', '\u0163', '\u02d9',
+}
+
+// ISO-8859-2 characters from 0xA0.
+var iso88592Table = [96]rune{
+	'\u00a0', '\u0104', '\u02d8', '\u0141', '\u00a4', '\u013d', '\u015a', '\u00a7',
+	'\u00a8', '\u0160', '\u015e', '\u0164', '\u0179', '\u00ad', '\u017d', '\u017b',
+	'\u00b0', '\u0105', '\u02db', '\u0142', '\u00b4', '\u013e', '\u015b', '\u02c7',
+	'\u00b8', '\u0161', '\u015f', '\u0165', '\u017a', '\u02dd', '\u017e', '\u017c',
+	'\u0154', '\u00c1', '\u00c2', '\u0102', '\u00c4', '\u0139', '\u0106', '\u00c7',
+	'\u010c', '\u00c9', '\u0118', '\u00cb', '\u011a', '\u00cd', '\u00ce', '\u010e',
+	'\u0110', '\u0143', '\u0147', '\u00d3', '\u00d4', '\u0150', '\u00d6', '\u00d7',
+	'\u0158', '\u016e', '\u00da', '\u0170', '\u00dc', '\u00dd', '\u0162', '\u00df',
+	'\u0155', '\u00e1', '\u00e2', '\u0103', '\u00e4', '\u013a', '\u0107', '\u00e7',
+	'\u010d', '\u00e9', '\u0119', '\u00eb', '\u011b', '\u00ed', '\u00ee', '\u010f',
+	'\u0111', '\u0144', '\u0148', '\u00f3', '\u00f4', '\u0151', '\u00f6', '\u00f7',
+	'\u0159', '\u016f', '\u00fa', '\u0171', '\u00fc', '\u00fd', '\u0163', '\u02d9',
+}
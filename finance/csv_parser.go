@@ -0,0 +1,386 @@
+package finance
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Sign conventions of amounts in CSV files.
+const (
+	AmountSigned      = "signed"      // Negative amounts are outflows
+	AmountNegated     = "negated"     // Positive amounts are outflows, e.g. credit card statements
+	AmountDebitCredit = "debitCredit" // Outflows and inflows are in separate columns
+)
+
+// AmountSigns lists supported sign conventions.
+var AmountSigns = []string{AmountSigned, AmountNegated, AmountDebitCredit}
+
+const transactionDateLayout = "2006-01-02"
+
+// CsvMapping describes how transactions are read from CSV file exported by
+// particular bank. Columns are numbered from 1, zero means that the column
+// isn't present in the file.
+type CsvMapping struct {
+	Delimiter       string
+	Encoding        string
+	SkipRows        int // Rows before the header (or data), e.g. with account info
+	HasHeader       bool
+	DateLayout      string // Go time layout, e.g. 02.01.2006
+	DecimalComma    bool
+	AmountSign      string
+	DefaultCurrency string // Used when there is no currency column
+	AccountNumber   string // Used when there is no account column
+
+	ExecutionDateColumn int
+	OrderDateColumn     int // Execution date is used when not present
+	AmountColumn        int // Used unless AmountSign is AmountDebitCredit
+	DebitColumn         int
+	CreditColumn        int
+	CurrencyColumn      int
+	TypeColumn          int
+	DescriptionColumns  []int // Joined with space
+	EndingBalanceColumn int
+	AccountColumn       int
+}
+
+// Validate checks if mapping is complete.
+func (m CsvMapping) Validate() error {
+	if utf8.RuneCountInString(m.Delimiter) != 1 || strings.ContainsAny(m.Delimiter, "\"\r\n") {
+		return errors.New("delimiter should be single character other than quote or new line")
+	}
+	if !contains(Encodings, m.Encoding) {
+		return fmt.Errorf("unsupported encoding [%s]", m.Encoding)
+	}
+	if m.SkipRows < 0 {
+		return errors.New("number of skipped rows cannot be negative")
+	}
+	if m.DateLayout == "" {
+		return errors.New("date layout should be provided")
+	}
+	if !contains(AmountSigns, m.AmountSign) {
+		return fmt.Errorf("unsupported amount sign convention [%s]", m.AmountSign)
+	}
+	for _, column := range append([]int{m.ExecutionDateColumn, m.OrderDateColumn, m.AmountColumn,
+		m.DebitColumn, m.CreditColumn, m.CurrencyColumn, m.TypeColumn, m.EndingBalanceColumn,
+		m.AccountColumn}, m.DescriptionColumns...) {
+		if column < 0 {
+			return errors.New("column numbers cannot be negative")
+		}
+	}
+	if m.ExecutionDateColumn == 0 {
+		return errors.New("execution date column should be provided")
+	}
+	if m.AmountSign == AmountDebitCredit && (m.DebitColumn == 0 || m.CreditColumn == 0) {
+		return errors.New("debit and credit columns should be provided")
+	}
+	if m.AmountSign != AmountDebitCredit && m.AmountColumn == 0 {
+		return errors.New("amount column should be provided")
+	}
+	if len(m.DescriptionColumns) == 0 {
+		return errors.New("at least one description column should be provided")
+	}
+	if m.CurrencyColumn == 0 && m.DefaultCurrency == "" {
+		return errors.New("currency column or default currency should be provided")
+	}
+	return nil
+}
+
+// CsvParser parses CSV files according to the mapping.
+type CsvParser struct {
+	Mapping CsvMapping
+}
+
+// Transactions parses all data rows of CSV file. Empty rows are skipped,
+// otherwise any incorrect row fails the whole file.
+func (p CsvParser) Transactions(data []byte) ([]Transaction, error) {
+	if vErr := p.Mapping.Validate(); vErr != nil {
+		return nil, vErr
+	}
+	rows, rErr := p.Mapping.records(data)
+	if rErr != nil {
+		return nil, rErr
+	}
+
+	transactions := make([]Transaction, 0, len(rows))
+	for idx, row := range rows {
+		if idx < p.Mapping.firstDataRow() || isEmptyRow(row) {
+			continue
+		}
+		t, tErr := p.Mapping.transaction(row)
+		if tErr != nil {
+			return nil, fmt.Errorf("row %d: %w", idx+1, tErr)
+		}
+		transactions = append(transactions, t)
+	}
+	if len(transactions) == 0 {
+		return nil, errors.New("there are no transactions in the file")
+	}
+	return transactions, nil
+}
+
+// CsvPreview shows how the first rows of CSV file are read using mapping.
+type CsvPreview struct {
+	Columns      int    // The largest number of columns in previewed rows
+	MappingError string // Set when mapping is incomplete, transactions aren't parsed then
+	Rows         []CsvPreviewRow
+}
+
+// CsvPreviewRow is a single previewed row. Transaction or Error is set only
+// for data rows.
+type CsvPreviewRow struct {
+	Number      int
+	Cells       []string
+	Skipped     bool
+	Header      bool
+	Transaction *Transaction
+	Error       string
+}
+
+// Preview reads at most maxRows of CSV file (including skipped and header
+// rows) and parses data rows into transactions. Incomplete mapping is
+// reported in the preview, so it can be used while the mapping is being built.
+// Error is returned only when the file cannot be read as CSV at all.
+func (m CsvMapping) Preview(data []byte, maxRows int) (CsvPreview, error) {
+	var preview CsvPreview
+	if m.Encoding == "" {
+		m.Encoding = EncodingUtf8
+	}
+	if m.Delimiter == "" {
+		m.Delimiter = ","
+	}
+	rows, rErr := m.records(data)
+	if rErr != nil {
+		return preview, rErr
+	}
+	mappingErr := m.Validate()
+	if mappingErr != nil {
+		preview.MappingError = mappingErr.Error()
+	}
+
+	for idx, row := range rows {
+		if idx == maxRows {
+			break
+		}
+		previewRow := CsvPreviewRow{
+			Number:  idx + 1,
+			Cells:   row,
+			Skipped: idx < m.SkipRows,
+			Header:  m.HasHeader && idx == m.SkipRows,
+		}
+		if len(row) > preview.Columns {
+			preview.Columns = len(row)
+		}
+		if mappingErr == nil && idx >= m.firstDataRow() && !isEmptyRow(row) {
+			t, tErr := m.transaction(row)
+			if tErr != nil {
+				previewRow.Error = tErr.Error()
+			} else {
+				previewRow.Transaction = &t
+			}
+		}
+		preview.Rows = append(preview.Rows, previewRow)
+	}
+	return preview, nil
+}
+
+// Decodes file and reads all CSV records. Rows might have different number of
+// fields.
+func (m CsvMapping) records(data []byte) ([][]string, error) {
+	text, dErr := decodeText(data, m.Encoding)
+	if dErr != nil {
+		return nil, dErr
+	}
+	delimiter, _ := utf8.DecodeRuneInString(m.Delimiter)
+
+	reader := csv.NewReader(strings.NewReader(text))
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, rErr := reader.ReadAll()
+	if rErr != nil {
+		return nil, fmt.Errorf("cannot read CSV file: %w", rErr)
+	}
+	return rows, nil
+}
+
+func (m CsvMapping) firstDataRow() int {
+	if m.HasHeader {
+		return m.SkipRows + 1
+	}
+	return m.SkipRows
+}
+
+func (m CsvMapping) transaction(row []string) (Transaction, error) {
+	execDate, eErr := m.date(row, m.ExecutionDateColumn)
+	if eErr != nil {
+		return Transaction{}, fmt.Errorf("execution date: %w", eErr)
+	}
+	orderDate := execDate
+	if m.OrderDateColumn != 0 {
+		date, oErr := m.date(row, m.OrderDateColumn)
+		if oErr != nil {
+			return Transaction{}, fmt.Errorf("order date: %w", oErr)
+		}
+		orderDate = date
+	}
+
+	amount, aErr := m.amount(row)
+	if aErr != nil {
+		return Transaction{}, aErr
+	}
+
+	currency := m.DefaultCurrency
+	if m.CurrencyColumn != 0 {
+		currency = cell(row, m.CurrencyColumn)
+		if currency == "" {
+			return Transaction{}, errors.New("currency is empty")
+		}
+	}
+	account := m.AccountNumber
+	if m.AccountColumn != 0 {
+		account = cell(row, m.AccountColumn)
+	}
+
+	descriptions := make([]string, 0, len(m.DescriptionColumns))
+	for _, column := range m.DescriptionColumns {
+		if d := cell(row, column); d != "" {
+			descriptions = append(descriptions, d)
+		}
+	}
+
+	t := Transaction{
+		AccountNumber:  account,
+		ExecutionDate:  execDate,
+		OrderDate:      orderDate,
+		AmountCurrency: currency,
+		AmountValue:    amount,
+		Description:    strings.Join(descriptions, " "),
+	}
+	if m.TypeColumn != 0 {
+		tType := cell(row, m.TypeColumn)
+		t.Type = &tType
+	}
+	if m.EndingBalanceColumn != 0 && cell(row, m.EndingBalanceColumn) != "" {
+		balance, bErr := m.parseAmount(cell(row, m.EndingBalanceColumn))
+		if bErr != nil {
+			return Transaction{}, fmt.Errorf("ending balance: %w", bErr)
+		}
+		balanceCurrency := currency
+		t.EndingBalanceValue = &balance
+		t.EndingBalanceCurrency = &balanceCurrency
+	}
+	return t, nil
+}
+
+// Parses date in mapping layout and formats it as ISO date.
+func (m CsvMapping) date(row []string, column int) (string, error) {
+	value := cell(row, column)
+	date, pErr := time.Parse(m.DateLayout, value)
+	if pErr != nil {
+		return "", fmt.Errorf("[%s] doesn't match layout [%s]", value, m.DateLayout)
+	}
+	return date.Format(transactionDateLayout), nil
+}
+
+// Reads amount according to sign convention. Inflows are positive and
+// outflows are negative.
+func (m CsvMapping) amount(row []string) (float64, error) {
+	switch m.AmountSign {
+	case AmountSigned, AmountNegated:
+		amount, pErr := m.parseAmount(cell(row, m.AmountColumn))
+		if pErr != nil {
+			return 0, fmt.Errorf("amount: %w", pErr)
+		}
+		if m.AmountSign == AmountNegated {
+			amount = -amount
+		}
+		return amount, nil
+	case AmountDebitCredit:
+		debit, credit := cell(row, m.DebitColumn), cell(row, m.CreditColumn)
+		if debit == "" && credit == "" {
+			return 0, errors.New("both debit and credit are empty")
+		}
+		var amount float64
+		if debit != "" {
+			value, dErr := m.parseAmount(debit)
+			if dErr != nil {
+				return 0, fmt.Errorf("debit: %w", dErr)
+			}
+			amount = amount - math.Abs(value)
+		}
+		if credit != "" {
+			value, cErr := m.parseAmount(credit)
+			if cErr != nil {
+				return 0, fmt.Errorf("credit: %w", cErr)
+			}
+			amount = amount + math.Abs(value)
+		}
+		return amount, nil
+	}
+	return 0, fmt.Errorf("unsupported amount sign convention [%s]", m.AmountSign)
+}
+
+// Parses amounts like "-1 234,56 PLN" (with decimal comma) or "1,234.56".
+// Thousands separators and currency symbols are ignored. Anything else than
+// digits, sign and separators is rejected, so forms accepted by ParseFloat
+// like "1e5" or "0x1p3" aren't read as amounts.
+func (m CsvMapping) parseAmount(value string) (float64, error) {
+	cleaned := strings.TrimFunc(value, func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsSpace(r) || r == '$' || r == '€' || r == '£'
+	})
+	cleaned = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '\'' {
+			return -1
+		}
+		return r
+	}, cleaned)
+	for _, r := range cleaned {
+		if !unicode.IsDigit(r) && !strings.ContainsRune("+-.,", r) {
+			return 0, fmt.Errorf("[%s] is not a number", value)
+		}
+	}
+	if m.DecimalComma {
+		cleaned = strings.ReplaceAll(cleaned, ".", "")
+		cleaned = strings.ReplaceAll(cleaned, ",", ".")
+	} else {
+		cleaned = strings.ReplaceAll(cleaned, ",", "")
+	}
+	amount, pErr := strconv.ParseFloat(cleaned, 64)
+	if pErr != nil {
+		return 0, fmt.Errorf("[%s] is not a number", value)
+	}
+	return amount, nil
+}
+
+// Returns trimmed value of column numbered from 1. Missing cells are empty.
+func cell(row []string, column int) string {
+	if column < 1 || column > len(row) {
+		return ""
+	}
+	return strings.TrimSpace(row[column-1])
+}
+
+func isEmptyRow(row []string) bool {
+	for _, c := range row {
+		if strings.TrimSpace(c) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,178 @@
+package finance
+
+import (
+	"strings"
+	"testing"
+)
+
+func pkoCsvMapping() CsvMapping {
+	return CsvMapping{
+		Delimiter:           ";",
+		Encoding:            EncodingCp1250,
+		SkipRows:            1,
+		HasHeader:           true,
+		DateLayout:          "02.01.2006",
+		DecimalComma:        true,
+		AmountSign:          AmountSigned,
+		AccountNumber:       "123",
+		ExecutionDateColumn: 1,
+		OrderDateColumn:     2,
+		TypeColumn:          3,
+		AmountColumn:        4,
+		CurrencyColumn:      5,
+		EndingBalanceColumn: 6,
+		DescriptionColumns:  []int{7, 8},
+	}
+}
+
+// "Płatność kartą" and "Żabka" encoded in Windows-1250
+const pkoCsv = "Historia rachunku 123;;;;;;;\r\n" +
+	"Data operacji;Data waluty;Typ;Kwota;Waluta;Saldo;Opis;Lokalizacja\r\n" +
+	"30.04.2022;29.04.2022;P\xb3atno\x9c\xe6 kart\xb9;-1 038,50;PLN;2 000,00;\"Sklep; \xafabka\";Krak\xf3w\r\n" +
+	";;;;;;;\r\n" +
+	"02.05.2022;02.05.2022;Przelew;+250,00;PLN;;Zwrot;\r\n"
+
+func TestCsvParser(t *testing.T) {
+	transactions, err := CsvParser{Mapping: pkoCsvMapping()}.Transactions([]byte(pkoCsv))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(transactions) != 2 {
+		t.Fatalf("expected 2 transactions, got %d", len(transactions))
+	}
+
+	t1 := transactions[0]
+	if t1.ExecutionDate != "2022-04-30" || t1.OrderDate != "2022-04-29" || t1.AccountNumber != "123" {
+		t.Errorf("unexpected dates or account: %+v", t1)
+	}
+	if t1.Type == nil || *t1.Type != "Płatność kartą" {
+		t.Errorf("unexpected type: %v", t1.Type)
+	}
+	if t1.AmountValue != -1038.5 || t1.AmountCurrency != "PLN" {
+		t.Errorf("unexpected amount: %f %s", t1.AmountValue, t1.AmountCurrency)
+	}
+	if t1.EndingBalanceValue == nil || *t1.EndingBalanceValue != 2000 {
+		t.Errorf("unexpected ending balance: %v", t1.EndingBalanceValue)
+	}
+	if t1.Description != "Sklep; Żabka Kraków" {
+		t.Errorf("unexpected description: [%s]", t1.Description)
+	}
+
+	t2 := transactions[1]
+	if t2.AmountValue != 250 || t2.EndingBalanceValue != nil || t2.Description != "Zwrot" {
+		t.Errorf("unexpected second transaction: %+v", t2)
+	}
+}
+
+func TestCsvParserAmountSigns(t *testing.T) {
+	data := []byte("2024-05-01,Shop,12.50,,\n2024-05-02,Refund,-3.00,,\n2024-05-03,Salary,,\"1,000.00\",\n" +
+		"2024-05-04,Fee,,,-2.5\n")
+	mapping := CsvMapping{
+		Delimiter:           ",",
+		Encoding:            EncodingUtf8,
+		DateLayout:          "2006-01-02",
+		AmountSign:          AmountNegated,
+		DefaultCurrency:     "PLN",
+		ExecutionDateColumn: 1,
+		DescriptionColumns:  []int{2},
+		AmountColumn:        3,
+	}
+	if _, err := (CsvParser{Mapping: mapping}).Transactions(data); err == nil ||
+		!strings.Contains(err.Error(), "row 3: amount") {
+		t.Errorf("expected amount error in row 3, got %v", err)
+	}
+
+	transactions, err := CsvParser{Mapping: mapping}.Transactions(data[:48])
+	if err != nil || len(transactions) != 2 || transactions[0].AmountValue != -12.5 ||
+		transactions[1].AmountValue != 3 {
+		t.Errorf("unexpected negated amounts (error %v): %+v", err, transactions)
+	}
+
+	mapping.AmountSign = AmountDebitCredit
+	mapping.DebitColumn, mapping.CreditColumn = 5, 4
+	mapping.AmountColumn = 0
+	transactions, err = CsvParser{Mapping: mapping}.Transactions(data[48:])
+	if err != nil || len(transactions) != 2 || transactions[0].AmountValue != 1000 ||
+		transactions[1].AmountValue != -2.5 || transactions[0].OrderDate != "2024-05-03" {
+		t.Errorf("unexpected debit/credit amounts (error %v): %+v", err, transactions)
+	}
+}
+
+func TestCsvParseAmount(t *testing.T) {
+	cases := []struct {
+		value        string
+		decimalComma bool
+		expected     float64
+		isValid      bool
+	}{
+		{"-1 234,56 PLN", true, -1234.56, true},
+		{"1.234,56", true, 1234.56, true},
+		{"$1,234.56", false, 1234.56, true},
+		{"+250.00", false, 250, true},
+		{"1'000.5", false, 1000.5, true},
+		{"1e5", false, 0, false},
+		{"0x1p3", false, 0, false},
+		{"1_000", false, 0, false},
+		{"Inf", false, 0, false},
+		{"", false, 0, false},
+	}
+	for _, c := range cases {
+		amount, err := CsvMapping{DecimalComma: c.decimalComma}.parseAmount(c.value)
+		if c.isValid && (err != nil || amount != c.expected) {
+			t.Errorf("[%s] expected %v, got %v (error %v)", c.value, c.expected, amount, err)
+		}
+		if !c.isValid && err == nil {
+			t.Errorf("[%s] expected error, got %v", c.value, amount)
+		}
+	}
+}
+
+func TestCsvMappingValidate(t *testing.T) {
+	cases := map[string]func(m *CsvMapping){
+		"delimiter":           func(m *CsvMapping) { m.Delimiter = ";;" },
+		"encoding":            func(m *CsvMapping) { m.Encoding = "latin1" },
+		"execution date":      func(m *CsvMapping) { m.ExecutionDateColumn = 0 },
+		"amount column":       func(m *CsvMapping) { m.AmountColumn = 0 },
+		"debit and credit":    func(m *CsvMapping) { m.AmountSign = AmountDebitCredit },
+		"description":         func(m *CsvMapping) { m.DescriptionColumns = nil },
+		"currency":            func(m *CsvMapping) { m.CurrencyColumn = 0 },
+		"cannot be negative":  func(m *CsvMapping) { m.TypeColumn = -1 },
+		"sign convention [x]": func(m *CsvMapping) { m.AmountSign = "x" },
+	}
+	for expected, change := range cases {
+		mapping := pkoCsvMapping()
+		change(&mapping)
+		if err := mapping.Validate(); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("expected [%s] error, got %v", expected, err)
+		}
+	}
+	if err := pkoCsvMapping().Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCsvPreview(t *testing.T) {
+	mapping := pkoCsvMapping()
+	mapping.DateLayout = "2006-01-02"
+	preview, err := mapping.Preview([]byte(pkoCsv), 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(preview.Rows) != 3 || preview.Columns != 8 || preview.MappingError != "" {
+		t.Fatalf("unexpected preview: %+v", preview)
+	}
+	if !preview.Rows[0].Skipped || !preview.Rows[1].Header || preview.Rows[1].Cells[0] != "Data operacji" {
+		t.Errorf("unexpected skipped and header rows: %+v", preview.Rows[:2])
+	}
+	if preview.Rows[2].Transaction != nil || !strings.Contains(preview.Rows[2].Error, "[30.04.2022]") {
+		t.Errorf("expected date error, got %+v", preview.Rows[2])
+	}
+
+	preview, err = CsvMapping{}.Preview([]byte("date,amount\n2024-05-01,12.50\n"), 10)
+	if err != nil || preview.MappingError == "" || len(preview.Rows) != 2 || preview.Columns != 2 {
+		t.Errorf("expected raw rows with mapping error (error %v): %+v", err, preview)
+	}
+	if _, err := (CsvMapping{Encoding: EncodingUtf8}).Preview([]byte(pkoCsv), 10); err == nil {
+		t.Error("expected error for invalid UTF-8")
+	}
+}
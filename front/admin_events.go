@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func AdminEvents(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/admin_events.html")
+}
@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func AdminOutbox(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/admin_outbox.html")
+}
@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func AdminUsers(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/admin_users.html")
+}
@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func ApiTokens(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/api_tokens.html")
+}
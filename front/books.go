@@ -2,10 +2,10 @@ package front
 
 import "html/template"
 
-func Books() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/books.html")...))
+func Books(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/books.html")
 }
 
-func BooksNewForm() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/books_form.html")...))
+func BooksNewForm(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/books_form.html")
 }
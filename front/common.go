@@ -1,5 +1,10 @@
 package front
 
+import (
+	"html/template"
+	"path/filepath"
+)
+
 var TemplateCommonFiles []string = []string{
 	"html/common/css.html",
 	"html/common/header.html",
@@ -12,3 +17,29 @@ var TemplateCommonFiles []string = []string{
 func withCommonTemplates(paths ...string) []string {
 	return append(paths, TemplateCommonFiles...)
 }
+
+// Common holds data of logged in user used by common templates and forms.
+type Common struct {
+	Modules   map[string]bool // modules which user can read
+	IsAdmin   bool
+	CsrfToken string
+	CspNonce  string
+}
+
+func (c Common) funcs() template.FuncMap {
+	return template.FuncMap{
+		"canRead":   func(module string) bool { return c.IsAdmin || c.Modules[module] },
+		"isAdmin":   func() bool { return c.IsAdmin },
+		"csrfToken": func() string { return c.CsrfToken },
+		"cspNonce":  func() string { return c.CspNonce },
+	}
+}
+
+// Parses given page together with common templates. Common data is available
+// in templates via "canRead", "isAdmin", "csrfToken" and "cspNonce" functions.
+// Every POST form of logged in user has to include "csrfToken" hidden field and
+// every inline script has to have "cspNonce" nonce attribute.
+func parseWithCommonTemplates(common Common, path string) *template.Template {
+	tmpl := template.New(filepath.Base(path)).Funcs(common.funcs())
+	return template.Must(tmpl.ParseFiles(withCommonTemplates(path)...))
+}
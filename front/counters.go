@@ -4,10 +4,10 @@ import "html/template"
 
 type CountersData struct{}
 
-func Counters() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/counters.html")...))
+func Counters(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/counters.html")
 }
 
-func CountersNewForm() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/counters_form.html")...))
+func CountersNewForm(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/counters_form.html")
 }
@@ -2,10 +2,10 @@ package front
 
 import "html/template"
 
-func Documents() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/documents.html")...))
+func Documents(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/documents.html")
 }
 
-func DocumentsNewForm() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/documents_form.html")...))
+func DocumentsNewForm(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/documents_form.html")
 }
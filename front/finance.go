@@ -2,10 +2,14 @@ package front
 
 import "html/template"
 
-func Finance() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/finance.html")...))
+func Finance(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/finance.html")
 }
 
-func FinanceNewForm() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/finance_form.html")...))
+func FinanceNewForm(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/finance_form.html")
+}
+
+func FinanceCsvProfiles(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/finance_csv_profiles.html")
 }
@@ -2,6 +2,6 @@ package front
 
 import "html/template"
 
-func FinanceExplorer() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/finance_explorer.html")...))
+func FinanceExplorer(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/finance_explorer.html")
 }
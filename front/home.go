@@ -4,6 +4,6 @@ import "html/template"
 
 type HomeData struct{}
 
-func Home() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/home.html")...))
+func Home(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/home.html")
 }
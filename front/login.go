@@ -2,6 +2,10 @@ package front
 
 import "html/template"
 
-func Login() *template.Template {
-	return template.Must(template.ParseFiles(withCommonTemplates("html/login.html")...))
+func Login(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/login.html")
+}
+
+func Login2FA(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/login_2fa.html")
 }
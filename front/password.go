@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func Password(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/password.html")
+}
@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func Sessions(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/sessions.html")
+}
@@ -0,0 +1,7 @@
+package front
+
+import "html/template"
+
+func TwoFactor(common Common) *template.Template {
+	return parseWithCommonTemplates(common, "html/two_factor.html")
+}
@@ -3,13 +3,17 @@ package main
 import (
 	"fmt"
 	"net/http"
+	"os"
+	"time"
 
 	"homeApp/auth"
 	"homeApp/auth/telegram"
+	"homeApp/bot"
 	"homeApp/controller"
 	"homeApp/db"
+	"homeApp/digest"
 	"homeApp/monitor"
-	"homeApp/rand"
+	"homeApp/notify"
 
 	"github.com/rs/zerolog/log"
 
@@ -17,11 +21,14 @@ import (
 )
 
 const (
-	SessCookieName   = "session"
-	JwtSigningKeyLen = 256
+	SessCookieName = "session"
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "user" {
+		os.Exit(runUserCmd(os.Args[2:]))
+	}
+
 	config := ParseConfigFlags()
 	config.setupZerolog()
 
@@ -29,27 +36,96 @@ func main() {
 	if dbErr != nil {
 		log.Fatal().Err(dbErr).Msg("Cannot connect to SQLite")
 	}
+	if mErr := dbClient.Migrate(schemaSql); mErr != nil {
+		log.Fatal().Err(mErr).Msg("Cannot migrate database schema")
+	}
 
 	// Long-lived
 	httpClient := http.Client{Timeout: config.HttpClientTimeout}
 	var telegramClient *telegram.Client = nil
-	var monitoringMsgSender monitor.MessageSender = monitor.MockMessageSender{}
-	if config.UseTelegram2FA {
-		telegramClient = telegram.NewClient(&httpClient, config.Telegram.BotToken, config.Telegram.ChannelId)
-		monitoringMsgSender = telegramClient
+	var telegramWebhook http.HandlerFunc = nil
+	if config.UseTelegram {
+		telegramClient = telegram.NewClient(&httpClient, config.Telegram.ApiUrl, config.Telegram.BotToken,
+			config.Telegram.ChannelId)
+		if config.Telegram.BotCommands {
+			botRouter := bot.NewRouter(dbClient, telegramClient, config.Telegram.BotChatIds)
+			telegramClient.Subscribe(botRouter.HandleUpdate)
+		}
+		if config.Telegram.WebhookUrl != "" {
+			webhook, whErr := telegramClient.StartWebhook(config.Telegram.WebhookUrl)
+			if whErr != nil {
+				log.Fatal().Err(whErr).Msg("Cannot register Telegram webhook")
+			}
+			telegramWebhook = webhook
+		} else if config.Telegram.BotCommands {
+			telegramClient.StartPolling()
+		}
+	}
+
+	notifyRouter := newNotifier(config.Notify, telegramClient, &httpClient)
+	notifier := notify.OutboxNotifier{DbClient: dbClient}
+	outboxWorker := notify.NewOutboxWorker(dbClient, notifyRouter, config.Notify.OutboxMaxAttempts)
+	go outboxWorker.Run()
+
+	if len(config.Digest.Digests) > 0 {
+		digestTemplates, tErr := digest.LoadTemplates(config.Digest.TemplatesDir)
+		if tErr != nil {
+			log.Fatal().Err(tErr).Msg("Cannot load digest templates")
+		}
+		digestScheduler := digest.Scheduler{
+			Composer:  &digest.Composer{DbClient: dbClient, ExpiryDays: config.Digest.ExpiryDays},
+			Templates: digestTemplates,
+			Notifier:  notifier,
+			Digests:   config.Digest.Digests,
+		}
+		digestScheduler.Start()
 	}
 
 	registeredEndpoints := make(map[string]struct{}) // To be updated during endpoint registration
-	pageViews := monitor.NewPageViews(config.UseTelegram2FA, registeredEndpoints)
-	go pageViews.PublishViews(config.PublishViewsAfter, monitoringMsgSender)
+	pageViews := monitor.NewPageViews(registeredEndpoints)
+	go pageViews.PublishViews(config.PublishViewsAfter, notifier)
+
+	signingKeys, skErr := auth.NewSigningKeys(dbClient, config.JwtKeyRotation, config.JwtKeyGracePeriod)
+	if skErr != nil {
+		log.Fatal().Err(skErr).Msg("Cannot load JWT signing keys")
+	}
+	go signingKeys.RotatePeriodically()
+
+	var secretBox *auth.SecretBox = nil
+	if config.SecretsKey != nil {
+		sb, sbErr := auth.NewSecretBox(config.SecretsKey)
+		if sbErr != nil {
+			log.Fatal().Err(sbErr).Msg("Cannot initialize secrets encryption")
+		}
+		secretBox = sb
+	}
+	twoFactor := auth.NewTwoFactor(dbClient, secretBox, telegramClient, config.Default2FAMethod)
 
 	userAuth := auth.UserAuth{
-		DbClient:       dbClient,
-		JwtSigningKey:  []byte(rand.AlphanumStr(JwtSigningKeyLen)),
-		JwtExpMinutes:  config.SessionTimeoutMinutes,
-		TelegramClient: telegramClient,
+		DbClient:      dbClient,
+		SigningKeys:   signingKeys,
+		JwtExpMinutes: config.SessionTimeoutMinutes,
+		TwoFactor:     twoFactor,
+	}
+	loginLimiter := &auth.LoginLimiter{
+		DbClient:    dbClient,
+		Alerter:     notifier,
+		MaxFailures: config.LoginMaxFailures,
+		Lockout:     config.LoginLockout,
+	}
+	authAudit := &auth.AuthAudit{
+		DbClient:   dbClient,
+		Alerter:    notifier,
+		AlertNewIp: config.LoginAlertNewIp,
+	}
+	pendingLogins := auth.NewPendingLogins()
+	authHandlerMan := auth.HandlerManager{
+		UserAuthenticator: userAuth,
+		PendingLogins:     pendingLogins,
+		LoginLimiter:      loginLimiter,
+		Audit:             authAudit,
+		SecureCookies:     config.SecureCookies,
 	}
-	authHandlerMan := auth.HandlerManager{UserAuthenticator: userAuth}
 	homeContr := controller.Home{
 		DbClient:    dbClient,
 		UserAuth:    userAuth,
@@ -57,24 +133,18 @@ func main() {
 		CurrentHash: config.CurrentCommitSHA,
 	}
 	counterContr := controller.Counters{
-		DbClient:       dbClient,
-		TelegramClient: telegramClient,
-		UserAuth:       userAuth,
+		DbClient: dbClient,
 	}
 	documentsContr := controller.Documents{
 		DbClient:       dbClient,
 		TelegramClient: telegramClient,
-		UserAuth:       userAuth,
 	}
 	booksContr := controller.Books{
 		DbClient:       dbClient,
 		TelegramClient: telegramClient,
-		UserAuth:       userAuth,
 	}
 	finContr := controller.Finance{
-		DbClient:       dbClient,
-		TelegramClient: telegramClient,
-		UserAuth:       userAuth,
+		DbClient: dbClient,
 	}
 	finExpContr := controller.FinanceExplorer{
 		DbClient:       dbClient,
@@ -82,44 +152,108 @@ func main() {
 		UserAuth:       userAuth,
 	}
 	loginContr := controller.LoginForm{
-		TelegramClient: telegramClient,
-		AuthManager:    authHandlerMan,
-		DbClient:       dbClient,
-		AppVersion:     config.AppVersion,
-		CurrentHash:    config.CurrentCommitSHA,
+		AuthManager: authHandlerMan,
+		DbClient:    dbClient,
+		AppVersion:  config.AppVersion,
+		CurrentHash: config.CurrentCommitSHA,
 	}
 	sessionContr := controller.Session{
-		UserAuth: userAuth,
+		UserAuth:      userAuth,
+		DbClient:      dbClient,
+		Audit:         authAudit,
+		SecureCookies: config.SecureCookies,
+	}
+	twoFactorContr := controller.TwoFactorSettings{
+		UserAuth:     userAuth,
+		TwoFactor:    twoFactor,
+		DbClient:     dbClient,
+		LoginLimiter: loginLimiter,
+	}
+	passwordContr := controller.PasswordSettings{
+		DbClient:     dbClient,
+		UserAuth:     userAuth,
+		LoginLimiter: loginLimiter,
+		Audit:        authAudit,
+	}
+	apiTokensContr := controller.ApiTokens{
+		ApiTokens: auth.ApiTokens{DbClient: dbClient},
+	}
+	adminEventsContr := controller.AdminEvents{
+		Audit: authAudit,
+	}
+	csvProfilesContr := controller.FinanceCsvProfiles{
+		DbClient: dbClient,
+	}
+	adminOutboxContr := controller.AdminOutbox{
+		DbClient: dbClient,
+	}
+	adminUsersContr := controller.AdminUsers{
+		UserAdmin: auth.UserAdmin{DbClient: dbClient, PendingLogins: pendingLogins},
+		DbClient:  dbClient,
 	}
 	endpoints := EndpointRegister{
 		PageViews:           pageViews,
 		AuthHandler:         &authHandlerMan,
 		RegisteredEndpoints: registeredEndpoints,
 	}
+	if config.Tls != nil {
+		endpoints.HstsMaxAge = config.Tls.HstsMaxAge
+	}
 
 	endpoints.register("/", loginContr.LoginFormHandler)
 	endpoints.register("/login", authHandlerMan.Login)
+	endpoints.register("/login/2fa", loginContr.TwoFactorFormHandler)
+	endpoints.register("/login/2fa/verify", authHandlerMan.Login2FA)
+	endpoints.register("/login/2fa/status", authHandlerMan.Login2FAStatus)
 	endpoints.registerWithAuth("/home", homeContr.HomeSummaryView)
-	endpoints.registerWithAuth("/books", booksContr.BooksViewHandler)
-	endpoints.registerWithAuth("/books-new", booksContr.BooksInsertForm)
-	endpoints.registerWithAuth("/books/upload", booksContr.InsertNewBook)
-	endpoints.registerWithAuth("/bookFile", booksContr.DownloadBook)
-	endpoints.registerWithAuth("/counters", counterContr.CountersViewHandler)
-	endpoints.registerWithAuth("/counters-new", counterContr.CountersInsertForm)
-	endpoints.registerWithAuth("/counters/upload", counterContr.CountersUploadNew)
-	endpoints.registerWithAuth("/documents", documentsContr.DocumentsViewHandler)
-	endpoints.registerWithAuth("/documents-new", documentsContr.DocumentsInsertForm)
-	endpoints.registerWithAuth("/documents/uploadFile", documentsContr.InsertNewDocument)
-	endpoints.registerWithAuth("/documentFile", documentsContr.PreviewDocument)
-	endpoints.registerWithAuth("/finance", finContr.FinanceViewHandler)
-	endpoints.registerWithAuth("/finance-new", finContr.FinanceInsertForm)
-	endpoints.registerWithAuth("/finance/upload", finContr.FinanceUploadFile)
-	endpoints.registerWithAuth("/finance-explorer", finExpContr.FinanceExplorerViewHandler)
+	endpoints.registerWithPermission("/books", auth.ModuleBooks, auth.AccessRead, booksContr.BooksViewHandler)
+	endpoints.registerWithPermission("/books-new", auth.ModuleBooks, auth.AccessWrite, booksContr.BooksInsertForm)
+	endpoints.registerWithPermission("/books/upload", auth.ModuleBooks, auth.AccessWrite, booksContr.InsertNewBook)
+	endpoints.registerWithPermission("/bookFile", auth.ModuleBooks, auth.AccessRead, booksContr.DownloadBook)
+	endpoints.registerWithPermission("/books/sendTelegram", auth.ModuleBooks, auth.AccessWrite, booksContr.SendBookToTelegram)
+	endpoints.registerWithPermission("/counters", auth.ModuleCounters, auth.AccessRead, counterContr.CountersViewHandler)
+	endpoints.registerWithPermission("/counters-new", auth.ModuleCounters, auth.AccessWrite, counterContr.CountersInsertForm)
+	endpoints.registerWithPermission("/counters/upload", auth.ModuleCounters, auth.AccessWrite, counterContr.CountersUploadNew)
+	endpoints.registerWithPermission("/documents", auth.ModuleDocuments, auth.AccessRead, documentsContr.DocumentsViewHandler)
+	endpoints.registerWithPermission("/documents-new", auth.ModuleDocuments, auth.AccessWrite, documentsContr.DocumentsInsertForm)
+	endpoints.registerWithPermission("/documents/uploadFile", auth.ModuleDocuments, auth.AccessWrite, documentsContr.InsertNewDocument)
+	endpoints.registerWithPermission("/documentFile", auth.ModuleDocuments, auth.AccessRead, documentsContr.PreviewDocument)
+	endpoints.registerWithPermission("/documents/sendTelegram", auth.ModuleDocuments, auth.AccessWrite, documentsContr.SendDocumentToTelegram)
+	endpoints.registerWithPermission("/finance", auth.ModuleFinance, auth.AccessRead, finContr.FinanceViewHandler)
+	endpoints.registerWithPermission("/finance-new", auth.ModuleFinance, auth.AccessWrite, finContr.FinanceInsertForm)
+	endpoints.registerWithPermission("/finance/upload", auth.ModuleFinance, auth.AccessWrite, finContr.FinanceUploadFile)
+	endpoints.registerWithPermission("/finance/csv-profiles", auth.ModuleFinance, auth.AccessWrite, csvProfilesContr.ProfilesView)
+	endpoints.registerWithPermission("/finance/csv-profiles/preview", auth.ModuleFinance, auth.AccessWrite, csvProfilesContr.PreviewHandler)
+	endpoints.registerWithPermission("/finance/csv-profiles/save", auth.ModuleFinance, auth.AccessWrite, csvProfilesContr.SaveHandler)
+	endpoints.registerWithPermission("/finance/csv-profiles/delete", auth.ModuleFinance, auth.AccessWrite, csvProfilesContr.DeleteHandler)
+	endpoints.registerWithPermission("/finance-explorer", auth.ModuleFinance, auth.AccessRead, finExpContr.FinanceExplorerViewHandler)
 	endpoints.registerWithAuth("/logout", authHandlerMan.TerminateSession)
 	endpoints.registerWithAuth("/session/prolong", sessionContr.ProlongHandler)
+	endpoints.registerWithAuth("/sessions", sessionContr.ActiveSessionsView)
+	endpoints.registerWithAuth("/sessions/revoke", sessionContr.RevokeHandler)
+	endpoints.registerWithAuth("/sessions/revokeAll", sessionContr.RevokeAllHandler)
+	endpoints.registerWithAuth("/settings/2fa", twoFactorContr.TwoFactorView)
+	endpoints.registerWithAuth("/settings/2fa/method", twoFactorContr.SetMethodHandler)
+	endpoints.registerWithAuth("/settings/2fa/totp/start", twoFactorContr.TotpStartHandler)
+	endpoints.registerWithAuth("/settings/2fa/totp/confirm", twoFactorContr.TotpConfirmHandler)
+	endpoints.registerWithAuth("/settings/password", passwordContr.PasswordView)
+	endpoints.registerWithAuth("/settings/password/change", passwordContr.ChangeHandler)
+	endpoints.registerWithAuth("/settings/tokens", apiTokensContr.ApiTokensView)
+	endpoints.registerWithAuth("/settings/tokens/create", apiTokensContr.CreateHandler)
+	endpoints.registerWithAuth("/settings/tokens/revoke", apiTokensContr.RevokeHandler)
+	endpoints.registerWithAdmin("/admin/events", adminEventsContr.EventsView)
+	endpoints.registerWithAdmin("/admin/outbox", adminOutboxContr.OutboxView)
+	endpoints.registerWithAdmin("/admin/outbox/requeue", adminOutboxContr.RequeueHandler)
+	endpoints.registerWithAdmin("/admin/users", adminUsersContr.UsersView)
+	endpoints.registerWithAdmin("/admin/users/add", adminUsersContr.AddHandler)
+	endpoints.registerWithAdmin("/admin/users/setActive", adminUsersContr.SetActiveHandler)
+	endpoints.registerWithAdmin("/admin/users/resetPassword", adminUsersContr.ResetPasswordHandler)
+	endpoints.registerWithAdmin("/admin/users/permissions", adminUsersContr.PermissionsHandler)
+	if telegramWebhook != nil {
+		endpoints.register(config.Telegram.WebhookPath, telegramWebhook)
+	}
 
-	log.Info().Msgf("Listening on :%d...", config.Port)
-	lasErr := http.ListenAndServe(fmt.Sprintf(":%d", config.Port), nil)
+	lasErr := listenAndServe(config, http.DefaultServeMux)
 	if lasErr != nil {
 		log.Panic().Err(lasErr).Msgf("Cannot start the server")
 	}
@@ -129,6 +263,7 @@ type EndpointRegister struct {
 	PageViews           *monitor.PageViews
 	AuthHandler         *auth.HandlerManager
 	RegisteredEndpoints map[string]struct{}
+	HstsMaxAge          time.Duration // HSTS header is sent when positive
 }
 
 func (er *EndpointRegister) register(path string, handler func(http.ResponseWriter, *http.Request)) {
@@ -137,10 +272,30 @@ func (er *EndpointRegister) register(path string, handler func(http.ResponseWrit
 	}
 	er.RegisteredEndpoints[path] = struct{}{}
 
+	if er.HstsMaxAge > 0 {
+		handler = withHsts(er.HstsMaxAge, handler)
+	}
+	handler = auth.SecurityHeaders(handler)
 	// PageViews statistics should be included for every endpoint
 	http.HandleFunc(path, er.PageViews.Listen(handler))
 }
 
+// Registers endpoint for logged in users. It cannot be accessed using API
+// token.
 func (er *EndpointRegister) registerWithAuth(path string, handler func(http.ResponseWriter, *http.Request)) {
-	er.register(path, er.AuthHandler.CheckAuth(handler))
+	er.registerAuthenticated(path, er.AuthHandler.RequireSession(handler))
+}
+
+// Registers module endpoint. It can be accessed either by logged in users or
+// using API token, given the module permission.
+func (er *EndpointRegister) registerWithPermission(path, module, access string, handler func(http.ResponseWriter, *http.Request)) {
+	er.registerAuthenticated(path, er.AuthHandler.RequirePermission(module, access, handler))
+}
+
+func (er *EndpointRegister) registerAuthenticated(path string, handler func(http.ResponseWriter, *http.Request)) {
+	er.register(path, er.AuthHandler.CheckAuth(er.AuthHandler.CheckCsrf(handler)))
+}
+
+func (er *EndpointRegister) registerWithAdmin(path string, handler func(http.ResponseWriter, *http.Request)) {
+	er.registerWithAuth(path, er.AuthHandler.RequireAdmin(handler))
 }
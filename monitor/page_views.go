@@ -3,33 +3,15 @@ package monitor
 import (
 	"fmt"
 	"net/http"
-	"net/url"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
-	"github.com/rs/zerolog/log"
+	"homeApp/notify"
 )
 
-const (
-	MaxRetriesWithoutMessage      = 5
-	MaxSendMessageRetries         = 10
-	WaitBetweenSendMessageRetries = 60 * time.Second
-)
-
-// MessageSender would be used to send monitoring messages.
-type MessageSender interface {
-	SendMessage(string) error
-}
-
-// Trivial MessageSender implementation using logger.
-type MockMessageSender struct{}
-
-func (mms MockMessageSender) SendMessage(msg string) error {
-	log.Info().Msgf("[monitor/msgsender] %s", msg)
-	return nil
-}
+const MaxRetriesWithoutMessage = 5
 
 // PageViews keeps map with endpoints paths and visit counts. A set of
 // registered endpoint paths are needed to group other requests (like
@@ -39,15 +21,13 @@ type PageViews struct {
 	sync.Mutex
 	urlCounts           map[string]int64
 	registeredEndpoints map[string]struct{}
-	escapeMessageToUrl  bool
 }
 
 // NewPageViews creates new PageViews.
-func NewPageViews(escapeMessageToUrl bool, registeredEndpoints map[string]struct{}) *PageViews {
+func NewPageViews(registeredEndpoints map[string]struct{}) *PageViews {
 	return &PageViews{
 		urlCounts:           map[string]int64{},
 		registeredEndpoints: registeredEndpoints,
-		escapeMessageToUrl:  escapeMessageToUrl,
 	}
 }
 
@@ -64,11 +44,11 @@ func (pv *PageViews) Listen(next func(http.ResponseWriter, *http.Request)) func(
 }
 
 // PublishViews after each period time reports page views statistics using
-// messenger. If there is no statistics in given period, then message will no
+// notifier. If there is no statistics in given period, then message will no
 // be sent. In case when there will no statistics in MaxRetriesWithoutMessage
 // periods, then message will be sent regarding last update and information
 // about no visits.
-func (pv *PageViews) PublishViews(period time.Duration, messenger MessageSender) {
+func (pv *PageViews) PublishViews(period time.Duration, notifier notify.Notifier) {
 	lastUpdate := time.Now()
 	noViewsInPeriods := 0
 	for {
@@ -80,45 +60,20 @@ func (pv *PageViews) PublishViews(period time.Duration, messenger MessageSender)
 		if len(pv.urlCounts) == 0 && noViewsInPeriods >= MaxRetriesWithoutMessage {
 			msg := fmt.Sprintf("[Info] No page views since %s.",
 				lastUpdate.Format("2006-01-02 15:04:05"))
-			if pv.escapeMessageToUrl {
-				msg = url.QueryEscape(msg)
-			}
-			messenger.SendMessage(msg)
+			notifier.Notify(notify.Message{Event: notify.EventMonitoring, Text: msg})
 			lastUpdate = time.Now()
 			noViewsInPeriods = 0
 			continue
 		}
 		msg := fmt.Sprintf("[Info] Page views since [%s]:\n%s",
 			lastUpdate.Format("2006-01-02 15:04:05"), pv.toString())
-		if pv.escapeMessageToUrl {
-			msg = url.QueryEscape(msg)
-		}
-		messenger.SendMessage(msg)
+		notifier.Notify(notify.Message{Event: notify.EventMonitoring, Text: msg})
 		pv.urlCounts = map[string]int64{}
 		lastUpdate = time.Now()
 		noViewsInPeriods = 0
 	}
 }
 
-func publishMessage(msg string, messenger MessageSender) {
-	retryId := 0
-	for {
-		sendErr := messenger.SendMessage(msg)
-		if sendErr == nil {
-			return
-		}
-		if sendErr != nil {
-			if retryId >= MaxSendMessageRetries {
-				log.Error().Err(sendErr).
-					Msgf("[monitor/page_views] after %d retries seng message failed", MaxSendMessageRetries)
-				return
-			}
-			retryId = retryId + 1
-			time.Sleep(WaitBetweenSendMessageRetries)
-		}
-	}
-}
-
 func (pv *PageViews) addView(url string) {
 	const noRegisteredKey = "/_notRegistered"
 	pv.Lock()
@@ -7,7 +7,7 @@ func TestIsEndpointMatched(t *testing.T) {
 		"/":     {},
 		"/docs": {},
 	}
-	pv := NewPageViews(false, endpoints)
+	pv := NewPageViews(endpoints)
 
 	if !pv.isEndpointMatched("/") {
 		t.Errorf("endpoint [/] should be matched")
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"homeApp/auth/telegram"
+	"homeApp/notify"
+
+	"github.com/rs/zerolog/log"
+)
+
+// Creates notification router with channels enabled in the config. Fails when
+// routing rules refer channel which is not enabled.
+func newNotifier(config NotifyConfig, telegramClient *telegram.Client, httpClient *http.Client) *notify.Router {
+	channels := map[string]notify.Notifier{
+		"log": notify.LogNotifier{},
+	}
+	if telegramClient != nil {
+		channels["telegram"] = notify.TelegramNotifier{Client: telegramClient}
+	}
+	if config.SmtpAddr != "" {
+		channels["email"] = notify.SmtpNotifier{
+			Addr:     config.SmtpAddr,
+			From:     config.SmtpFrom,
+			To:       config.SmtpTo,
+			Username: config.SmtpUsername,
+			Password: config.SmtpPassword,
+		}
+	}
+	if config.WebhookUrl != "" {
+		channels["webhook"] = notify.WebhookNotifier{HttpClient: httpClient, Url: config.WebhookUrl}
+	}
+	if config.NtfyUrl != "" {
+		channels["ntfy"] = notify.NtfyNotifier{HttpClient: httpClient, Url: config.NtfyUrl, Token: config.NtfyToken}
+	}
+	if config.GotifyUrl != "" {
+		channels["gotify"] = notify.GotifyNotifier{HttpClient: httpClient, Url: config.GotifyUrl, Token: config.GotifyToken}
+	}
+
+	router, rErr := notify.NewRouter(channels, config.Rules)
+	if rErr != nil {
+		log.Fatal().Err(rErr).Msg("Cannot configure notifications")
+	}
+	log.Info().Str("channels", strings.Join(router.ChannelNames(), ",")).Msg("Notification channels configured")
+	return router
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// WebhookNotifier posts notifications as JSON to given URL, e.g. to home
+// automation system.
+//
+//	{"event": "upload", "title": "...", "text": "...", "timestamp": "2024-05-01T10:00:00Z"}
+type WebhookNotifier struct {
+	HttpClient *http.Client
+	Url        string
+}
+
+type webhookPayload struct {
+	Event     string `json:"event"`
+	Title     string `json:"title"`
+	Text      string `json:"text"`
+	Timestamp string `json:"timestamp"`
+}
+
+func (wn WebhookNotifier) Notify(msg Message) error {
+	body, mErr := json.Marshal(webhookPayload{
+		Event:     msg.Event,
+		Title:     msg.Subject(),
+		Text:      msg.Text,
+		Timestamp: time.Now().UTC().Format(time.RFC3339),
+	})
+	if mErr != nil {
+		return mErr
+	}
+	req, rErr := http.NewRequest(http.MethodPost, wn.Url, bytes.NewReader(body))
+	if rErr != nil {
+		return rErr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return doRequest(wn.HttpClient, req)
+}
+
+// NtfyNotifier publishes notifications on ntfy topic. Url is the topic URL,
+// e.g. https://ntfy.sh/homeapp. Token is optional access token.
+type NtfyNotifier struct {
+	HttpClient *http.Client
+	Url        string
+	Token      string
+}
+
+func (nn NtfyNotifier) Notify(msg Message) error {
+	req, rErr := http.NewRequest(http.MethodPost, nn.Url, strings.NewReader(msg.Text))
+	if rErr != nil {
+		return rErr
+	}
+	req.Header.Set("Title", msg.Subject())
+	req.Header.Set("Tags", msg.Event)
+	if msg.Event == EventSecurity {
+		req.Header.Set("Priority", "high")
+	}
+	if nn.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+nn.Token)
+	}
+	return doRequest(nn.HttpClient, req)
+}
+
+// GotifyNotifier sends notifications to Gotify server. Url is the server base
+// URL, Token is the application token.
+type GotifyNotifier struct {
+	HttpClient *http.Client
+	Url        string
+	Token      string
+}
+
+type gotifyMessage struct {
+	Title    string `json:"title"`
+	Message  string `json:"message"`
+	Priority int    `json:"priority"`
+}
+
+func (gn GotifyNotifier) Notify(msg Message) error {
+	priority := 5
+	if msg.Event == EventSecurity {
+		priority = 8
+	}
+	body, mErr := json.Marshal(gotifyMessage{Title: msg.Subject(), Message: msg.Text, Priority: priority})
+	if mErr != nil {
+		return mErr
+	}
+	req, rErr := http.NewRequest(http.MethodPost, strings.TrimSuffix(gn.Url, "/")+"/message", bytes.NewReader(body))
+	if rErr != nil {
+		return rErr
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Gotify-Key", gn.Token)
+	return doRequest(gn.HttpClient, req)
+}
+
+// Sends request and treats every non-2xx response as an error.
+func doRequest(httpClient *http.Client, req *http.Request) error {
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("got %d status code from %s: %s", resp.StatusCode, req.URL.Host,
+			strings.TrimSpace(string(respBody)))
+	}
+	return nil
+}
@@ -0,0 +1,180 @@
+// Package notify sends notifications (uploads, security alerts, monitoring,
+// digests) over configured channels like Telegram, email or HTTP push
+// services. Router decides which channels are used for given event type.
+package notify
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rs/zerolog/log"
+)
+
+const notifyPrefix = "notify"
+
+// Event types of notifications. They're used in routing rules.
+const (
+	EventUpload     = "upload"     // New documents, books, counters and finance data
+	EventSecurity   = "security"   // Login lockouts and logins from new IP addresses
+	EventMonitoring = "monitoring" // Page views statistics
+	EventDigest     = "digest"     // Scheduled weekly and monthly digests
+
+	// AnyEvent routing rule is used for events without their own rule.
+	AnyEvent = "*"
+)
+
+// Events lists known event types.
+var Events = []string{EventUpload, EventSecurity, EventMonitoring, EventDigest}
+
+// Message is a single notification.
+type Message struct {
+	Event string
+	Title string // Optional, used e.g. as email subject
+	Text  string
+}
+
+// Subject returns message title or default title based on event type.
+func (m Message) Subject() string {
+	if m.Title != "" {
+		return m.Title
+	}
+	return fmt.Sprintf("HomeApp %s notification", m.Event)
+}
+
+// Notifier sends notifications over single channel.
+type Notifier interface {
+	Notify(msg Message) error
+}
+
+// LogNotifier only logs notifications. It's used when no other channel is
+// configured.
+type LogNotifier struct{}
+
+func (LogNotifier) Notify(msg Message) error {
+	log.Info().Str("event", msg.Event).Msgf("[%s] %s", notifyPrefix, msg.Text)
+	return nil
+}
+
+// Router sends notification to every channel routed for its event type.
+// Router is a Notifier itself.
+type Router struct {
+	channels map[string]Notifier
+	rules    map[string][]string
+}
+
+// NewRouter creates router for given named channels and rules mapping event
+// type (or AnyEvent) to channel names. Rules referring unknown channels are
+// rejected.
+func NewRouter(channels map[string]Notifier, rules map[string][]string) (*Router, error) {
+	for event, names := range rules {
+		for _, name := range names {
+			if _, exists := channels[name]; !exists {
+				return nil, fmt.Errorf("notification channel [%s] used for [%s] events is not configured",
+					name, event)
+			}
+		}
+	}
+	return &Router{channels: channels, rules: rules}, nil
+}
+
+// Notify sends message to channels routed for its event. All channels are
+// tried, even if some of them fail. Messages of events without any channel are
+// logged.
+func (r *Router) Notify(msg Message) error {
+	names := r.Channels(msg.Event)
+	if len(names) == 0 {
+		return LogNotifier{}.Notify(msg)
+	}
+
+	failed := make([]string, 0)
+	for _, name := range names {
+		if nErr := r.NotifyChannel(name, msg); nErr != nil {
+			failed = append(failed, fmt.Sprintf("%s: %s", name, nErr.Error()))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("notification failed on %d of %d channels (%s)", len(failed), len(names),
+			strings.Join(failed, "; "))
+	}
+	return nil
+}
+
+// Channels returns names of channels routed for given event type.
+func (r *Router) Channels(event string) []string {
+	names, exists := r.rules[event]
+	if !exists {
+		names = r.rules[AnyEvent]
+	}
+	return names
+}
+
+// NotifyChannel sends message over single named channel, regardless of
+// routing rules.
+func (r *Router) NotifyChannel(name string, msg Message) error {
+	channel, exists := r.channels[name]
+	if !exists {
+		return fmt.Errorf("notification channel [%s] is not configured", name)
+	}
+	nErr := channel.Notify(msg)
+	if nErr != nil {
+		log.Error().Err(nErr).Str("channel", name).Str("event", msg.Event).
+			Msgf("[%s] sending notification failed", notifyPrefix)
+	}
+	return nErr
+}
+
+// ChannelNames returns sorted names of configured channels.
+func (r *Router) ChannelNames() []string {
+	names := make([]string, 0, len(r.channels))
+	for name := range r.channels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseRules parses routing rules in form of
+// "upload=telegram,email;security=ntfy;*=log". Empty channel list means that
+// notifications of the event are only logged.
+func ParseRules(spec string) (map[string][]string, error) {
+	rules := make(map[string][]string)
+	for _, rule := range strings.Split(spec, ";") {
+		if strings.TrimSpace(rule) == "" {
+			continue
+		}
+		event, channels, found := strings.Cut(rule, "=")
+		event = strings.TrimSpace(event)
+		if !found || event == "" {
+			return nil, fmt.Errorf("incorrect notification rule [%s], expected event=channel1,channel2", rule)
+		}
+		if event != AnyEvent && !isKnownEvent(event) {
+			return nil, fmt.Errorf("unknown event type [%s], expected one of: %s", event,
+				strings.Join(Events, ", "))
+		}
+		if _, exists := rules[event]; exists {
+			return nil, fmt.Errorf("duplicated notification rule for [%s]", event)
+		}
+		names := make([]string, 0)
+		for _, name := range strings.Split(channels, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+		rules[event] = names
+	}
+	if len(rules) == 0 {
+		return nil, errors.New("there are no notification rules")
+	}
+	return rules, nil
+}
+
+func isKnownEvent(event string) bool {
+	for _, e := range Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
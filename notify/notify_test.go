@@ -0,0 +1,133 @@
+package notify
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeNotifier struct {
+	messages []Message
+	err      error
+}
+
+func (fn *fakeNotifier) Notify(msg Message) error {
+	fn.messages = append(fn.messages, msg)
+	return fn.err
+}
+
+func TestRouterRoutesByEvent(t *testing.T) {
+	telegram, email := &fakeNotifier{}, &fakeNotifier{err: errors.New("connection refused")}
+	rules, pErr := ParseRules("upload=telegram,email; *=telegram")
+	if pErr != nil {
+		t.Fatalf("unexpected error: %v", pErr)
+	}
+	router, rErr := NewRouter(map[string]Notifier{"telegram": telegram, "email": email}, rules)
+	if rErr != nil {
+		t.Fatalf("unexpected error: %v", rErr)
+	}
+
+	err := router.Notify(Message{Event: EventUpload, Text: "new document"})
+	if err == nil || !strings.Contains(err.Error(), "email: connection refused") {
+		t.Errorf("expected email channel error, got %v", err)
+	}
+	if err := router.Notify(Message{Event: EventSecurity, Text: "lockout"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if len(telegram.messages) != 2 || len(email.messages) != 1 || email.messages[0].Text != "new document" {
+		t.Errorf("unexpected routing: telegram %+v, email %+v", telegram.messages, email.messages)
+	}
+
+	if _, err := NewRouter(map[string]Notifier{"log": LogNotifier{}}, rules); err == nil {
+		t.Error("expected error for rules with unknown channel")
+	}
+}
+
+func TestParseRules(t *testing.T) {
+	rules, err := ParseRules("security=ntfy, email;monitoring=;*=log")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(rules[EventSecurity], ",") != "ntfy,email" || len(rules[EventMonitoring]) != 0 ||
+		strings.Join(rules[AnyEvent], ",") != "log" {
+		t.Errorf("unexpected rules: %v", rules)
+	}
+
+	for _, spec := range []string{"", "upload", "downloads=log", "upload=log;upload=email", "=log"} {
+		if _, err := ParseRules(spec); err == nil {
+			t.Errorf("expected error for [%s]", spec)
+		}
+	}
+}
+
+func TestHttpNotifiers(t *testing.T) {
+	var lastReq *http.Request
+	var lastBody string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lastReq, lastBody = r, string(body)
+		if r.URL.Path == "/fail" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+	msg := Message{Event: EventSecurity, Text: "Lockout of [user:bob]"}
+
+	if err := (WebhookNotifier{HttpClient: server.Client(), Url: server.URL + "/hook"}).Notify(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var payload webhookPayload
+	if jErr := json.Unmarshal([]byte(lastBody), &payload); jErr != nil || payload.Event != EventSecurity ||
+		payload.Text != msg.Text || payload.Title != "HomeApp security notification" {
+		t.Errorf("unexpected webhook payload: %s", lastBody)
+	}
+
+	ntfy := NtfyNotifier{HttpClient: server.Client(), Url: server.URL + "/homeapp", Token: "tk_1"}
+	if err := ntfy.Notify(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lastBody != msg.Text || lastReq.Header.Get("Authorization") != "Bearer tk_1" ||
+		lastReq.Header.Get("Priority") != "high" {
+		t.Errorf("unexpected ntfy request: %v %s", lastReq.Header, lastBody)
+	}
+
+	gotify := GotifyNotifier{HttpClient: server.Client(), Url: server.URL + "/", Token: "app-token"}
+	if err := gotify.Notify(msg); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if lastReq.URL.Path != "/message" || lastReq.Header.Get("X-Gotify-Key") != "app-token" ||
+		!strings.Contains(lastBody, `"priority":8`) {
+		t.Errorf("unexpected gotify request: %s %v %s", lastReq.URL.Path, lastReq.Header, lastBody)
+	}
+
+	failing := WebhookNotifier{HttpClient: server.Client(), Url: server.URL + "/fail"}
+	if err := failing.Notify(msg); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("expected error with status code, got %v", err)
+	}
+}
+
+func TestSmtpMessage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	body, err := smtpMessage("home@example.com", []string{"a@example.com", "b@example.com"},
+		"Nowy dokument", "Uploaded [Umowa najmu żółta]\nsize 1 MB", now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := string(body)
+	for _, expected := range []string{
+		"To: a@example.com, b@example.com\r\n",
+		"Subject: Nowy dokument\r\n",
+		"Date: Wed, 01 May 2024 10:00:00 +0000\r\n",
+		"Content-Transfer-Encoding: quoted-printable\r\n\r\n",
+		"Uploaded [Umowa najmu =C5=BC=C3=B3=C5=82ta]\r\nsize 1 MB",
+	} {
+		if !strings.Contains(msg, expected) {
+			t.Errorf("expected %q in message:\n%s", expected, msg)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package notify
+
+import (
+	"strings"
+	"time"
+
+	"homeApp/db"
+
+	"github.com/rs/zerolog/log"
+)
+
+const outboxPrefix = "notify/outbox"
+
+// OutboxNotifier stores notifications in the outbox table instead of sending
+// them directly. They're delivered later by OutboxWorker, so temporary
+// unavailability of a channel doesn't lose the notification.
+type OutboxNotifier struct {
+	DbClient *db.Client
+}
+
+func (on OutboxNotifier) Notify(msg Message) error {
+	return on.DbClient.OutboxInsert(db.NewOutboxMessage{Event: msg.Event, Title: msg.Title, Text: msg.Text})
+}
+
+// OutboxWorker periodically delivers pending outbox messages using the router.
+// Channels which already got the message are remembered, so only the failed
+// ones are retried. Retries are delayed with exponential backoff and after
+// MaxAttempts attempts message is marked as dead. Delivery is at least once -
+// message might be sent again if the app stops before saving delivery state.
+type OutboxWorker struct {
+	DbClient      *db.Client
+	Router        *Router
+	PollInterval  time.Duration
+	BatchSize     int
+	MaxAttempts   int
+	BaseBackoff   time.Duration
+	MaxBackoff    time.Duration
+	KeepDelivered time.Duration // How long delivered messages are kept in the outbox
+}
+
+// NewOutboxWorker creates OutboxWorker with default intervals.
+func NewOutboxWorker(dbClient *db.Client, router *Router, maxAttempts int) *OutboxWorker {
+	return &OutboxWorker{
+		DbClient:      dbClient,
+		Router:        router,
+		PollInterval:  10 * time.Second,
+		BatchSize:     50,
+		MaxAttempts:   maxAttempts,
+		BaseBackoff:   30 * time.Second,
+		MaxBackoff:    6 * time.Hour,
+		KeepDelivered: 30 * 24 * time.Hour,
+	}
+}
+
+// Run delivers due messages and removes old delivered ones every
+// PollInterval. It should be run in a separate goroutine.
+func (ow *OutboxWorker) Run() {
+	for {
+		ow.deliverDue()
+		ow.cleanup()
+		time.Sleep(ow.PollInterval)
+	}
+}
+
+func (ow *OutboxWorker) deliverDue() {
+	now := time.Now().UTC()
+	messages, dErr := ow.DbClient.OutboxDue(now.Format(db.TimestampFormat), ow.BatchSize)
+	if dErr != nil {
+		log.Error().Err(dErr).Msgf("[%s] cannot read due outbox messages", outboxPrefix)
+		return
+	}
+	for _, msg := range messages {
+		updated := ow.deliver(msg, now)
+		if uErr := ow.DbClient.OutboxUpdate(updated); uErr != nil {
+			log.Error().Err(uErr).Int64("messageId", msg.MessageId).
+				Msgf("[%s] cannot save delivery state of outbox message", outboxPrefix)
+		}
+	}
+}
+
+func (ow *OutboxWorker) cleanup() {
+	beforeTs := time.Now().UTC().Add(-ow.KeepDelivered).Format(db.TimestampFormat)
+	deleted, dErr := ow.DbClient.OutboxDeleteDelivered(beforeTs)
+	if dErr != nil {
+		log.Error().Err(dErr).Msgf("[%s] cannot delete old delivered outbox messages", outboxPrefix)
+		return
+	}
+	if deleted > 0 {
+		log.Info().Int64("deleted", deleted).Msgf("[%s] deleted old delivered outbox messages", outboxPrefix)
+	}
+}
+
+// Sends message to routed channels which haven't got it yet and returns
+// message with updated delivery state.
+func (ow *OutboxWorker) deliver(msg db.OutboxMessage, now time.Time) db.OutboxMessage {
+	notification := Message{Event: msg.Event, Title: msg.Title, Text: msg.Text}
+	channels := ow.Router.Channels(msg.Event)
+	if len(channels) == 0 {
+		LogNotifier{}.Notify(notification)
+	}
+
+	delivered := make([]string, 0)
+	if msg.DeliveredChannels != "" {
+		delivered = strings.Split(msg.DeliveredChannels, ",")
+	}
+	failed := make([]string, 0)
+	for _, name := range channels {
+		if contains(delivered, name) {
+			continue
+		}
+		if nErr := ow.Router.NotifyChannel(name, notification); nErr != nil {
+			failed = append(failed, name+": "+nErr.Error())
+			continue
+		}
+		delivered = append(delivered, name)
+	}
+
+	msg.Attempts = msg.Attempts + 1
+	msg.DeliveredChannels = strings.Join(delivered, ",")
+	if len(failed) == 0 {
+		deliveredTs := now.Format(db.TimestampFormat)
+		msg.Status = db.OutboxDelivered
+		msg.LastError = nil
+		msg.DeliveredTs = &deliveredTs
+		return msg
+	}
+
+	lastErr := strings.Join(failed, "; ")
+	msg.LastError = &lastErr
+	if msg.Attempts >= ow.MaxAttempts {
+		msg.Status = db.OutboxDead
+		log.Error().Int64("messageId", msg.MessageId).Str("event", msg.Event).Str("error", lastErr).
+			Msgf("[%s] notification not delivered after %d attempts, marked as dead", outboxPrefix, msg.Attempts)
+		return msg
+	}
+	msg.NextAttemptTs = now.Add(outboxBackoff(msg.Attempts, ow.BaseBackoff, ow.MaxBackoff)).
+		Format(db.TimestampFormat)
+	log.Warn().Int64("messageId", msg.MessageId).Int("attempts", msg.Attempts).
+		Msgf("[%s] notification delivery failed, next attempt at %s", outboxPrefix, msg.NextAttemptTs)
+	return msg
+}
+
+// Delay before next delivery attempt. It's doubled after each failed attempt,
+// but never exceeds max.
+func outboxBackoff(attempts int, base, max time.Duration) time.Duration {
+	backoff := base
+	for i := 1; i < attempts; i++ {
+		backoff = backoff * 2
+		if backoff >= max {
+			return max
+		}
+	}
+	if backoff > max {
+		return max
+	}
+	return backoff
+}
+
+func contains(names []string, name string) bool {
+	for _, n := range names {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
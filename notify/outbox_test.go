@@ -0,0 +1,65 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"homeApp/db"
+)
+
+func TestOutboxBackoff(t *testing.T) {
+	base, max := 30*time.Second, 10*time.Minute
+	expected := map[int]time.Duration{
+		1:  30 * time.Second,
+		2:  time.Minute,
+		3:  2 * time.Minute,
+		5:  8 * time.Minute,
+		6:  10 * time.Minute,
+		60: 10 * time.Minute,
+	}
+	for attempts, exp := range expected {
+		if got := outboxBackoff(attempts, base, max); got != exp {
+			t.Errorf("attempts %d: expected %v, got %v", attempts, exp, got)
+		}
+	}
+}
+
+func TestOutboxWorkerRetriesOnlyFailedChannels(t *testing.T) {
+	telegram, email := &fakeNotifier{}, &fakeNotifier{err: errors.New("connection refused")}
+	router, rErr := NewRouter(map[string]Notifier{"telegram": telegram, "email": email},
+		map[string][]string{AnyEvent: {"telegram", "email"}})
+	if rErr != nil {
+		t.Fatalf("unexpected error: %v", rErr)
+	}
+	worker := &OutboxWorker{Router: router, MaxAttempts: 2, BaseBackoff: time.Minute, MaxBackoff: time.Hour}
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	msg := db.OutboxMessage{MessageId: 1, Event: EventUpload, Text: "new document", Status: db.OutboxPending}
+
+	msg = worker.deliver(msg, now)
+	if msg.Status != db.OutboxPending || msg.Attempts != 1 || msg.DeliveredChannels != "telegram" ||
+		msg.NextAttemptTs != "2024-05-01 10:01:00" {
+		t.Errorf("unexpected state after first attempt: %+v", msg)
+	}
+	if msg.LastError == nil || !strings.Contains(*msg.LastError, "email: connection refused") {
+		t.Errorf("expected email error, got %v", msg.LastError)
+	}
+
+	msg = worker.deliver(msg, now.Add(time.Minute))
+	if msg.Status != db.OutboxDead || msg.Attempts != 2 {
+		t.Errorf("expected dead message after max attempts, got %+v", msg)
+	}
+	if len(telegram.messages) != 1 || len(email.messages) != 2 {
+		t.Errorf("expected single telegram message and two email attempts, got %d and %d",
+			len(telegram.messages), len(email.messages))
+	}
+
+	email.err = nil
+	msg.Status, msg.Attempts = db.OutboxPending, 0
+	msg = worker.deliver(msg, now.Add(time.Hour))
+	if msg.Status != db.OutboxDelivered || msg.LastError != nil || msg.DeliveredTs == nil ||
+		msg.DeliveredChannels != "telegram,email" {
+		t.Errorf("expected delivered message, got %+v", msg)
+	}
+}
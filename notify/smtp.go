@@ -0,0 +1,60 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// SmtpNotifier sends notifications by email. Connection is upgraded using
+// STARTTLS when the server supports it. Credentials are optional.
+type SmtpNotifier struct {
+	Addr     string // host:port
+	From     string
+	To       []string
+	Username string
+	Password string
+}
+
+func (sn SmtpNotifier) Notify(msg Message) error {
+	var auth smtp.Auth
+	if sn.Username != "" {
+		host, _, sErr := net.SplitHostPort(sn.Addr)
+		if sErr != nil {
+			return sErr
+		}
+		auth = smtp.PlainAuth("", sn.Username, sn.Password, host)
+	}
+	body, bErr := smtpMessage(sn.From, sn.To, msg.Subject(), msg.Text, time.Now())
+	if bErr != nil {
+		return bErr
+	}
+	return smtp.SendMail(sn.Addr, auth, sn.From, sn.To, body)
+}
+
+// Builds plain text email, quoted-printable encoded, so non-ASCII characters
+// (e.g. Polish names of documents) are preserved.
+func smtpMessage(from string, to []string, subject, text string, now time.Time) ([]byte, error) {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", from)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	msg.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+
+	qp := quotedprintable.NewWriter(&msg)
+	if _, wErr := qp.Write([]byte(strings.ReplaceAll(text, "\n", "\r\n"))); wErr != nil {
+		return nil, wErr
+	}
+	if cErr := qp.Close(); cErr != nil {
+		return nil, cErr
+	}
+	return msg.Bytes(), nil
+}
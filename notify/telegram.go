@@ -0,0 +1,16 @@
+package notify
+
+import "homeApp/auth/telegram"
+
+// TelegramNotifier sends notifications onto the Telegram channel.
+type TelegramNotifier struct {
+	Client *telegram.Client
+}
+
+func (tn TelegramNotifier) Notify(msg Message) error {
+	text := msg.Text
+	if msg.Title != "" {
+		text = msg.Title + "\n" + text
+	}
+	return tn.Client.SendMessage(text)
+}
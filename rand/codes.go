@@ -1,21 +1,66 @@
 package rand
 
-import "math/rand"
+import "crypto/rand"
 
 const BaseCharsCount = 62
 
-// Generates random alphanumeric codes of given length.
+// Generates random alphanumeric codes of given length. Codes are generated
+// using cryptographically secure random number generator, so those can be
+// used as secrets.
 func AlphanumStr(length int) string {
-	code := make([]byte, length)
+	code := make([]byte, 0, length)
 	chars := activationCodeChars()
 
-	for i := 0; i < length; i++ {
-		code[i] = chars[rand.Intn(BaseCharsCount)]
+	// Bytes above maxByte are rejected, to make distribution of chars uniform
+	const maxByte = 256 - (256 % BaseCharsCount)
+	for len(code) < length {
+		for _, b := range Bytes(length) {
+			if int(b) >= maxByte {
+				continue
+			}
+			code = append(code, chars[int(b)%BaseCharsCount])
+			if len(code) == length {
+				break
+			}
+		}
 	}
 
 	return string(code)
 }
 
+// Generates random numeric codes of given length, using cryptographically
+// secure random number generator.
+func NumericStr(length int) string {
+	code := make([]byte, 0, length)
+
+	// Bytes above maxByte are rejected, to make distribution of digits uniform
+	const maxByte = 250
+	for len(code) < length {
+		for _, b := range Bytes(length) {
+			if int(b) >= maxByte {
+				continue
+			}
+			code = append(code, '0'+b%10)
+			if len(code) == length {
+				break
+			}
+		}
+	}
+
+	return string(code)
+}
+
+// Bytes generates slice of given length of cryptographically secure random
+// bytes. Failure of system random number generator is not recoverable, hence
+// it panics in that case.
+func Bytes(length int) []byte {
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		panic("crypto/rand failed: " + err.Error())
+	}
+	return buf
+}
+
 func activationCodeChars() [BaseCharsCount]byte {
 	return [...]byte{
 		'0', '1', '2', '3', '4', '5', '6', '7', '8', '9',
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rs/zerolog/log"
+)
+
+const serverPrefix = "server"
+
+// Starts HomeApp server and blocks. When TLS is configured, the server is
+// served over HTTPS and optionally plain HTTP requests on RedirectPort are
+// redirected to HTTPS.
+func listenAndServe(config Config, handler http.Handler) error {
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", config.Port),
+		Handler:           handler,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	if config.Tls == nil {
+		log.Info().Msgf("Listening on :%d...", config.Port)
+		return server.ListenAndServe()
+	}
+
+	certs, certErr := newCertReloader(config.Tls.CertPath, config.Tls.KeyPath)
+	if certErr != nil {
+		return certErr
+	}
+	go certs.reloadOnSighup()
+	server.TLSConfig = &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: certs.getCertificate,
+	}
+
+	if config.Tls.RedirectPort > 0 {
+		go serveHttpsRedirect(config.Tls.RedirectPort, config.Port)
+	}
+
+	log.Info().Msgf("Listening on :%d (HTTPS)...", config.Port)
+	return server.ListenAndServeTLS("", "")
+}
+
+// Serves plain HTTP on given port redirecting every request to HTTPS.
+func serveHttpsRedirect(port, httpsPort int) {
+	redirect := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, httpsUrl(r, httpsPort), http.StatusMovedPermanently)
+	})
+	server := &http.Server{
+		Addr:              fmt.Sprintf(":%d", port),
+		Handler:           redirect,
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	log.Info().Msgf("Redirecting HTTP on :%d to HTTPS on :%d...", port, httpsPort)
+	if lasErr := server.ListenAndServe(); lasErr != nil {
+		log.Error().Err(lasErr).Msgf("[%s] HTTPS redirect server stopped", serverPrefix)
+	}
+}
+
+// Builds HTTPS URL of given request on given port. Default HTTPS port is
+// omitted, IPv6 addresses are still bracketed.
+func httpsUrl(r *http.Request, httpsPort int) string {
+	host := r.Host
+	if hostname, _, splitErr := net.SplitHostPort(host); splitErr == nil {
+		host = hostname
+	}
+	if httpsPort != 443 {
+		host = net.JoinHostPort(host, strconv.Itoa(httpsPort))
+	} else if strings.Contains(host, ":") {
+		host = "[" + host + "]"
+	}
+	return "https://" + host + r.URL.RequestURI()
+}
+
+// Sets Strict-Transport-Security header, so browsers use only HTTPS for given
+// period.
+func withHsts(maxAge time.Duration, handler http.HandlerFunc) http.HandlerFunc {
+	headerValue := fmt.Sprintf("max-age=%d", int(maxAge.Seconds()))
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Strict-Transport-Security", headerValue)
+		handler(w, r)
+	}
+}
+
+// Keeps TLS certificate loaded from files. Certificate can be reloaded without
+// restart (e.g. after renewal) by sending SIGHUP to the process. When reload
+// fails, the previous certificate is still used.
+type certReloader struct {
+	sync.RWMutex
+	certPath string
+	keyPath  string
+	cert     *tls.Certificate
+}
+
+func newCertReloader(certPath, keyPath string) (*certReloader, error) {
+	cr := &certReloader{certPath: certPath, keyPath: keyPath}
+	if loadErr := cr.reload(); loadErr != nil {
+		return nil, loadErr
+	}
+	return cr, nil
+}
+
+func (cr *certReloader) reload() error {
+	cert, loadErr := tls.LoadX509KeyPair(cr.certPath, cr.keyPath)
+	if loadErr != nil {
+		log.Error().Err(loadErr).Str("cert", cr.certPath).Str("key", cr.keyPath).
+			Msgf("[%s] cannot load TLS certificate", serverPrefix)
+		return loadErr
+	}
+	cr.Lock()
+	cr.cert = &cert
+	cr.Unlock()
+	log.Info().Str("cert", cr.certPath).Msgf("[%s] TLS certificate loaded", serverPrefix)
+	return nil
+}
+
+func (cr *certReloader) getCertificate(_ *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	cr.RLock()
+	defer cr.RUnlock()
+	return cr.cert, nil
+}
+
+func (cr *certReloader) reloadOnSighup() {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		log.Info().Msgf("[%s] SIGHUP received, reloading TLS certificate", serverPrefix)
+		cr.reload()
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// Writes new self-signed certificate with given serial number and its key as
+// PEM files.
+func writeTestCert(t *testing.T, certPath, keyPath string, serial int64) {
+	t.Helper()
+	key, kErr := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if kErr != nil {
+		t.Fatal(kErr)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "homeapp.test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, cErr := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if cErr != nil {
+		t.Fatal(cErr)
+	}
+	keyDer, mErr := x509.MarshalECPrivateKey(key)
+	if mErr != nil {
+		t.Fatal(mErr)
+	}
+
+	certPem := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPem := pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer})
+	if wErr := os.WriteFile(certPath, certPem, 0600); wErr != nil {
+		t.Fatal(wErr)
+	}
+	if wErr := os.WriteFile(keyPath, keyPem, 0600); wErr != nil {
+		t.Fatal(wErr)
+	}
+}
+
+// Serial number of certificate currently served by the reloader.
+func servedSerial(t *testing.T, cr *certReloader) int64 {
+	t.Helper()
+	cert, _ := cr.getCertificate(nil)
+	parsed, pErr := x509.ParseCertificate(cert.Certificate[0])
+	if pErr != nil {
+		t.Fatal(pErr)
+	}
+	return parsed.SerialNumber.Int64()
+}
+
+func TestCertReloader(t *testing.T) {
+	dir := t.TempDir()
+	certPath, keyPath := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	if _, err := newCertReloader(certPath, keyPath); err == nil {
+		t.Error("expected error when certificate files don't exist")
+	}
+
+	writeTestCert(t, certPath, keyPath, 1)
+	cr, err := newCertReloader(certPath, keyPath)
+	if err != nil {
+		t.Fatalf("cannot load certificate: %v", err)
+	}
+
+	testCases := []struct {
+		name    string
+		prepare func()
+		isValid bool
+		serial  int64
+	}{
+		{"renewed certificate", func() { writeTestCert(t, certPath, keyPath, 2) }, true, 2},
+		{"broken certificate", func() { os.WriteFile(certPath, []byte("broken"), 0600) }, false, 2},
+		{"missing key", func() {
+			writeTestCert(t, certPath, keyPath, 3)
+			os.Remove(keyPath)
+		}, false, 2},
+		{"fixed certificate", func() { writeTestCert(t, certPath, keyPath, 4) }, true, 4},
+	}
+
+	for _, tc := range testCases {
+		tc.prepare()
+		if reloadErr := cr.reload(); (reloadErr == nil) != tc.isValid {
+			t.Errorf("[%s] expected valid=%v, got error %v", tc.name, tc.isValid, reloadErr)
+		}
+		if serial := servedSerial(t, cr); serial != tc.serial {
+			t.Errorf("[%s] expected certificate %d to be served, got %d", tc.name, tc.serial, serial)
+		}
+	}
+}
+
+func TestWithHsts(t *testing.T) {
+	testCases := []struct {
+		maxAge time.Duration
+		header string
+	}{
+		{180 * 24 * time.Hour, "max-age=15552000"},
+		{24 * time.Hour, "max-age=86400"},
+	}
+
+	for _, tc := range testCases {
+		handler := withHsts(tc.maxAge, func(w http.ResponseWriter, r *http.Request) {})
+		rec := httptest.NewRecorder()
+		handler(rec, httptest.NewRequest(http.MethodGet, "/home", nil))
+		if got := rec.Header().Get("Strict-Transport-Security"); got != tc.header {
+			t.Errorf("for %v expected header %q, got %q", tc.maxAge, tc.header, got)
+		}
+	}
+}
+
+func TestHttpsUrl(t *testing.T) {
+	testCases := []struct {
+		host      string
+		target    string
+		httpsPort int
+		url       string
+	}{
+		{"homeapp.test", "/home", 443, "https://homeapp.test/home"},
+		{"homeapp.test:80", "/home", 443, "https://homeapp.test/home"},
+		{"homeapp.test:8080", "/documents?id=1", 8443, "https://homeapp.test:8443/documents?id=1"},
+		{"192.168.1.10:8080", "/", 8443, "https://192.168.1.10:8443/"},
+		{"[::1]:8080", "/", 443, "https://[::1]/"},
+		{"[::1]:8080", "/", 8443, "https://[::1]:8443/"},
+	}
+
+	for _, tc := range testCases {
+		r := httptest.NewRequest(http.MethodGet, tc.target, nil)
+		r.Host = tc.host
+		if got := httpsUrl(r, tc.httpsPort); got != tc.url {
+			t.Errorf("for %s%s expected %s, got %s", tc.host, tc.target, tc.url, got)
+		}
+	}
+}
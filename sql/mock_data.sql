@@ -10,6 +10,12 @@ VALUES
     )
 ;
 
+DELETE FROM userRoles;
+INSERT INTO userRoles (UserId, Role)
+VALUES
+    (1, 'admin')
+;
+
 DELETE FROM waterCounter;
 INSERT INTO waterCounter (Date, ColdWaterLiters, HotWaterLiters)
 VALUES
@@ -11,6 +11,133 @@ CREATE TABLE IF NOT EXISTS users (
     UNIQUE(Username)
 );
 
+-- Roles granted to users, currently only "admin"
+CREATE TABLE IF NOT EXISTS userRoles (
+    UserId INT NOT NULL,
+    Role TEXT NOT NULL,
+
+    PRIMARY KEY (UserId, Role)
+);
+
+CREATE TABLE IF NOT EXISTS userPermissions (
+    UserId INT NOT NULL,
+    Module TEXT NOT NULL,
+    Access TEXT NOT NULL, -- read or write
+
+    PRIMARY KEY (UserId, Module)
+);
+
+CREATE TABLE IF NOT EXISTS jwtSigningKeys (
+    KeyId TEXT NOT NULL,
+    SigningKey BLOB NOT NULL,
+    CreateTs TEXT NOT NULL,
+    RetireTs TEXT NULL,
+
+    PRIMARY KEY (KeyId)
+);
+
+CREATE TABLE IF NOT EXISTS sessions (
+    SessionId TEXT NOT NULL,
+    UserId INT NOT NULL,
+    UserAgent TEXT NOT NULL,
+    IpAddress TEXT NOT NULL,
+    CsrfToken TEXT NOT NULL,
+    CreateTs TEXT NOT NULL,
+    LastSeenTs TEXT NOT NULL,
+    ExpireTs TEXT NOT NULL,
+    RevokeTs TEXT NULL,
+
+    PRIMARY KEY (SessionId)
+);
+
+-- Second factor method chosen by user. Users without a row use the default
+-- method from the configuration.
+CREATE TABLE IF NOT EXISTS userTwoFactor (
+    UserId INT NOT NULL,
+    Method TEXT NOT NULL,
+    TotpSecretEncrypted BLOB NULL,
+    TotpPendingSecretEncrypted BLOB NULL,
+    TotpLastCounter INT NOT NULL DEFAULT 0,
+    UpdateTs TEXT NOT NULL,
+
+    PRIMARY KEY (UserId)
+);
+
+CREATE TABLE IF NOT EXISTS userRecoveryCodes (
+    UserId INT NOT NULL,
+    CodeHashed TEXT NOT NULL,
+    CreateTs TEXT NOT NULL,
+    UsedTs TEXT NULL,
+
+    PRIMARY KEY (UserId, CodeHashed)
+);
+
+-- Failed login attempts per username ("user:" prefix) and per IP address
+-- ("ip:" prefix) used for throttling and lockouts.
+CREATE TABLE IF NOT EXISTS loginThrottle (
+    ThrottleKey TEXT NOT NULL,
+    FailedCount INT NOT NULL,
+    LastFailureTs TEXT NOT NULL,
+    BlockedUntilTs TEXT NULL,
+
+    PRIMARY KEY (ThrottleKey)
+);
+
+-- Personal API tokens. Only SHA256 of the token is stored. Scopes are comma
+-- separated module:access pairs, e.g. "counters:write,finance:read".
+CREATE TABLE IF NOT EXISTS apiTokens (
+    TokenId TEXT NOT NULL,
+    UserId INT NOT NULL,
+    Name TEXT NOT NULL,
+    TokenHash TEXT NOT NULL,
+    Scopes TEXT NOT NULL,
+    CreateTs TEXT NOT NULL,
+    LastUsedTs TEXT NULL,
+    RevokeTs TEXT NULL,
+
+    PRIMARY KEY (TokenId),
+    UNIQUE (TokenHash)
+);
+
+-- Audit log of authentication events (login, 2FA, session prolongation,
+-- logout, password change). UserId is NULL when user is not known yet (failed
+-- password check).
+CREATE TABLE IF NOT EXISTS authEvents (
+    EventId INTEGER PRIMARY KEY,
+    Ts TEXT NOT NULL,
+    EventType TEXT NOT NULL,
+    Result TEXT NOT NULL,
+    UserId INT NULL,
+    Username TEXT NOT NULL,
+    IpAddress TEXT NOT NULL,
+    UserAgent TEXT NOT NULL,
+    Details TEXT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS authEventsTsIdx ON authEvents(Ts);
+CREATE INDEX IF NOT EXISTS authEventsUserIpIdx ON authEvents(UserId, IpAddress);
+
+-- Notifications waiting for delivery (transactional outbox). Upload
+-- notifications are inserted in the same transaction as uploaded data. Status
+-- is one of pending, delivered and dead (delivery failed too many times).
+-- DeliveredChannels is comma separated list of channels which already got the
+-- message, so retries don't send it twice.
+CREATE TABLE IF NOT EXISTS outbox (
+    MessageId INTEGER PRIMARY KEY,
+    Event TEXT NOT NULL,
+    Title TEXT NOT NULL,
+    Text TEXT NOT NULL,
+    Status TEXT NOT NULL,
+    Attempts INT NOT NULL,
+    DeliveredChannels TEXT NOT NULL,
+    LastError TEXT NULL,
+    CreatedTs TEXT NOT NULL,
+    NextAttemptTs TEXT NOT NULL,
+    DeliveredTs TEXT NULL
+);
+
+CREATE INDEX IF NOT EXISTS outboxStatusNextAttemptIdx ON outbox(Status, NextAttemptTs);
+
 CREATE TABLE IF NOT EXISTS energyCounter (
     Date TEXT NOT NULL,
     EnergyKwh REAL NOT NULL,
@@ -39,6 +166,34 @@ CREATE TABLE IF NOT EXISTS bankTransactions (
     UNIQUE(AccountNumber, ExecutionDate, OrderDate, AmountValue, Description)
 );
 
+-- Saved mappings of CSV files with bank transactions (see finance.CsvMapping).
+-- Column numbers start from 1, zero means that the column isn't present.
+-- DescriptionColumns is comma separated list of column numbers.
+CREATE TABLE IF NOT EXISTS csvProfiles (
+    ProfileId INTEGER PRIMARY KEY,
+    Name TEXT NOT NULL UNIQUE,
+    Delimiter TEXT NOT NULL,
+    Encoding TEXT NOT NULL,
+    SkipRows INT NOT NULL,
+    HasHeader INT NOT NULL,
+    DateLayout TEXT NOT NULL,
+    DecimalComma INT NOT NULL,
+    AmountSign TEXT NOT NULL,
+    DefaultCurrency TEXT NOT NULL,
+    AccountNumber TEXT NOT NULL,
+    ExecutionDateColumn INT NOT NULL,
+    OrderDateColumn INT NOT NULL,
+    AmountColumn INT NOT NULL,
+    DebitColumn INT NOT NULL,
+    CreditColumn INT NOT NULL,
+    CurrencyColumn INT NOT NULL,
+    TypeColumn INT NOT NULL,
+    DescriptionColumns TEXT NOT NULL,
+    EndingBalanceColumn INT NOT NULL,
+    AccountColumn INT NOT NULL,
+    UpdatedTs TEXT NOT NULL
+);
+
 CREATE TABLE IF NOT EXISTS documents (
     DocumentId INT NOT NULL,
     DocumentName TEXT NOT NULL,
@@ -48,6 +203,9 @@ CREATE TABLE IF NOT EXISTS documents (
     PersonInvolved TEXT NULL,
     FileExtension TEXT NOT NULL,
     FileSize INT NOT NULL,
+    -- Optional, used by digests. Added to existing tables on startup
+    -- (db/migrations.go).
+    ExpiryDate TEXT NULL,
 
     PRIMARY KEY (DocumentId),
     UNIQUE(DocumentName, DocumentDate, Category, PersonInvolved)